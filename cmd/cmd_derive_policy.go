@@ -17,12 +17,20 @@ var deriveCmd = &cobra.Command{
 
 func init() {
 	deriveCmd.Flags().String("config-file", "config.yml", "Configuration file path")
-	deriveCmd.Flags().String("vm-prices-file","vm_profiles.json", "VM prices file path")
+	deriveCmd.Flags().String("vm-prices-file","", "VM catalog file path. If set, the stored VM catalog is reloaded from this file before the derivation")
 }
 
 func derive (cmd *cobra.Command, args []string) {
 	configFile := cmd.Flag("config-file").Value.String()
 	sysConfiguration,_ := util.ReadConfigFile(configFile)
+	if vmPricesFile := cmd.Flag("vm-prices-file").Value.String(); vmPricesFile != "" {
+		catalogVersion, _, err := server.ReloadVMCatalog(sysConfiguration, vmPricesFile)
+		if err != nil {
+			log.Error("The VM catalog could not be reloaded from %s. Details: %s", vmPricesFile, err)
+			return
+		}
+		log.Info("Deriving with VM catalog version %d", catalogVersion.Version)
+	}
 	timeStart := sysConfiguration.ScalingHorizon.StartTime
 	timeEnd := sysConfiguration.ScalingHorizon.EndTime
 	_, err := server.StartPolicyDerivation(timeStart,timeEnd,sysConfiguration)
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Cloud-Pie/SPDT/planner/derivation"
+	"github.com/Cloud-Pie/SPDT/server"
+	db "github.com/Cloud-Pie/SPDT/storage"
+	"github.com/Cloud-Pie/SPDT/types"
+	"github.com/Cloud-Pie/SPDT/util"
+	"github.com/spf13/cobra"
+	"gopkg.in/mgo.v2/bson"
+	"io/ioutil"
+	"net/http"
+)
+
+// diffCmd represents the diff policies command
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Compare two policies",
+	Long: `Compare the scaling actions of two stored policies aligned by time.
+	The ids can also refer to derivation jobs of the running server, in which case the selected policy of the job is used.
+	The differences per interval are written in the file output.json`,
+	Run: diff,
+}
+
+func init() {
+	diffCmd.Flags().String("a", "", "ID of the reference policy or derivation job")
+	diffCmd.Flags().String("b", "", "ID of the compared policy or derivation job")
+	diffCmd.Flags().String("config-file", "config.yml", "Configuration file path")
+}
+
+func diff(cmd *cobra.Command, args []string) {
+	idA := cmd.Flag("a").Value.String()
+	idB := cmd.Flag("b").Value.String()
+	if idA == "" || idB == "" {
+		fmt.Println("You need to specify the policies to compare with the flags --a and --b")
+		return
+	}
+	configFile := cmd.Flag("config-file").Value.String()
+	systemConfiguration, _ := util.ReadConfigFile(configFile)
+
+	policyA, err := resolvePolicy(systemConfiguration, idA)
+	check(err, "Policy "+idA+" not found.")
+	policyB, err := resolvePolicy(systemConfiguration, idB)
+	check(err, "Policy "+idB+" not found.")
+	vmProfiles, _, err := server.ReadVMProfiles(systemConfiguration)
+	check(err, "No VM profiles found.")
+
+	policiesDiff := derivation.DiffPolicies(policyA, policyB, systemConfiguration.MainServiceName, derivation.VMListToMap(vmProfiles))
+	summary := policiesDiff.Summary
+	fmt.Printf("Cost %.2f -> %.2f (%+.2f, %+.2f%%). %d intervals changed\n", summary.CostA, summary.CostB,
+		summary.CostDelta, summary.CostDeltaPercent, summary.ChangedIntervals)
+	writeToFile(policiesDiff)
+}
+
+//Find a stored policy, or the policy selected by a derivation job of the running server
+func resolvePolicy(systemConfiguration util.SystemConfiguration, id string) (types.Policy, error) {
+	if !bson.IsObjectIdHex(id) {
+		return types.Policy{}, errors.New("Invalid id " + id)
+	}
+	policy, err := db.GetPolicyDAO(systemConfiguration.MainServiceName).FindByID(id)
+	if err == nil {
+		return policy, nil
+	}
+	//Derivation jobs are only kept in the memory of the server
+	jobURL := util.ParseURL(systemConfiguration.Host+util.ENDPOINT_DERIVATION_JOB,
+		map[string]string{"service": systemConfiguration.MainServiceName, "id": id})
+	response, err := http.Get(jobURL)
+	if err != nil {
+		return types.Policy{}, err
+	}
+	defer response.Body.Close()
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return types.Policy{}, err
+	}
+	if response.StatusCode != http.StatusOK {
+		return types.Policy{}, errors.New("Policy or derivation job " + id + " not found")
+	}
+	job := server.DerivationJob{}
+	err = json.Unmarshal(data, &job)
+	if err != nil {
+		return types.Policy{}, err
+	}
+	if job.SelectedPolicy == nil {
+		return types.Policy{}, errors.New("Derivation job " + id + " has no selected policy")
+	}
+	return *job.SelectedPolicy, nil
+}
@@ -7,7 +7,6 @@ import (
 	"github.com/spf13/cobra"
 	"fmt"
 	"os"
-	"time"
 	"github.com/Cloud-Pie/SPDT/util"
 )
 
@@ -27,16 +26,47 @@ func init() {
 	policiesCmd.Flags().String("pId", "", "Policy ID")
 	policiesCmd.Flags().BoolVar(&all,"all", false, "Retrieve all stored policies")
 	policiesCmd.Flags().String("config-file", "config.yml", "Configuration file path")
+	policiesCmd.Flags().String("algorithm", "", "Comma separated list of algorithms")
+	policiesCmd.Flags().String("status", "", "Comma separated list of status (selected, discarted, scheduled)")
+	policiesCmd.Flags().String("min-cost", "", "Minimum cost of the policies")
+	policiesCmd.Flags().String("max-cost", "", "Maximum cost of the policies")
+	policiesCmd.Flags().String("created-after", "", "Policies created after this time")
+	policiesCmd.Flags().String("created-before", "", "Policies created before this time")
+	policiesCmd.Flags().String("sort", "", "Comma separated list of sort fields, use - for descending order. E.g. cost,-created")
+	policiesCmd.Flags().String("limit", "", "Max number of policies retrieved")
+	policiesCmd.Flags().String("cursor", "", "Cursor returned by a previous query to retrieve the next page")
+	policiesCmd.Flags().String("fields", "", "Comma separated list of fields retrieved. E.g. id,algorithm,metrics")
+}
+
+//Flags of the command mapped to the parameters of a policy query
+var policyQueryFlags = map[string]string {
+	"start-time": db.QUERY_START,
+	"end-time": db.QUERY_END,
+	"algorithm": db.QUERY_ALGORITHM,
+	"status": db.QUERY_STATUS,
+	"min-cost": db.QUERY_MIN_COST,
+	"max-cost": db.QUERY_MAX_COST,
+	"created-after": db.QUERY_CREATED_AFTER,
+	"created-before": db.QUERY_CREATED_BEFORE,
+	"sort": db.QUERY_SORT,
+	"limit": db.QUERY_LIMIT,
+	"cursor": db.QUERY_CURSOR,
+	"fields": db.QUERY_FIELDS,
 }
 
 func retrieve (cmd *cobra.Command, args []string) {
 	id := cmd.Flag("pId").Value.String()
-	start := cmd.Flag("start-time").Value.String()
-	end := cmd.Flag("end-time").Value.String()
 	configFile := cmd.Flag("config-file").Value.String()
 	systemConfiguration,_ := util.ReadConfigFile(configFile)
 	policyDAO := db.GetPolicyDAO(systemConfiguration.MainServiceName)
 
+	params := make(map[string]string)
+	for flag, param := range policyQueryFlags {
+		if value := cmd.Flag(flag).Value.String(); value != "" {
+			params[param] = value
+		}
+	}
+
 	if id != "" {
 		policy,err := policyDAO.FindByID(id)
 		if err != nil {
@@ -46,22 +76,19 @@ func retrieve (cmd *cobra.Command, args []string) {
 			fmt.Println("Policy retrieved")
 			writeToFile(policy)
 		}
-	} else if start != "" && end != "" {
-		startTime,err := time.Parse(util.UTC_TIME_LAYOUT , start)
-		check(err, "Invalid start time")
-		endTime,err := time.Parse(util.UTC_TIME_LAYOUT , end)
-		check(err, "Invalid end time")
-		policies,err := policyDAO.FindAllByTimeWindow(startTime, endTime)
-		check(err, "No policies for the specified window")
+	} else if len(params) > 0 || all {
+		query,err := db.ParsePolicyQuery(params)
+		check(err, "Invalid parameters.")
+		policies,nextCursor,err := policyDAO.FindByQuery(query)
+		check(err, "No policies for the specified parameters")
 		if len(policies) == 0 {
-			log.Fatalf("No policies found for the specified window")
-			fmt.Println("No policies found for the specified window")
+			fmt.Println("No policies found for the specified parameters")
+			return
 		}
 		writeToFile(policies)
-	}else if all {
-		policies,err := policyDAO.FindAll()
-		check(err, "No policies for the specified window")
-		writeToFile(policies)
+		if nextCursor != "" {
+			fmt.Println("More policies available, use --cursor=" + nextCursor)
+		}
 	}else {
 		fmt.Println("You need to use the flags to specify the parameters")
 	}
@@ -0,0 +1,248 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/Cloud-Pie/SPDT/planner/profiles_processing"
+	db "github.com/Cloud-Pie/SPDT/storage"
+	"github.com/Cloud-Pie/SPDT/types"
+	"github.com/Cloud-Pie/SPDT/util"
+	"github.com/spf13/cobra"
+	"gopkg.in/mgo.v2/bson"
+	"os"
+	"strconv"
+	"time"
+)
+
+// profilesCmd groups the commands to manage the stored performance profiles
+var profilesCmd = &cobra.Command{
+	Use:   "profiles",
+	Short: "Manage performance profiles",
+	Long:  "Import and export the performance profiles of the service",
+}
+
+// profilesImportCmd represents the import of performance profiles from load tests results
+var profilesImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import performance profiles",
+	Long: `Import performance profiles from load tests results.
+	Formats: csv (cpu_cores, mem_gb, replicas, msc_per_second, pod_boot_time_sec, sd_pod_boot_time_sec),
+	json (exported profiles), k6 (--summary-export) and vegeta (report -type=json).
+	The outputs of k6 and vegeta require the flags --cpu-cores, --mem-gb and --replicas`,
+	Run: importProfiles,
+}
+
+// profilesCheckCmd represents the quality check of the stored performance profiles
+var profilesCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Check performance profiles",
+	Long: `Check the stored performance profiles for zero capacities, outliers, capacities that decrease
+	with more replicas and missing boot times. With --repair=smooth or --repair=exclude the profiles are repaired
+	and stored again. The issues found are written in the file output.json`,
+	Run: checkProfiles,
+}
+
+// profilesExportCmd represents the export of the stored performance profiles
+var profilesExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export performance profiles",
+	Long:  "Export the stored performance profiles in json or csv format",
+	Run:   exportProfiles,
+}
+
+func init() {
+	profilesImportCmd.Flags().String("file", "", "Path of the file to import")
+	profilesImportCmd.Flags().String("format", profiles_processing.FORMAT_CSV, "Format of the file: csv, json, k6 or vegeta")
+	profilesImportCmd.Flags().String("cpu-cores", "", "CPU cores limit of the service under test (k6 and vegeta)")
+	profilesImportCmd.Flags().String("mem-gb", "", "Memory limit in GB of the service under test (k6 and vegeta)")
+	profilesImportCmd.Flags().String("replicas", "", "Number of replicas of the service under test (k6 and vegeta)")
+	profilesImportCmd.Flags().String("boot-time-sec", "0", "Boot time in seconds of the replicas (k6 and vegeta)")
+	profilesImportCmd.Flags().Bool("replace", false, "Remove the stored profiles before the import")
+	profilesImportCmd.Flags().String("repair", profiles_processing.REPAIR_SMOOTH, "Repair of the imported profiles: smooth, exclude or none")
+	profilesImportCmd.Flags().String("config-file", "config.yml", "Configuration file path")
+
+	profilesCheckCmd.Flags().String("repair", profiles_processing.REPAIR_NONE, "Repair of the stored profiles: smooth, exclude or none")
+	profilesCheckCmd.Flags().String("config-file", "config.yml", "Configuration file path")
+
+	profilesExportCmd.Flags().String("format", profiles_processing.FORMAT_JSON, "Format of the file: json or csv")
+	profilesExportCmd.Flags().String("output", "", "Path of the exported file. Default profiles.<format>")
+	profilesExportCmd.Flags().String("config-file", "config.yml", "Configuration file path")
+
+	profilesCmd.AddCommand(profilesImportCmd)
+	profilesCmd.AddCommand(profilesExportCmd)
+	profilesCmd.AddCommand(profilesCheckCmd)
+}
+
+func importProfiles(cmd *cobra.Command, args []string) {
+	fileName := cmd.Flag("file").Value.String()
+	if fileName == "" {
+		fmt.Println("You need to specify the file to import with the flag --file")
+		return
+	}
+	format := cmd.Flag("format").Value.String()
+	repair := cmd.Flag("repair").Value.String()
+	if !validRepairMode(repair) {
+		fmt.Println("Unknown repair " + repair)
+		return
+	}
+	file, err := os.Open(fileName)
+	check(err, "Error reading file "+fileName+".")
+	defer file.Close()
+
+	var imported []types.PerformanceProfile
+	switch format {
+	case profiles_processing.FORMAT_CSV:
+		imported, err = profiles_processing.ReadCSVProfiles(file)
+	case profiles_processing.FORMAT_JSON:
+		imported, err = profiles_processing.ReadJSONProfiles(file)
+	case profiles_processing.FORMAT_K6, profiles_processing.FORMAT_VEGETA:
+		setting, errSetting := loadTestSetting(cmd)
+		check(errSetting, "Invalid load test configuration.")
+		var profile types.PerformanceProfile
+		if format == profiles_processing.FORMAT_K6 {
+			profile, err = profiles_processing.ReadK6Summary(file, setting)
+		} else {
+			profile, err = profiles_processing.ReadVegetaReport(file, setting)
+		}
+		imported = []types.PerformanceProfile{profile}
+	default:
+		fmt.Println("Unknown format " + format)
+		return
+	}
+	check(err, "Invalid profiles in "+fileName+".")
+	for i := range imported {
+		imported[i].Source = types.PROFILE_SOURCE_IMPORT
+		imported[i].FetchedAt = time.Now()
+	}
+
+	configFile := cmd.Flag("config-file").Value.String()
+	systemConfiguration, _ := util.ReadConfigFile(configFile)
+	profilesDAO := db.GetPerformanceProfileDAO(systemConfiguration.MainServiceName)
+	replace, _ := cmd.Flags().GetBool("replace")
+	var stored []types.PerformanceProfile
+	if replace {
+		err = profilesDAO.DeleteAll()
+		check(err, "Error removing old profiles.")
+	} else {
+		stored, _ = profilesDAO.FindAll()
+	}
+
+	for _, p := range imported {
+		fmt.Printf("Imported %d settings for %.2f cores and %.2f GB\n", len(p.MSCSettings), p.Limit.CPUCores, p.Limit.MemoryGB)
+	}
+	profiles, issues := profiles_processing.CheckProfiles(profiles_processing.MergeProfiles(stored, imported), repair)
+	printProfileIssues(issues)
+
+	numSettings := 0
+	for _, p := range profiles {
+		if p.ID == "" {
+			p.ID = bson.NewObjectId()
+			err = profilesDAO.Insert(p)
+		} else {
+			err = profilesDAO.UpdateById(p.ID, p)
+		}
+		check(err, "Error storing profiles.")
+		numSettings += len(p.MSCSettings)
+	}
+	fmt.Printf("%d settings stored\n", numSettings)
+}
+
+func checkProfiles(cmd *cobra.Command, args []string) {
+	repair := cmd.Flag("repair").Value.String()
+	if !validRepairMode(repair) {
+		fmt.Println("Unknown repair " + repair)
+		return
+	}
+	configFile := cmd.Flag("config-file").Value.String()
+	systemConfiguration, _ := util.ReadConfigFile(configFile)
+	profilesDAO := db.GetPerformanceProfileDAO(systemConfiguration.MainServiceName)
+	stored, err := profilesDAO.FindAll()
+	check(err, "No profiles found.")
+
+	profiles, issues := profiles_processing.CheckProfiles(stored, repair)
+	printProfileIssues(issues)
+	if len(issues) == 0 {
+		fmt.Println("No issues found")
+		return
+	}
+	writeToFile(issues)
+	if repair != profiles_processing.REPAIR_NONE {
+		for _, p := range profiles {
+			err = profilesDAO.UpdateById(p.ID, p)
+			check(err, "Error storing profiles.")
+		}
+		fmt.Println("Repaired profiles stored")
+	}
+}
+
+func exportProfiles(cmd *cobra.Command, args []string) {
+	format := cmd.Flag("format").Value.String()
+	if format != profiles_processing.FORMAT_JSON && format != profiles_processing.FORMAT_CSV {
+		fmt.Println("Unknown format " + format)
+		return
+	}
+	fileName := cmd.Flag("output").Value.String()
+	if fileName == "" {
+		fileName = "profiles." + format
+	}
+	configFile := cmd.Flag("config-file").Value.String()
+	systemConfiguration, _ := util.ReadConfigFile(configFile)
+	profilesDAO := db.GetPerformanceProfileDAO(systemConfiguration.MainServiceName)
+	stored, err := profilesDAO.FindAll()
+	check(err, "No profiles found.")
+	profiles := profiles_processing.MergeProfiles(stored, nil)
+
+	file, err := os.Create(fileName)
+	check(err, "Error writing file "+fileName+".")
+	defer file.Close()
+	if format == profiles_processing.FORMAT_CSV {
+		err = profiles_processing.WriteCSVProfiles(file, profiles)
+	} else {
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(profiles)
+	}
+	check(err, "Error writing file "+fileName+".")
+	fmt.Printf("%d profiles exported to %s\n", len(profiles), fileName)
+}
+
+//Limits and replicas of the service under test taken from the flags
+func loadTestSetting(cmd *cobra.Command) (profiles_processing.LoadTestSetting, error) {
+	var setting profiles_processing.LoadTestSetting
+	cores, err := strconv.ParseFloat(cmd.Flag("cpu-cores").Value.String(), 64)
+	if err != nil {
+		return setting, fmt.Errorf("--cpu-cores is required")
+	}
+	memory, err := strconv.ParseFloat(cmd.Flag("mem-gb").Value.String(), 64)
+	if err != nil {
+		return setting, fmt.Errorf("--mem-gb is required")
+	}
+	replicas, err := strconv.Atoi(cmd.Flag("replicas").Value.String())
+	if err != nil {
+		return setting, fmt.Errorf("--replicas is required")
+	}
+	bootTime, err := strconv.ParseFloat(cmd.Flag("boot-time-sec").Value.String(), 64)
+	if err != nil {
+		return setting, fmt.Errorf("--boot-time-sec should be a number")
+	}
+	setting.Limit = types.Limit{CPUCores: cores, MemoryGB: memory}
+	setting.Replicas = replicas
+	setting.BootTimeSec = bootTime
+	return setting, nil
+}
+
+func validRepairMode(repair string) bool {
+	return repair == profiles_processing.REPAIR_NONE || repair == profiles_processing.REPAIR_SMOOTH ||
+		repair == profiles_processing.REPAIR_EXCLUDE
+}
+
+func printProfileIssues(issues []profiles_processing.ProfileIssue) {
+	for _, issue := range issues {
+		fmt.Printf("%.2f cores %.2f GB, %d replicas: %s (%s) %s", issue.Limit.CPUCores, issue.Limit.MemoryGB,
+			issue.Replicas, issue.Kind, issue.Description, issue.Action)
+		if issue.Action == profiles_processing.ACTION_REPAIRED {
+			fmt.Printf(" %.2f -> %.2f", issue.OldValue, issue.NewValue)
+		}
+		fmt.Println()
+	}
+}
@@ -37,6 +37,9 @@ func Execute() {
 	RootCmd.AddCommand(policiesCmd)
 	RootCmd.AddCommand(invalidateCmd)
 	RootCmd.AddCommand(updateProfilesCmd)
+	RootCmd.AddCommand(diffCmd)
+	RootCmd.AddCommand(profilesCmd)
+	RootCmd.AddCommand(vmsCmd)
 
 	if err := RootCmd.Execute(); err != nil {
 		fmt.Println(err)
@@ -0,0 +1,370 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/Cloud-Pie/SPDT/planner/catalog_processing"
+	"github.com/Cloud-Pie/SPDT/server"
+	db "github.com/Cloud-Pie/SPDT/storage"
+	"github.com/Cloud-Pie/SPDT/types"
+	"github.com/Cloud-Pie/SPDT/util"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// vmsCmd groups the commands to manage the VM catalog
+var vmsCmd = &cobra.Command{
+	Use:   "vms",
+	Short: "Manage the VM catalog",
+	Long: `Manage the VM types and prices available per provider and region.
+	The catalog is stored in the profiles database and every change creates a new version`,
+}
+
+// vmsListCmd represents the list of VM types of the catalog
+var vmsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List VM types",
+	Long:  "List the VM types of the latest version of the catalog, or of the version --version",
+	Run:   listVMs,
+}
+
+// vmsAddCmd represents the addition of a VM type to the catalog
+var vmsAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Add a VM type",
+	Long:  "Add a VM type to the catalog",
+	Run:   addVM,
+}
+
+// vmsUpdateCmd represents the change of a VM type of the catalog
+var vmsUpdateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Update a VM type",
+	Long: `Update the values of a VM type of the catalog. Only the values of the flags set are changed.
+	Use --disallowed to exclude a VM type from the derivation and --allowed to use only the allowed types
+	of the provider and region`,
+	Run: updateVM,
+}
+
+// vmsDeleteCmd represents the removal of a VM type from the catalog
+var vmsDeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Delete a VM type",
+	Long:  "Remove a VM type from the catalog",
+	Run:   deleteVM,
+}
+
+// vmsReloadCmd represents the reload of the catalog from a file
+var vmsReloadCmd = &cobra.Command{
+	Use:   "reload",
+	Short: "Reload the VM catalog",
+	Long: `Reload the catalog from a file, default the vm-catalog-file of the configuration.
+	The file is validated and stored as a new version, keeping the allowed and disallowed flags of the VM types`,
+	Run: reloadVMs,
+}
+
+// vmsVersionsCmd represents the list of versions of the catalog
+var vmsVersionsCmd = &cobra.Command{
+	Use:   "versions",
+	Short: "List the VM catalog versions",
+	Long:  "List the versions of the VM catalog, the most recent first",
+	Run:   listVMCatalogVersions,
+}
+
+// vmsImportCmd represents the import of VM types from price list files
+var vmsImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import VM types and prices",
+	Long: `Import VM types and prices from a price list file into the VM catalog.
+	Formats: aws-json and aws-csv (offer files of the AWS Price List bulk API), gcp-json (SKUs of Compute Engine
+	in the Cloud Billing Catalog API), azure-json (Azure Retail Prices API) and
+	csv (type, cpu_cores, mem_gb, price, unit, os, region, family, architecture, current_generation,
+	spot_discount, interruption_rate)`,
+	Run: importVMs,
+}
+
+func init() {
+	vmsImportCmd.Flags().String("file", "", "Path of the price list file")
+	vmsImportCmd.Flags().String("format", catalog_processing.FORMAT_AWS_JSON, "Format of the file: aws-json, aws-csv, gcp-json, azure-json or csv")
+	vmsImportCmd.Flags().String("sizes", "", "Path of the cores and memory of the VM types, required by gcp-json and azure-json")
+	vmsImportCmd.Flags().String("csp", "", "Cloud service provider of the prices. Default the CSP of the configuration")
+	vmsImportCmd.Flags().String("region", "", "Region of the prices. Default the region of the configuration")
+	vmsImportCmd.Flags().String("os", "Linux", "Operating system")
+	vmsImportCmd.Flags().String("families", "", "Comma separated list of VM families. E.g. t3,m5")
+	vmsImportCmd.Flags().Bool("current-generation", false, "Import only current generation types")
+	vmsImportCmd.Flags().String("architecture", "", "Processor architecture: x86_64 or arm64")
+	vmsImportCmd.Flags().Bool("replace", false, "Remove the types of the provider and region that are not imported")
+	vmsImportCmd.Flags().String("output", "", "Path of the VM catalog. Default the vm-catalog-file of the configuration")
+	vmsImportCmd.Flags().Bool("reload", false, "Reload the stored catalog from the output file")
+	vmsImportCmd.Flags().String("config-file", "config.yml", "Configuration file path")
+
+	vmsListCmd.Flags().String("csp", "", "Cloud service provider of the VM types")
+	vmsListCmd.Flags().String("region", "", "Region of the VM types")
+	vmsListCmd.Flags().Int("version", 0, "Version of the catalog. Default the latest")
+	vmsListCmd.Flags().String("config-file", "config.yml", "Configuration file path")
+
+	for _, c := range []*cobra.Command{vmsAddCmd, vmsUpdateCmd, vmsDeleteCmd} {
+		c.Flags().String("csp", "", "Cloud service provider. Default the CSP of the configuration")
+		c.Flags().String("region", "", "Region. Default the region of the configuration")
+		c.Flags().String("type", "", "VM type")
+		c.Flags().String("config-file", "config.yml", "Configuration file path")
+	}
+	for _, c := range []*cobra.Command{vmsAddCmd, vmsUpdateCmd} {
+		c.Flags().Float64("cpu-cores", 0, "Number of cores")
+		c.Flags().Float64("mem-gb", 0, "Memory in GB")
+		c.Flags().Float64("price", 0, "Price")
+		c.Flags().String("unit", types.PRICE_UNIT_HOUR, "Unit of the price: Second, Minute, Hour or Month")
+		c.Flags().String("os", "Linux", "Operating system")
+		c.Flags().Bool("allowed", false, "Use only the allowed VM types of the provider and region")
+		c.Flags().Bool("disallowed", false, "Exclude the VM type from the derivation")
+		c.Flags().Float64("spot-discount", 0, "Discount of the spot variant over the on-demand price, between 0 and 1")
+		c.Flags().Float64("interruption-rate", 0, "Expected interruptions per hour of a spot VM")
+	}
+
+	vmsReloadCmd.Flags().String("file", "", "Path of the VM catalog file. Default the vm-catalog-file of the configuration")
+	vmsReloadCmd.Flags().String("config-file", "config.yml", "Configuration file path")
+
+	vmsVersionsCmd.Flags().String("config-file", "config.yml", "Configuration file path")
+
+	vmsCmd.AddCommand(vmsImportCmd)
+	vmsCmd.AddCommand(vmsListCmd)
+	vmsCmd.AddCommand(vmsAddCmd)
+	vmsCmd.AddCommand(vmsUpdateCmd)
+	vmsCmd.AddCommand(vmsDeleteCmd)
+	vmsCmd.AddCommand(vmsReloadCmd)
+	vmsCmd.AddCommand(vmsVersionsCmd)
+}
+
+func importVMs(cmd *cobra.Command, args []string) {
+	fileName := cmd.Flag("file").Value.String()
+	if fileName == "" {
+		fmt.Println("You need to specify the price list file with the flag --file")
+		return
+	}
+	configFile := cmd.Flag("config-file").Value.String()
+	systemConfiguration, _ := util.ReadConfigFile(configFile)
+	csp := cmd.Flag("csp").Value.String()
+	if csp == "" {
+		csp = systemConfiguration.CSP
+	}
+	region := cmd.Flag("region").Value.String()
+	if region == "" {
+		region = systemConfiguration.Region
+	}
+	currentGeneration, _ := cmd.Flags().GetBool("current-generation")
+	filter := catalog_processing.ImportFilter{
+		Region:            region,
+		OS:                cmd.Flag("os").Value.String(),
+		CurrentGeneration: currentGeneration,
+		Architecture:      cmd.Flag("architecture").Value.String(),
+	}
+	if families := cmd.Flag("families").Value.String(); families != "" {
+		filter.Families = strings.Split(families, ",")
+	}
+
+	file, err := os.Open(fileName)
+	check(err, "Error reading file "+fileName+".")
+	defer file.Close()
+	var vmProfiles []types.VmProfile
+	switch format := cmd.Flag("format").Value.String(); format {
+	case catalog_processing.FORMAT_AWS_JSON:
+		vmProfiles, err = catalog_processing.ReadAWSPriceListJSON(file, filter)
+	case catalog_processing.FORMAT_AWS_CSV:
+		vmProfiles, err = catalog_processing.ReadAWSPriceListCSV(file, filter)
+	case catalog_processing.FORMAT_CSV:
+		vmProfiles, err = catalog_processing.ReadVMsCSV(file, filter)
+	case catalog_processing.FORMAT_GCP_JSON, catalog_processing.FORMAT_AZURE_JSON:
+		sizesFileName := cmd.Flag("sizes").Value.String()
+		if sizesFileName == "" {
+			fmt.Println("You need to specify the file with the cores and memory of the VM types with the flag --sizes")
+			return
+		}
+		sizesFile, errSizes := os.Open(sizesFileName)
+		check(errSizes, "Error reading file "+sizesFileName+".")
+		defer sizesFile.Close()
+		if format == catalog_processing.FORMAT_GCP_JSON {
+			vmProfiles, err = catalog_processing.ReadGCPBillingCatalog(file, sizesFile, filter)
+		} else {
+			vmProfiles, err = catalog_processing.ReadAzureRetailPrices(file, sizesFile, filter)
+		}
+	default:
+		fmt.Println("Unknown format " + format)
+		return
+	}
+	check(err, "No VM types imported from "+fileName+".")
+
+	output := cmd.Flag("output").Value.String()
+	if output == "" {
+		output = systemConfiguration.VMCatalogFile
+	}
+	if output == "" {
+		output = util.DEFAULT_VM_CATALOG_FILE
+	}
+	catalog := types.VMCatalog{}
+	if data, err := ioutil.ReadFile(output); err == nil {
+		catalog, err = catalog_processing.ParseCatalog(data, systemConfiguration.CSP, systemConfiguration.Region)
+		check(err, "Invalid VM catalog "+output+".")
+	}
+	replace, _ := cmd.Flags().GetBool("replace")
+	catalog = catalog_processing.MergeVMProfiles(catalog, csp, region, vmProfiles, replace)
+
+	data, err := json.MarshalIndent(catalog, "", "  ")
+	check(err, "Error writing file "+output+".")
+	err = ioutil.WriteFile(output, data, 0644)
+	check(err, "Error writing file "+output+".")
+	fmt.Printf("%d VM types imported for %s %s in %s\n", len(vmProfiles), csp, region, output)
+
+	if reload, _ := cmd.Flags().GetBool("reload"); reload {
+		printCatalogChange(server.ReloadVMCatalog(systemConfiguration, output))
+	}
+}
+
+func listVMs(cmd *cobra.Command, args []string) {
+	configFile := cmd.Flag("config-file").Value.String()
+	systemConfiguration, _ := util.ReadConfigFile(configFile)
+	var catalogVersion types.VMCatalogVersion
+	var err error
+	if version, _ := cmd.Flags().GetInt("version"); version > 0 {
+		catalogVersion, err = db.GetVMCatalogDAO().FindByVersion(version)
+	} else {
+		catalogVersion, err = db.GetVMCatalogDAO().FindLatest()
+		if err != nil {
+			catalogVersion, _, err = server.ReloadVMCatalog(systemConfiguration, "")
+		}
+	}
+	check(err, "Error reading the VM catalog.")
+
+	csp := cmd.Flag("csp").Value.String()
+	region := cmd.Flag("region").Value.String()
+	fmt.Printf("VM catalog version %d (%s, %s)\n", catalogVersion.Version, catalogVersion.Source,
+		catalogVersion.CreatedAt.Format(util.UTC_TIME_LAYOUT))
+	for _, e := range catalogVersion.Entries {
+		if (csp != "" && !strings.EqualFold(e.CSP, csp)) || (region != "" && !strings.EqualFold(e.Region, region)) {
+			continue
+		}
+		flag := ""
+		if e.Allowed {
+			flag = "allowed"
+		} else if e.Disallowed {
+			flag = "disallowed"
+		}
+		fmt.Printf("%s %s %s: %.2f cores, %.2f GB, %.4f per %s %s\n", e.CSP, e.Region, e.VmProfile.Type,
+			e.VmProfile.CPUCores, e.VmProfile.Memory, e.VmProfile.Pricing.Price, e.VmProfile.Pricing.Unit, flag)
+	}
+}
+
+func addVM(cmd *cobra.Command, args []string) {
+	entry, ok := vmEntryFromFlags(cmd)
+	if !ok {
+		return
+	}
+	entry.VmProfile.CPUCores, _ = cmd.Flags().GetFloat64("cpu-cores")
+	entry.VmProfile.Memory, _ = cmd.Flags().GetFloat64("mem-gb")
+	entry.VmProfile.Pricing.Price, _ = cmd.Flags().GetFloat64("price")
+	entry.VmProfile.Pricing.Unit = cmd.Flag("unit").Value.String()
+	entry.VmProfile.OS = cmd.Flag("os").Value.String()
+	entry.Allowed, _ = cmd.Flags().GetBool("allowed")
+	entry.Disallowed, _ = cmd.Flags().GetBool("disallowed")
+	entry.VmProfile.Spot.Discount, _ = cmd.Flags().GetFloat64("spot-discount")
+	entry.VmProfile.Spot.InterruptionRate, _ = cmd.Flags().GetFloat64("interruption-rate")
+	printCatalogChange(server.UpdateVMCatalog(server.VM_CATALOG_SOURCE_CLI, server.PutVMCatalogEntry(entry, true)))
+}
+
+func updateVM(cmd *cobra.Command, args []string) {
+	key, ok := vmEntryFromFlags(cmd)
+	if !ok {
+		return
+	}
+	change := func(entries []types.VMCatalogEntry) ([]types.VMCatalogEntry, error) {
+		i := catalog_processing.FindEntry(entries, key.CSP, key.Region, key.VmProfile.Type)
+		if i < 0 {
+			return entries, fmt.Errorf("VM type %s not found", key.VmProfile.Type)
+		}
+		entry := entries[i]
+		flags := cmd.Flags()
+		if flags.Changed("cpu-cores") {
+			entry.VmProfile.CPUCores, _ = flags.GetFloat64("cpu-cores")
+		}
+		if flags.Changed("mem-gb") {
+			entry.VmProfile.Memory, _ = flags.GetFloat64("mem-gb")
+		}
+		if flags.Changed("price") {
+			entry.VmProfile.Pricing.Price, _ = flags.GetFloat64("price")
+		}
+		if flags.Changed("unit") {
+			entry.VmProfile.Pricing.Unit = cmd.Flag("unit").Value.String()
+		}
+		if flags.Changed("os") {
+			entry.VmProfile.OS = cmd.Flag("os").Value.String()
+		}
+		if flags.Changed("allowed") {
+			entry.Allowed, _ = flags.GetBool("allowed")
+		}
+		if flags.Changed("disallowed") {
+			entry.Disallowed, _ = flags.GetBool("disallowed")
+		}
+		if flags.Changed("spot-discount") {
+			entry.VmProfile.Spot.Discount, _ = flags.GetFloat64("spot-discount")
+		}
+		if flags.Changed("interruption-rate") {
+			entry.VmProfile.Spot.InterruptionRate, _ = flags.GetFloat64("interruption-rate")
+		}
+		return server.PutVMCatalogEntry(entry, false)(entries)
+	}
+	printCatalogChange(server.UpdateVMCatalog(server.VM_CATALOG_SOURCE_CLI, change))
+}
+
+func deleteVM(cmd *cobra.Command, args []string) {
+	key, ok := vmEntryFromFlags(cmd)
+	if !ok {
+		return
+	}
+	printCatalogChange(server.UpdateVMCatalog(server.VM_CATALOG_SOURCE_CLI,
+		server.DeleteVMCatalogEntry(key.CSP, key.Region, key.VmProfile.Type)))
+}
+
+func reloadVMs(cmd *cobra.Command, args []string) {
+	configFile := cmd.Flag("config-file").Value.String()
+	systemConfiguration, _ := util.ReadConfigFile(configFile)
+	printCatalogChange(server.ReloadVMCatalog(systemConfiguration, cmd.Flag("file").Value.String()))
+}
+
+func listVMCatalogVersions(cmd *cobra.Command, args []string) {
+	catalogVersions, err := db.GetVMCatalogDAO().FindVersions()
+	check(err, "Error reading the VM catalog versions.")
+	for _, v := range catalogVersions {
+		fmt.Printf("%d\t%s\t%s\n", v.Version, v.CreatedAt.Format(util.UTC_TIME_LAYOUT), v.Source)
+	}
+}
+
+//Provider, region and type of the VM type selected with the flags
+func vmEntryFromFlags(cmd *cobra.Command) (types.VMCatalogEntry, bool) {
+	entry := types.VMCatalogEntry{}
+	entry.VmProfile.Type = cmd.Flag("type").Value.String()
+	if entry.VmProfile.Type == "" {
+		fmt.Println("You need to specify the VM type with the flag --type")
+		return entry, false
+	}
+	configFile := cmd.Flag("config-file").Value.String()
+	systemConfiguration, _ := util.ReadConfigFile(configFile)
+	entry.CSP = cmd.Flag("csp").Value.String()
+	if entry.CSP == "" {
+		entry.CSP = systemConfiguration.CSP
+	}
+	entry.Region = cmd.Flag("region").Value.String()
+	if entry.Region == "" {
+		entry.Region = systemConfiguration.Region
+	}
+	return entry, true
+}
+
+func printCatalogChange(catalogVersion types.VMCatalogVersion, created bool, err error) {
+	check(err, "The VM catalog was not changed.")
+	if !created {
+		fmt.Printf("No changes, the VM catalog is still version %d\n", catalogVersion.Version)
+		return
+	}
+	fmt.Printf("VM catalog version %d created with %d VM types\n", catalogVersion.Version, len(catalogVersion.Entries))
+}
@@ -11,6 +11,7 @@ require (
 	github.com/mattn/go-isatty v0.0.12 // indirect
 	github.com/modern-go/concurrent v0.0.0-20180306012644-bacd9c7ef1dd // indirect
 	github.com/op/go-logging v0.0.0-20160211212156-b2cb9fa56473
+	github.com/prometheus/client_golang v1.4.1
 	github.com/spf13/cobra v0.0.5
 	github.com/spf13/pflag v1.0.5 // indirect
 	golang.org/x/sys v0.0.0-20200124204421-9fbb57f87de9 // indirect
@@ -0,0 +1,40 @@
+package monitoring
+
+import (
+	"net/http"
+	"time"
+)
+
+//Components reached through the instrumented clients
+const (
+	COMPONENT_FORECAST             = "forecast"
+	COMPONENT_PERFORMANCE_PROFILES = "performance_profiles"
+	COMPONENT_SCHEDULER            = "scheduler"
+	COMPONENT_TIME_SERIE           = "time_serie_processing"
+)
+
+//RoundTripper that records latency and errors of every request sent to a component
+type instrumentedTransport struct {
+	component string
+	next      http.RoundTripper
+}
+
+func (t instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	failed := err != nil || resp.StatusCode >= http.StatusBadRequest
+	ObserveRemoteRequest(t.component, req.Method, start, failed)
+	return resp, err
+}
+
+/* Create an http client whose requests are published as metrics
+	in:
+		@component	- Name of the remote component reached with the client
+	out:
+		@*http.Client
+*/
+func NewInstrumentedClient(component string) *http.Client {
+	return &http.Client{
+		Transport: instrumentedTransport{component: component, next: http.DefaultTransport},
+	}
+}
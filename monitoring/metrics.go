@@ -0,0 +1,202 @@
+package monitoring
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const namespace = "spdt"
+
+//Labels used for the threshold violations
+const (
+	VIOLATION_OVER_CAPACITY  = "over_capacity"
+	VIOLATION_UNDER_CAPACITY = "under_capacity"
+)
+
+//Outcomes of a forecast update
+const (
+	FORECAST_UPDATE_RECEIVED    = "received"
+	FORECAST_UPDATE_VALID       = "policy_valid"
+	FORECAST_UPDATE_INVALIDATED = "policy_invalidated"
+	FORECAST_UPDATE_FAILED      = "failed"
+	FORECAST_UPDATE_REJECTED    = "rejected"
+	FORECAST_UPDATE_DUPLICATED  = "duplicated"
+)
+
+//Label of the metrics of services that are not configured
+const SERVICE_OTHER = "other"
+
+//Services accepted as label values and algorithm of the last policy published for each one
+var (
+	servicesLock      sync.Mutex
+	services          = map[string]bool{}
+	selectedAlgorithm = map[string]string{}
+)
+
+var (
+	derivationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "derivation_duration_seconds",
+		Help:      "Time spent deriving a candidate policy, per algorithm.",
+		Buckets:   []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+	}, []string{"service", "algorithm"})
+
+	selectedPolicyCost = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "selected_policy_cost",
+		Help:      "Cost of the last selected policy.",
+	}, []string{"service", "algorithm"})
+
+	selectedPolicyOverProvision = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "selected_policy_over_provision_percent",
+		Help:      "Predicted average over provisioning of the last selected policy.",
+	}, []string{"service", "algorithm"})
+
+	selectedPolicyUnderProvision = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "selected_policy_under_provision_percent",
+		Help:      "Predicted average under provisioning of the last selected policy.",
+	}, []string{"service", "algorithm"})
+
+	selectedPolicyScalingActions = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "selected_policy_scaling_actions",
+		Help:      "Number of scaling actions of the last selected policy.",
+	}, []string{"service", "algorithm"})
+
+	forecastUpdates = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "forecast_updates_total",
+		Help:      "Forecast update events, by outcome.",
+	}, []string{"service", "outcome"})
+
+	thresholdViolations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "threshold_violations_total",
+		Help:      "Forecast points found outside the capacity band of a scaling action.",
+	}, []string{"service", "direction"})
+
+	driftEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "drift_events_total",
+		Help:      "Differences found between the infrastructure and the state expected by the selected policy.",
+	}, []string{"service", "kind"})
+
+	remoteRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "remote_request_duration_seconds",
+		Help:      "Latency of the requests to the remote components.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"component", "method"})
+
+	remoteRequestErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "remote_request_errors_total",
+		Help:      "Failed requests to the remote components.",
+	}, []string{"component", "method"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		derivationDuration,
+		selectedPolicyCost,
+		selectedPolicyOverProvision,
+		selectedPolicyUnderProvision,
+		selectedPolicyScalingActions,
+		forecastUpdates,
+		thresholdViolations,
+		driftEvents,
+		remoteRequestDuration,
+		remoteRequestErrors,
+	)
+}
+
+//Handler that exposes the registered metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+/* Set the services whose name is used as label value.
+	Service names come also from pushed forecasts, the metrics of any other service are labelled SERVICE_OTHER
+	so the number of series stays bounded
+	in:
+		@names	- Names of the configured services
+*/
+func SetServices(names ...string) {
+	servicesLock.Lock()
+	defer servicesLock.Unlock()
+	services = map[string]bool{}
+	for _, name := range names {
+		services[name] = true
+	}
+}
+
+//Label value of a service
+func serviceLabel(service string) string {
+	servicesLock.Lock()
+	defer servicesLock.Unlock()
+	if services[service] {
+		return service
+	}
+	return SERVICE_OTHER
+}
+
+//Record how long the derivation of a candidate policy took
+func ObserveDerivation(service string, algorithm string, durationSec float64) {
+	derivationDuration.WithLabelValues(serviceLabel(service), algorithm).Observe(durationSec)
+}
+
+/* Publish the metrics of the policy selected for a service.
+	The metrics of the policy previously selected for the same service are removed
+	in:
+		@service	- Name of the scaled service
+		@algorithm	- Algorithm used to derive the policy
+		@cost	- Total cost of the policy
+		@overProvision	- Average over provisioning (%)
+		@underProvision	- Average under provisioning (%)
+		@scalingActions	- Number of scaling actions
+*/
+func SetSelectedPolicy(service string, algorithm string, cost float64, overProvision float64,
+	underProvision float64, scalingActions int) {
+	service = serviceLabel(service)
+	servicesLock.Lock()
+	defer servicesLock.Unlock()
+	if previous, ok := selectedAlgorithm[service]; ok && previous != algorithm {
+		selectedPolicyCost.DeleteLabelValues(service, previous)
+		selectedPolicyOverProvision.DeleteLabelValues(service, previous)
+		selectedPolicyUnderProvision.DeleteLabelValues(service, previous)
+		selectedPolicyScalingActions.DeleteLabelValues(service, previous)
+	}
+	selectedAlgorithm[service] = algorithm
+	selectedPolicyCost.WithLabelValues(service, algorithm).Set(cost)
+	selectedPolicyOverProvision.WithLabelValues(service, algorithm).Set(overProvision)
+	selectedPolicyUnderProvision.WithLabelValues(service, algorithm).Set(underProvision)
+	selectedPolicyScalingActions.WithLabelValues(service, algorithm).Set(float64(scalingActions))
+}
+
+//Count a forecast update event with its outcome
+func ForecastUpdate(service string, outcome string) {
+	forecastUpdates.WithLabelValues(serviceLabel(service), outcome).Inc()
+}
+
+//Count a forecast point outside the capacity band of a scaling action
+func ThresholdViolation(service string, direction string) {
+	thresholdViolations.WithLabelValues(serviceLabel(service), direction).Inc()
+}
+
+//Count a drift between the infrastructure and the selected policy
+func DriftDetected(service string, kind string) {
+	driftEvents.WithLabelValues(serviceLabel(service), kind).Inc()
+}
+
+//Record the latency of a request to a remote component and whether it failed
+func ObserveRemoteRequest(component string, method string, start time.Time, failed bool) {
+	remoteRequestDuration.WithLabelValues(component, method).Observe(time.Since(start).Seconds())
+	if failed {
+		remoteRequestErrors.WithLabelValues(component, method).Inc()
+	}
+}
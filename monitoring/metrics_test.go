@@ -0,0 +1,93 @@
+package monitoring
+
+import (
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"testing"
+)
+
+func TestServiceLabel(t *testing.T) {
+	SetServices("web", "api")
+	defer SetServices()
+
+	var tests = []struct {
+		service  string
+		expected string
+	}{
+		{"web", "web"},
+		{"api", "api"},
+		{"unknown-service", SERVICE_OTHER},
+		{"", SERVICE_OTHER},
+	}
+	for _, test := range tests {
+		label := serviceLabel(test.service)
+		if label != test.expected {
+			t.Error(
+				"For: ", test.service,
+				"expected: ", test.expected,
+				"got: ", label,
+			)
+		}
+	}
+}
+
+func TestSetSelectedPolicy(t *testing.T) {
+	SetServices("web", "api")
+	defer SetServices()
+	defer selectedPolicyCost.Reset()
+
+	SetSelectedPolicy("web", "naive", 10, 5, 1, 3)
+	SetSelectedPolicy("api", "naive", 20, 5, 1, 3)
+	//A new policy of a service replaces only the metrics of that service
+	SetSelectedPolicy("web", "always-resize", 8, 4, 0, 2)
+
+	if n := testutil.CollectAndCount(selectedPolicyCost); n != 2 {
+		t.Error(
+			"For: ", "selected policies of web and api",
+			"expected: ", 2,
+			"got: ", n,
+		)
+	}
+	var tests = []struct {
+		service   string
+		algorithm string
+		cost      float64
+	}{
+		{"web", "always-resize", 8},
+		{"api", "naive", 20},
+	}
+	for _, test := range tests {
+		cost := testutil.ToFloat64(selectedPolicyCost.WithLabelValues(test.service, test.algorithm))
+		if cost != test.cost {
+			t.Error(
+				"For: ", test.service, test.algorithm,
+				"expected: ", test.cost,
+				"got: ", cost,
+			)
+		}
+	}
+}
+
+func TestForecastUpdateOfUnknownService(t *testing.T) {
+	SetServices("web")
+	defer SetServices()
+	defer forecastUpdates.Reset()
+
+	ForecastUpdate("web", FORECAST_UPDATE_RECEIVED)
+	ForecastUpdate("pushed-1", FORECAST_UPDATE_RECEIVED)
+	ForecastUpdate("pushed-2", FORECAST_UPDATE_RECEIVED)
+
+	if n := testutil.CollectAndCount(forecastUpdates); n != 2 {
+		t.Error(
+			"For: ", "updates of web and two unknown services",
+			"expected: ", 2,
+			"got: ", n,
+		)
+	}
+	if n := testutil.ToFloat64(forecastUpdates.WithLabelValues(SERVICE_OTHER, FORECAST_UPDATE_RECEIVED)); n != 2 {
+		t.Error(
+			"For: ", "updates of unknown services",
+			"expected: ", 2,
+			"got: ", n,
+		)
+	}
+}
@@ -0,0 +1,166 @@
+package catalog_processing
+
+import (
+	"fmt"
+	"github.com/Cloud-Pie/SPDT/types"
+	"sort"
+	"strings"
+)
+
+/* List the VM types of a catalog as catalog entries, sorted by provider, region and type.
+	The entries are neither allowed nor disallowed
+	in:
+		@catalog types.VMCatalog
+	out:
+		@[]types.VMCatalogEntry
+*/
+func CatalogEntries(catalog types.VMCatalog) []types.VMCatalogEntry {
+	entries := []types.VMCatalogEntry{}
+	for csp, regions := range catalog {
+		for region, vms := range regions {
+			for _, vm := range vms {
+				entries = append(entries, types.VMCatalogEntry{CSP: csp, Region: region, VmProfile: vm})
+			}
+		}
+	}
+	sortEntries(entries)
+	return entries
+}
+
+/* Check that the entries can be used as VM catalog.
+	Every entry needs provider, region, type, cores, memory, a valid price and valid spot values, and a VM type
+	can not be repeated or be allowed and disallowed at the same time
+	in:
+		@entries []types.VMCatalogEntry
+	out:
+		@error	- First invalid entry found
+*/
+func ValidateEntries(entries []types.VMCatalogEntry) error {
+	if len(entries) == 0 {
+		return fmt.Errorf("The catalog has no VM types")
+	}
+	seen := map[string]bool{}
+	for _, e := range entries {
+		name := e.CSP + " " + e.Region + " " + e.VmProfile.Type
+		switch {
+		case e.CSP == "" || e.Region == "" || e.VmProfile.Type == "":
+			return fmt.Errorf("VM type %s: provider, region and type are required", name)
+		case e.VmProfile.CPUCores <= 0 || e.VmProfile.Memory <= 0:
+			return fmt.Errorf("VM type %s: cores and memory should be positive", name)
+		case e.Allowed && e.Disallowed:
+			return fmt.Errorf("VM type %s: can not be allowed and disallowed", name)
+		case types.IsSpotType(e.VmProfile.Type):
+			return fmt.Errorf("VM type %s: the suffix %s is reserved for spot variants", name, types.SPOT_SUFFIX)
+		case e.VmProfile.Spot.Discount < 0 || e.VmProfile.Spot.Discount >= 1 || e.VmProfile.Spot.InterruptionRate < 0:
+			return fmt.Errorf("VM type %s: the spot discount should be between 0 and 1 and the interruption rate positive", name)
+		}
+		if _, err := NormalizePricing(e.VmProfile.Pricing); err != nil {
+			return fmt.Errorf("VM type %s: %s", name, err.Error())
+		}
+		key := entryKey(e.CSP, e.Region, e.VmProfile.Type)
+		if seen[key] {
+			return fmt.Errorf("VM type %s is repeated", name)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+/* Build the catalog with the VM types that can be used in the derivation.
+	Disallowed types are excluded. If some type of a provider and region is allowed,
+	only the allowed types of that provider and region are included
+	in:
+		@entries []types.VMCatalogEntry
+	out:
+		@types.VMCatalog
+*/
+func UsableCatalog(entries []types.VMCatalogEntry) types.VMCatalog {
+	onlyAllowed := map[string]bool{}
+	for _, e := range entries {
+		if e.Allowed {
+			onlyAllowed[entryKey(e.CSP, e.Region, "")] = true
+		}
+	}
+	catalog := types.VMCatalog{}
+	for _, e := range entries {
+		if e.Disallowed || (onlyAllowed[entryKey(e.CSP, e.Region, "")] && !e.Allowed) {
+			continue
+		}
+		if _, ok := catalog[e.CSP]; !ok {
+			catalog[e.CSP] = map[string][]types.VmProfile{}
+		}
+		catalog[e.CSP][e.Region] = append(catalog[e.CSP][e.Region], e.VmProfile)
+	}
+	return catalog
+}
+
+/* Find the entry of a VM type, matching provider, region and type ignoring case
+	in:
+		@entries []types.VMCatalogEntry
+		@csp string
+		@region string
+		@vmType string
+	out:
+		@int	- Index of the entry, -1 if it is not found
+*/
+func FindEntry(entries []types.VMCatalogEntry, csp string, region string, vmType string) int {
+	key := entryKey(csp, region, vmType)
+	for i, e := range entries {
+		if entryKey(e.CSP, e.Region, e.VmProfile.Type) == key {
+			return i
+		}
+	}
+	return -1
+}
+
+/* Copy the allowed and disallowed flags of the current entries to the entries reloaded from a file
+	in:
+		@current []types.VMCatalogEntry
+		@reloaded []types.VMCatalogEntry
+	out:
+		@[]types.VMCatalogEntry
+*/
+func KeepEntryFlags(current []types.VMCatalogEntry, reloaded []types.VMCatalogEntry) []types.VMCatalogEntry {
+	entries := append([]types.VMCatalogEntry{}, reloaded...)
+	for i, e := range entries {
+		if j := FindEntry(current, e.CSP, e.Region, e.VmProfile.Type); j >= 0 {
+			entries[i].Allowed = current[j].Allowed
+			entries[i].Disallowed = current[j].Disallowed
+		}
+	}
+	return entries
+}
+
+/* Check if two sets of entries are equal regardless of their order
+	in:
+		@a []types.VMCatalogEntry
+		@b []types.VMCatalogEntry
+	out:
+		@bool
+*/
+func SameEntries(a []types.VMCatalogEntry, b []types.VMCatalogEntry) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	sortedA := append([]types.VMCatalogEntry{}, a...)
+	sortedB := append([]types.VMCatalogEntry{}, b...)
+	sortEntries(sortedA)
+	sortEntries(sortedB)
+	for i := range sortedA {
+		if sortedA[i] != sortedB[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func sortEntries(entries []types.VMCatalogEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		return entryKey(entries[i].CSP, entries[i].Region, entries[i].VmProfile.Type) <
+			entryKey(entries[j].CSP, entries[j].Region, entries[j].VmProfile.Type)
+	})
+}
+
+func entryKey(csp string, region string, vmType string) string {
+	return strings.ToLower(csp) + "/" + strings.ToLower(region) + "/" + vmType
+}
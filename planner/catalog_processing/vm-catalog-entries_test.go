@@ -0,0 +1,39 @@
+package catalog_processing
+
+import (
+	"github.com/Cloud-Pie/SPDT/types"
+	"testing"
+)
+
+func TestUsableCatalog(t *testing.T) {
+	vm := func(vmType string) types.VmProfile {
+		return types.VmProfile{Type: vmType, CPUCores: 2, Memory: 4, Pricing: types.Pricing{Price: 0.1, Unit: "Hour"}}
+	}
+	entries := []types.VMCatalogEntry{
+		{CSP: "AWS", Region: "us-east-2", VmProfile: vm("t2.large")},
+		{CSP: "AWS", Region: "us-east-2", VmProfile: vm("t2.micro"), Disallowed: true},
+		{CSP: "AWS", Region: "eu-west-1", VmProfile: vm("m5.large"), Allowed: true},
+		{CSP: "AWS", Region: "eu-west-1", VmProfile: vm("m5.xlarge")},
+	}
+	if err := ValidateEntries(entries); err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+
+	catalog := UsableCatalog(entries)
+	if vms := catalog["AWS"]["us-east-2"]; len(vms) != 1 || vms[0].Type != "t2.large" {
+		t.Errorf("Expected the disallowed type excluded, got %v", vms)
+	}
+	if vms := catalog["AWS"]["eu-west-1"]; len(vms) != 1 || vms[0].Type != "m5.large" {
+		t.Errorf("Expected only the allowed type, got %v", vms)
+	}
+
+	reloaded := KeepEntryFlags(entries, []types.VMCatalogEntry{{CSP: "aws", Region: "us-east-2", VmProfile: vm("t2.micro")}})
+	if !reloaded[0].Disallowed {
+		t.Errorf("Expected the disallowed flag kept after the reload")
+	}
+
+	repeated := append(entries, types.VMCatalogEntry{CSP: "aws", Region: "US-EAST-2", VmProfile: vm("t2.large")})
+	if err := ValidateEntries(repeated); err == nil {
+		t.Errorf("Expected error for a repeated VM type")
+	}
+}
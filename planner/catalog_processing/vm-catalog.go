@@ -0,0 +1,105 @@
+package catalog_processing
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Cloud-Pie/SPDT/types"
+	"github.com/Cloud-Pie/SPDT/util"
+	"sort"
+	"strings"
+)
+
+/* Parse a VM catalog.
+	The catalog groups the VM types by cloud service provider and region. A flat list of VM types,
+	the format used before the catalog, is read as the types of the given provider and region
+	in:
+		@data []byte	- JSON content of the catalog
+		@csp string	- Provider of a flat list
+		@region string	- Region of a flat list
+	out:
+		@types.VMCatalog
+		@error
+*/
+func ParseCatalog(data []byte, csp string, region string) (types.VMCatalog, error) {
+	var catalog types.VMCatalog
+	if strings.HasPrefix(strings.TrimSpace(string(data)), "[") {
+		var vmProfiles []types.VmProfile
+		if err := json.Unmarshal(data, &vmProfiles); err != nil {
+			return catalog, err
+		}
+		catalog = types.VMCatalog{csp: {region: vmProfiles}}
+		return catalog, nil
+	}
+	err := json.Unmarshal(data, &catalog)
+	return catalog, err
+}
+
+/* Select the VM types of a provider and region with the prices normalized per hour.
+	Providers and regions are matched ignoring case
+	in:
+		@catalog types.VMCatalog
+		@csp string
+		@region string
+	out:
+		@[]types.VmProfile	- VM types sorted by price
+		@error	- If the catalog has no types for the provider and region or a price unit is unknown
+*/
+func SelectVMProfiles(catalog types.VMCatalog, csp string, region string) ([]types.VmProfile, error) {
+	var vmProfiles []types.VmProfile
+	for catalogCSP, regions := range catalog {
+		if !strings.EqualFold(catalogCSP, csp) {
+			continue
+		}
+		for catalogRegion, vms := range regions {
+			if strings.EqualFold(catalogRegion, region) {
+				vmProfiles = append(vmProfiles, vms...)
+			}
+		}
+	}
+	if len(vmProfiles) == 0 {
+		return vmProfiles, fmt.Errorf("No VM types in the catalog for %s %s", csp, region)
+	}
+
+	for i, vm := range vmProfiles {
+		pricing, err := NormalizePricing(vm.Pricing)
+		if err != nil {
+			return nil, fmt.Errorf("VM type %s: %s", vm.Type, err.Error())
+		}
+		vmProfiles[i].Pricing = pricing
+	}
+	sort.Slice(vmProfiles, func(i, j int) bool {
+		return vmProfiles[i].Pricing.Price <= vmProfiles[j].Pricing.Price
+	})
+	return vmProfiles, nil
+}
+
+/* Convert a price to price per hour, the unit used in the cost calculation.
+	Accepts the units Second, Minute, Hour and Month, ignoring case, or their abbreviations s, m, h and M.
+	A price without unit is taken as price per hour
+	in:
+		@pricing types.Pricing
+	out:
+		@types.Pricing	- Price per hour
+		@error
+*/
+func NormalizePricing(pricing types.Pricing) (types.Pricing, error) {
+	if pricing.Price < 0 {
+		return pricing, errors.New("price should not be negative")
+	}
+	var factor float64
+	switch {
+	case pricing.Unit == "" || pricing.Unit == util.HOUR || strings.EqualFold(pricing.Unit, types.PRICE_UNIT_HOUR) ||
+		strings.EqualFold(pricing.Unit, "Hrs"):
+		factor = 1
+	case pricing.Unit == util.SECOND || strings.EqualFold(pricing.Unit, types.PRICE_UNIT_SECOND):
+		factor = 3600
+	case pricing.Unit == util.MINUTE || strings.EqualFold(pricing.Unit, types.PRICE_UNIT_MINUTE):
+		factor = 60
+	case pricing.Unit == util.MONTH || strings.EqualFold(pricing.Unit, types.PRICE_UNIT_MONTH):
+		factor = 1 / (float64(util.ParseIntervalToSeconds("1"+util.MONTH)) / 3600)
+	default:
+		return pricing, fmt.Errorf("unknown price unit %s", pricing.Unit)
+	}
+	return types.Pricing{Price: pricing.Price * factor, Unit: types.PRICE_UNIT_HOUR}, nil
+}
@@ -0,0 +1,44 @@
+package catalog_processing
+
+import (
+	"github.com/Cloud-Pie/SPDT/types"
+	"math"
+	"testing"
+)
+
+func TestSelectVMProfiles(t *testing.T) {
+	data := []byte(`{
+		"AWS": {"us-east-2": [{"type": "t2.large", "pricing": {"price": 0.0928, "unit": "Hour"}},
+			{"type": "t2.micro", "pricing": {"price": 8.468, "unit": "Month"}}]},
+		"GCP": {"europe-west3": [{"type": "n1-standard-1", "pricing": {"price": 0.00001, "unit": "Second"}}]}
+	}`)
+	catalog, err := ParseCatalog(data, "AWS", "us-east-2")
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+
+	vmProfiles, err := SelectVMProfiles(catalog, "aws", "US-EAST-2")
+	if err != nil || len(vmProfiles) != 2 {
+		t.Fatalf("Expected 2 VM types for AWS us-east-2, got %v %v", vmProfiles, err)
+	}
+	if vmProfiles[0].Type != "t2.micro" || math.Abs(vmProfiles[0].Pricing.Price-0.01159) > 0.0001 {
+		t.Errorf("Expected t2.micro first with its monthly price per hour, got %v", vmProfiles[0])
+	}
+
+	vmProfiles, _ = SelectVMProfiles(catalog, "GCP", "europe-west3")
+	if math.Abs(vmProfiles[0].Pricing.Price-0.036) > 0.000001 || vmProfiles[0].Pricing.Unit != types.PRICE_UNIT_HOUR {
+		t.Errorf("Expected the price per second normalized per hour, got %v", vmProfiles[0].Pricing)
+	}
+
+	if _, err := SelectVMProfiles(catalog, "Azure", "westeurope"); err == nil {
+		t.Errorf("Expected error for a provider without VM types")
+	}
+}
+
+func TestParseFlatCatalog(t *testing.T) {
+	data := []byte(`[{"type": "t2.nano", "pricing": {"price": 0.0058, "unit": "Hour"}}]`)
+	catalog, err := ParseCatalog(data, "AWS", "us-east-2")
+	if err != nil || len(catalog["AWS"]["us-east-2"]) != 1 {
+		t.Errorf("Expected the flat list as the types of AWS us-east-2, got %v %v", catalog, err)
+	}
+}
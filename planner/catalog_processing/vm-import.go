@@ -0,0 +1,623 @@
+package catalog_processing
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Cloud-Pie/SPDT/types"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//Formats of the price list files
+const (
+	FORMAT_AWS_JSON = "aws-json"
+	FORMAT_AWS_CSV = "aws-csv"
+	FORMAT_CSV = "csv"
+	FORMAT_GCP_JSON = "gcp-json"
+	FORMAT_AZURE_JSON = "azure-json"
+)
+
+//Processor architectures
+const (
+	ARCHITECTURE_X86 = "x86_64"
+	ARCHITECTURE_ARM = "arm64"
+)
+
+//Conditions that the imported VM types should fulfill. Empty fields do not filter
+type ImportFilter struct {
+	Region            string
+	OS                string
+	Families          []string
+	CurrentGeneration bool
+	Architecture      string
+}
+
+//VM type read from a price list before filtering
+type priceListEntry struct {
+	vmProfile         types.VmProfile
+	region            string
+	family            string
+	currentGeneration bool
+	architecture      string
+}
+
+//Offer file of the AWS Price List bulk API
+type awsOffer struct {
+	Products map[string]struct {
+		Sku           string            `json:"sku"`
+		ProductFamily string            `json:"productFamily"`
+		Attributes    map[string]string `json:"attributes"`
+	} `json:"products"`
+	Terms struct {
+		OnDemand map[string]map[string]struct {
+			PriceDimensions map[string]struct {
+				Unit         string            `json:"unit"`
+				PricePerUnit map[string]string `json:"pricePerUnit"`
+			} `json:"priceDimensions"`
+		} `json:"OnDemand"`
+	} `json:"terms"`
+}
+
+//Response of the Cloud Billing Catalog API listing the SKUs of Compute Engine
+type gcpBillingCatalog struct {
+	Skus []struct {
+		Description string `json:"description"`
+		Category    struct {
+			ResourceFamily string `json:"resourceFamily"`
+			UsageType      string `json:"usageType"`
+		} `json:"category"`
+		ServiceRegions []string `json:"serviceRegions"`
+		PricingInfo    []struct {
+			PricingExpression struct {
+				UsageUnit   string `json:"usageUnit"`
+				TieredRates []struct {
+					StartUsageAmount float64 `json:"startUsageAmount"`
+					UnitPrice        struct {
+						Units string `json:"units"`
+						Nanos int64  `json:"nanos"`
+					} `json:"unitPrice"`
+				} `json:"tieredRates"`
+			} `json:"pricingExpression"`
+		} `json:"pricingInfo"`
+	} `json:"skus"`
+}
+
+//Machine type listed by gcloud compute machine-types list --format=json
+type gcpMachineType struct {
+	Name        string  `json:"name"`
+	GuestCpus   float64 `json:"guestCpus"`
+	MemoryMb    float64 `json:"memoryMb"`
+	IsSharedCpu bool    `json:"isSharedCpu"`
+}
+
+//Response of the Azure Retail Prices API
+type azureRetailPrices struct {
+	Items []struct {
+		RetailPrice   float64 `json:"retailPrice"`
+		ArmRegionName string  `json:"armRegionName"`
+		ProductName   string  `json:"productName"`
+		SkuName       string  `json:"skuName"`
+		ServiceName   string  `json:"serviceName"`
+		ArmSkuName    string  `json:"armSkuName"`
+		Type          string  `json:"type"`
+		UnitOfMeasure string  `json:"unitOfMeasure"`
+	} `json:"Items"`
+}
+
+//VM size listed by az vm list-sizes --output json
+type azureVMSize struct {
+	Name          string  `json:"name"`
+	NumberOfCores float64 `json:"numberOfCores"`
+	MemoryInMb    float64 `json:"memoryInMb"`
+}
+
+/* Read the VM types from an offer file of the AWS Price List bulk API in JSON format.
+	Only on-demand prices of shared instances without pre-installed software are read
+	in:
+		@reader io.Reader
+		@filter ImportFilter
+	out:
+		@[]types.VmProfile	- VM types sorted by price per hour
+		@error
+*/
+func ReadAWSPriceListJSON(reader io.Reader, filter ImportFilter) ([]types.VmProfile, error) {
+	var offer awsOffer
+	if err := json.NewDecoder(reader).Decode(&offer); err != nil {
+		return nil, err
+	}
+	entries := []priceListEntry{}
+	for sku, product := range offer.Products {
+		if product.ProductFamily != "Compute Instance" || !awsDefaultOffer(product.Attributes) {
+			continue
+		}
+		for _, term := range offer.Terms.OnDemand[sku] {
+			for _, dimension := range term.PriceDimensions {
+				price, err := strconv.ParseFloat(dimension.PricePerUnit["USD"], 64)
+				if err != nil {
+					continue
+				}
+				entry, err := awsEntry(product.Attributes, price, dimension.Unit)
+				if err != nil {
+					return nil, fmt.Errorf("sku %s: %s", sku, err.Error())
+				}
+				entries = append(entries, entry)
+			}
+		}
+	}
+	return filterEntries(entries, filter)
+}
+
+/* Read the VM types from an offer file of the AWS Price List bulk API in CSV format.
+	The metadata lines before the header are skipped
+	in:
+		@reader io.Reader
+		@filter ImportFilter
+	out:
+		@[]types.VmProfile	- VM types sorted by price per hour
+		@error
+*/
+func ReadAWSPriceListCSV(reader io.Reader, filter ImportFilter) ([]types.VmProfile, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+	rows, err := csvReader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	header := -1
+	for i, row := range rows {
+		if len(row) > 0 && row[0] == "SKU" {
+			header = i
+			break
+		}
+	}
+	if header < 0 {
+		return nil, errors.New("header with the column SKU not found")
+	}
+	//Columns of the csv mapped to the attributes of the json offer file
+	attributeNames := map[string]string{
+		"Instance Type": "instanceType", "vCPU": "vcpu", "Memory": "memory", "Operating System": "operatingSystem",
+		"Region Code": "regionCode", "Tenancy": "tenancy", "Pre Installed S/W": "preInstalledSw",
+		"CapacityStatus": "capacitystatus", "Current Generation": "currentGeneration",
+		"Physical Processor": "physicalProcessor", "License Model": "licenseModel",
+	}
+	columns := map[string]int{}
+	for i, name := range rows[header] {
+		columns[name] = i
+	}
+	entries := []priceListEntry{}
+	for _, row := range rows[header+1:] {
+		value := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(row) {
+				return row[i]
+			}
+			return ""
+		}
+		if value("TermType") != "OnDemand" || value("Product Family") != "Compute Instance" {
+			continue
+		}
+		attributes := map[string]string{}
+		for column, attribute := range attributeNames {
+			attributes[attribute] = value(column)
+		}
+		if !awsDefaultOffer(attributes) {
+			continue
+		}
+		price, err := strconv.ParseFloat(value("PricePerUnit"), 64)
+		if err != nil {
+			continue
+		}
+		entry, err := awsEntry(attributes, price, value("Unit"))
+		if err != nil {
+			return nil, fmt.Errorf("sku %s: %s", value("SKU"), err.Error())
+		}
+		entries = append(entries, entry)
+	}
+	return filterEntries(entries, filter)
+}
+
+/* Read the VM types from the SKUs of Compute Engine in the Cloud Billing Catalog API.
+	The catalog prices cores and memory per machine series, then the price of each machine type is computed
+	from its cores and memory. Only on-demand prices of predefined machine types with dedicated cores are read
+	in:
+		@catalogReader io.Reader	- Response of services/6F81-5844-456A/skus, the SKUs of Compute Engine
+		@machineTypesReader io.Reader	- Output of gcloud compute machine-types list --format=json
+		@filter ImportFilter
+	out:
+		@[]types.VmProfile	- VM types sorted by price per hour
+		@error
+*/
+func ReadGCPBillingCatalog(catalogReader io.Reader, machineTypesReader io.Reader, filter ImportFilter) ([]types.VmProfile, error) {
+	var catalog gcpBillingCatalog
+	if err := json.NewDecoder(catalogReader).Decode(&catalog); err != nil {
+		return nil, err
+	}
+	var machineTypes []gcpMachineType
+	if err := json.NewDecoder(machineTypesReader).Decode(&machineTypes); err != nil {
+		return nil, err
+	}
+
+	//Price per core hour and per GB hour of each series and region
+	type seriesPrice struct {
+		core   float64
+		memory float64
+	}
+	prices := map[string]map[string]seriesPrice{}
+	for _, sku := range catalog.Skus {
+		if sku.Category.ResourceFamily != "Compute" || sku.Category.UsageType != "OnDemand" ||
+			len(sku.PricingInfo) == 0 || !gcpPredefinedSku(sku.Description) {
+			continue
+		}
+		expression := sku.PricingInfo[len(sku.PricingInfo)-1].PricingExpression
+		isCore := strings.Contains(sku.Description, "Instance Core") && expression.UsageUnit == "h"
+		isMemory := strings.Contains(sku.Description, "Instance Ram") && expression.UsageUnit == "GiBy.h"
+		if !isCore && !isMemory {
+			continue
+		}
+		price := -1.0
+		for _, rate := range expression.TieredRates {
+			if rate.StartUsageAmount == 0 {
+				units, err := strconv.ParseFloat(rate.UnitPrice.Units, 64)
+				if err != nil && rate.UnitPrice.Units != "" {
+					return nil, errors.New("invalid price of " + sku.Description)
+				}
+				price = units + float64(rate.UnitPrice.Nanos)/1e9
+			}
+		}
+		if price < 0 {
+			continue
+		}
+		series := strings.ToLower(strings.Fields(sku.Description)[0])
+		if prices[series] == nil {
+			prices[series] = map[string]seriesPrice{}
+		}
+		for _, region := range sku.ServiceRegions {
+			p := prices[series][region]
+			if isCore {
+				p.core = price
+			} else {
+				p.memory = price
+			}
+			prices[series][region] = p
+		}
+	}
+
+	entries := []priceListEntry{}
+	listed := map[string]bool{}
+	for _, machineType := range machineTypes {
+		//The list has one entry per zone
+		if machineType.IsSharedCpu || listed[machineType.Name] {
+			continue
+		}
+		listed[machineType.Name] = true
+		series := typeFamily(machineType.Name)
+		architecture := ARCHITECTURE_X86
+		if series == "t2a" {
+			architecture = ARCHITECTURE_ARM
+		}
+		memory := machineType.MemoryMb / 1024
+		for region, p := range prices[series] {
+			if p.core <= 0 || p.memory <= 0 {
+				continue
+			}
+			entries = append(entries, priceListEntry{
+				vmProfile: types.VmProfile{Type: machineType.Name, CPUCores: machineType.GuestCpus, Memory: memory,
+					OS: "Linux", Pricing: types.Pricing{Price: machineType.GuestCpus*p.core + memory*p.memory,
+						Unit: types.PRICE_UNIT_HOUR}},
+				region:            region,
+				family:            series,
+				currentGeneration: true,
+				architecture:      architecture,
+			})
+		}
+	}
+	return filterEntries(entries, filter)
+}
+
+/* Read the VM types from an export of the Azure Retail Prices API.
+	The prices do not include the cores and memory, which are read from the list of VM sizes.
+	Only pay as you go prices are read, spot and low priority prices are skipped
+	in:
+		@pricesReader io.Reader	- Response of prices.azure.com/api/retail/prices for serviceName 'Virtual Machines'
+		@sizesReader io.Reader	- Output of az vm list-sizes --output json
+		@filter ImportFilter
+	out:
+		@[]types.VmProfile	- VM types sorted by price per hour
+		@error
+*/
+func ReadAzureRetailPrices(pricesReader io.Reader, sizesReader io.Reader, filter ImportFilter) ([]types.VmProfile, error) {
+	var prices azureRetailPrices
+	if err := json.NewDecoder(pricesReader).Decode(&prices); err != nil {
+		return nil, err
+	}
+	var vmSizes []azureVMSize
+	if err := json.NewDecoder(sizesReader).Decode(&vmSizes); err != nil {
+		return nil, err
+	}
+	sizes := map[string]azureVMSize{}
+	for _, size := range vmSizes {
+		sizes[size.Name] = size
+	}
+
+	entries := []priceListEntry{}
+	for _, item := range prices.Items {
+		if item.ServiceName != "Virtual Machines" || item.Type != "Consumption" ||
+			strings.Contains(item.SkuName, "Spot") || strings.Contains(item.SkuName, "Low Priority") {
+			continue
+		}
+		size, ok := sizes[item.ArmSkuName]
+		if !ok {
+			continue
+		}
+		pricing, err := NormalizePricing(types.Pricing{Price: item.RetailPrice,
+			Unit: strings.TrimPrefix(item.UnitOfMeasure, "1 ")})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", item.ArmSkuName, err.Error())
+		}
+		operatingSystem := "Linux"
+		if strings.HasSuffix(item.ProductName, " Windows") {
+			operatingSystem = "Windows"
+		}
+		//E.g. Dv3 for Virtual Machines Dv3 Series Windows
+		series := strings.TrimSuffix(item.ProductName, " Windows")
+		series = strings.TrimSuffix(strings.TrimPrefix(series, "Virtual Machines "), " Series")
+		architecture := ARCHITECTURE_X86
+		if len(series) > 1 && strings.Contains(series[1:], "p") {
+			architecture = ARCHITECTURE_ARM
+		}
+		entries = append(entries, priceListEntry{
+			vmProfile: types.VmProfile{Type: item.ArmSkuName, CPUCores: size.NumberOfCores,
+				Memory: size.MemoryInMb / 1024, OS: operatingSystem, Pricing: pricing},
+			region:            item.ArmRegionName,
+			family:            series,
+			currentGeneration: true,
+			architecture:      architecture,
+		})
+	}
+	return filterEntries(entries, filter)
+}
+
+/* Read the VM types from a csv file with one row per type, used for the exports of any provider.
+	Required columns: type, cpu_cores, mem_gb, price. Optional columns: unit (default Hour), os, region,
+	family (default the prefix of the type), architecture and current_generation
+	in:
+		@reader io.Reader
+		@filter ImportFilter
+	out:
+		@[]types.VmProfile	- VM types sorted by price per hour
+		@error
+*/
+func ReadVMsCSV(reader io.Reader, filter ImportFilter) ([]types.VmProfile, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.TrimLeadingSpace = true
+	rows, err := csvReader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) < 2 {
+		return nil, errors.New("csv file without VM types")
+	}
+	columns := map[string]int{}
+	for i, name := range rows[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"type", "cpu_cores", "mem_gb", "price"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("column %s is required", name)
+		}
+	}
+
+	entries := []priceListEntry{}
+	for i, row := range rows[1:] {
+		value := func(name string) string {
+			if c, ok := columns[name]; ok && c < len(row) {
+				return strings.TrimSpace(row[c])
+			}
+			return ""
+		}
+		cores, errCores := strconv.ParseFloat(value("cpu_cores"), 64)
+		memory, errMemory := strconv.ParseFloat(value("mem_gb"), 64)
+		price, errPrice := strconv.ParseFloat(value("price"), 64)
+		if errCores != nil || errMemory != nil || errPrice != nil {
+			return nil, fmt.Errorf("row %d: cpu_cores, mem_gb and price should be numbers", i+2)
+		}
+		pricing, err := NormalizePricing(types.Pricing{Price: price, Unit: value("unit")})
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %s", i+2, err.Error())
+		}
+		vmType := value("type")
+		family := value("family")
+		if family == "" {
+			family = typeFamily(vmType)
+		}
+		architecture := value("architecture")
+		if architecture == "" {
+			architecture = ARCHITECTURE_X86
+		}
+		var spot types.SpotVariant
+		if value("spot_discount") != "" {
+			spot.Discount, err = strconv.ParseFloat(value("spot_discount"), 64)
+			if err == nil && value("interruption_rate") != "" {
+				spot.InterruptionRate, err = strconv.ParseFloat(value("interruption_rate"), 64)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("row %d: spot_discount and interruption_rate should be numbers", i+2)
+			}
+		}
+		entries = append(entries, priceListEntry{
+			vmProfile:         types.VmProfile{Type: vmType, CPUCores: cores, Memory: memory, OS: value("os"), Pricing: pricing, Spot: spot},
+			region:            value("region"),
+			family:            family,
+			currentGeneration: !strings.EqualFold(value("current_generation"), "no") && !strings.EqualFold(value("current_generation"), "false"),
+			architecture:      architecture,
+		})
+	}
+	return filterEntries(entries, filter)
+}
+
+/* Set the imported VM types of a provider and region in the catalog
+	in:
+		@catalog types.VMCatalog
+		@csp string
+		@region string
+		@vmProfiles []types.VmProfile
+		@replace bool	- Remove the types of the provider and region that were not imported
+	out:
+		@types.VMCatalog
+*/
+func MergeVMProfiles(catalog types.VMCatalog, csp string, region string, vmProfiles []types.VmProfile, replace bool) types.VMCatalog {
+	if catalog == nil {
+		catalog = types.VMCatalog{}
+	}
+	if catalog[csp] == nil {
+		catalog[csp] = map[string][]types.VmProfile{}
+	}
+	merged := []types.VmProfile{}
+	imported := map[string]bool{}
+	for _, vm := range vmProfiles {
+		imported[vm.Type] = true
+	}
+	stored := map[string]types.VmProfile{}
+	for _, vm := range catalog[csp][region] {
+		stored[vm.Type] = vm
+		if !replace && !imported[vm.Type] {
+			merged = append(merged, vm)
+		}
+	}
+	for _, vm := range vmProfiles {
+		//Price lists without spot prices keep the spot variant of the stored type
+		if vm.Spot == (types.SpotVariant{}) {
+			vm.Spot = stored[vm.Type].Spot
+		}
+		merged = append(merged, vm)
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].Type < merged[j].Type })
+	catalog[csp][region] = merged
+	return catalog
+}
+
+//Offers of shared instances, with the capacity reserved on demand and without pre-installed software
+func awsDefaultOffer(attributes map[string]string) bool {
+	if attributes["tenancy"] != "" && attributes["tenancy"] != "Shared" {
+		return false
+	}
+	if attributes["preInstalledSw"] != "" && attributes["preInstalledSw"] != "NA" {
+		return false
+	}
+	if attributes["capacitystatus"] != "" && attributes["capacitystatus"] != "Used" {
+		return false
+	}
+	if attributes["licenseModel"] != "" && attributes["licenseModel"] != "No License required" {
+		return false
+	}
+	return true
+}
+
+func awsEntry(attributes map[string]string, price float64, unit string) (priceListEntry, error) {
+	var entry priceListEntry
+	cores, err := strconv.ParseFloat(attributes["vcpu"], 64)
+	if err != nil {
+		return entry, errors.New("invalid vcpu " + attributes["vcpu"])
+	}
+	memory, err := parseMemory(attributes["memory"])
+	if err != nil {
+		return entry, err
+	}
+	pricing, err := NormalizePricing(types.Pricing{Price: price, Unit: unit})
+	if err != nil {
+		return entry, err
+	}
+	architecture := ARCHITECTURE_X86
+	if strings.Contains(attributes["physicalProcessor"], "Graviton") {
+		architecture = ARCHITECTURE_ARM
+	}
+	vmType := attributes["instanceType"]
+	entry = priceListEntry{
+		vmProfile: types.VmProfile{Type: vmType, CPUCores: cores, Memory: memory,
+			OS: attributes["operatingSystem"], Pricing: pricing},
+		region:            attributes["regionCode"],
+		family:            typeFamily(vmType),
+		currentGeneration: attributes["currentGeneration"] != "No",
+		architecture:      architecture,
+	}
+	return entry, nil
+}
+
+//SKUs of predefined machine types, custom and sole tenant machines have other prices
+func gcpPredefinedSku(description string) bool {
+	for _, excluded := range []string{"Custom", "Sole Tenancy", "Extended", "Commitment", "Preemptible"} {
+		if strings.Contains(description, excluded) {
+			return false
+		}
+	}
+	return len(strings.Fields(description)) > 0
+}
+
+//Memory in GB from values as "1,024 GiB"
+func parseMemory(memory string) (float64, error) {
+	value := strings.Replace(memory, ",", "", -1)
+	value = strings.TrimSpace(strings.TrimSuffix(strings.TrimSuffix(value, "GiB"), "GB"))
+	gb, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, errors.New("invalid memory " + memory)
+	}
+	return gb, nil
+}
+
+//Family of a VM type, the prefix before the first separator. E.g. t2 for t2.micro or n1 for n1-standard-1
+func typeFamily(vmType string) string {
+	if i := strings.IndexAny(vmType, ".-_"); i > 0 {
+		return vmType[:i]
+	}
+	return vmType
+}
+
+func filterEntries(entries []priceListEntry, filter ImportFilter) ([]types.VmProfile, error) {
+	families := map[string]bool{}
+	for _, f := range filter.Families {
+		families[strings.ToLower(f)] = true
+	}
+	byType := map[string]types.VmProfile{}
+	for _, e := range entries {
+		if e.vmProfile.Pricing.Price <= 0 {
+			continue
+		}
+		if filter.Region != "" && e.region != "" && !strings.EqualFold(e.region, filter.Region) {
+			continue
+		}
+		if filter.OS != "" && e.vmProfile.OS != "" && !strings.EqualFold(e.vmProfile.OS, filter.OS) {
+			continue
+		}
+		if len(families) > 0 && !families[strings.ToLower(e.family)] {
+			continue
+		}
+		if filter.CurrentGeneration && !e.currentGeneration {
+			continue
+		}
+		if filter.Architecture != "" && !strings.EqualFold(e.architecture, filter.Architecture) {
+			continue
+		}
+		//Several prices for the same type keep the lowest one
+		if stored, ok := byType[e.vmProfile.Type]; ok && stored.Pricing.Price <= e.vmProfile.Pricing.Price {
+			continue
+		}
+		byType[e.vmProfile.Type] = e.vmProfile
+	}
+	if len(byType) == 0 {
+		return nil, errors.New("no VM types fulfill the filters")
+	}
+	vmProfiles := []types.VmProfile{}
+	for _, vm := range byType {
+		vmProfiles = append(vmProfiles, vm)
+	}
+	sort.Slice(vmProfiles, func(i, j int) bool {
+		if vmProfiles[i].Pricing.Price != vmProfiles[j].Pricing.Price {
+			return vmProfiles[i].Pricing.Price < vmProfiles[j].Pricing.Price
+		}
+		return vmProfiles[i].Type < vmProfiles[j].Type
+	})
+	return vmProfiles, nil
+}
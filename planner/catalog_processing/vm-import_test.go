@@ -0,0 +1,166 @@
+package catalog_processing
+
+import (
+	"github.com/Cloud-Pie/SPDT/types"
+	"math"
+	"strings"
+	"testing"
+)
+
+const awsOfferFile = `{
+  "products": {
+    "SKU1": {"sku": "SKU1", "productFamily": "Compute Instance", "attributes": {"instanceType": "t3.micro",
+      "vcpu": "2", "memory": "1 GiB", "operatingSystem": "Linux", "regionCode": "us-east-2", "tenancy": "Shared",
+      "preInstalledSw": "NA", "capacitystatus": "Used", "currentGeneration": "Yes", "physicalProcessor": "Intel Xeon"}},
+    "SKU2": {"sku": "SKU2", "productFamily": "Compute Instance", "attributes": {"instanceType": "t3.micro",
+      "vcpu": "2", "memory": "1 GiB", "operatingSystem": "Windows", "regionCode": "us-east-2", "tenancy": "Shared",
+      "preInstalledSw": "NA", "capacitystatus": "Used", "currentGeneration": "Yes"}},
+    "SKU3": {"sku": "SKU3", "productFamily": "Compute Instance", "attributes": {"instanceType": "m6g.large",
+      "vcpu": "2", "memory": "8 GiB", "operatingSystem": "Linux", "regionCode": "us-east-2", "tenancy": "Shared",
+      "preInstalledSw": "NA", "capacitystatus": "Used", "currentGeneration": "Yes", "physicalProcessor": "AWS Graviton2 Processor"}},
+    "SKU4": {"sku": "SKU4", "productFamily": "Compute Instance", "attributes": {"instanceType": "m1.large",
+      "vcpu": "2", "memory": "7.5 GiB", "operatingSystem": "Linux", "regionCode": "us-east-2", "tenancy": "Shared",
+      "preInstalledSw": "NA", "capacitystatus": "Used", "currentGeneration": "No"}}
+  },
+  "terms": {"OnDemand": {
+    "SKU1": {"SKU1.T": {"priceDimensions": {"SKU1.T.R": {"unit": "Hrs", "pricePerUnit": {"USD": "0.0104"}}}}},
+    "SKU2": {"SKU2.T": {"priceDimensions": {"SKU2.T.R": {"unit": "Hrs", "pricePerUnit": {"USD": "0.0196"}}}}},
+    "SKU3": {"SKU3.T": {"priceDimensions": {"SKU3.T.R": {"unit": "Hrs", "pricePerUnit": {"USD": "0.077"}}}}},
+    "SKU4": {"SKU4.T": {"priceDimensions": {"SKU4.T.R": {"unit": "Hrs", "pricePerUnit": {"USD": "0.175"}}}}}
+  }}
+}`
+
+func TestReadAWSPriceListJSON(t *testing.T) {
+	filter := ImportFilter{Region: "us-east-2", OS: "Linux"}
+	vmProfiles, err := ReadAWSPriceListJSON(strings.NewReader(awsOfferFile), filter)
+	if err != nil || len(vmProfiles) != 3 {
+		t.Fatalf("Expected 3 Linux VM types, got %v %v", vmProfiles, err)
+	}
+	if vmProfiles[0].Type != "t3.micro" || vmProfiles[0].Pricing.Price != 0.0104 || vmProfiles[0].Memory != 1 {
+		t.Errorf("Unexpected first VM type %v", vmProfiles[0])
+	}
+
+	filter.CurrentGeneration = true
+	filter.Architecture = ARCHITECTURE_ARM
+	vmProfiles, err = ReadAWSPriceListJSON(strings.NewReader(awsOfferFile), filter)
+	if err != nil || len(vmProfiles) != 1 || vmProfiles[0].Type != "m6g.large" {
+		t.Errorf("Expected only m6g.large, got %v %v", vmProfiles, err)
+	}
+}
+
+func TestReadVMsCSV(t *testing.T) {
+	data := `type,cpu_cores,mem_gb,price,unit,region
+n1-standard-1,1,3.75,0.0475,Hour,europe-west3
+e2-small,2,2,12.23,Month,europe-west3
+n1-standard-2,2,7.5,0.095,Hour,us-central1
+`
+	filter := ImportFilter{Region: "europe-west3", Families: []string{"n1"}}
+	vmProfiles, err := ReadVMsCSV(strings.NewReader(data), filter)
+	if err != nil || len(vmProfiles) != 1 || vmProfiles[0].Type != "n1-standard-1" {
+		t.Fatalf("Expected only n1-standard-1, got %v %v", vmProfiles, err)
+	}
+
+	catalog := types.VMCatalog{"GCP": {"europe-west3": {{Type: "e2-small"}}}}
+	catalog = MergeVMProfiles(catalog, "GCP", "europe-west3", vmProfiles, false)
+	if len(catalog["GCP"]["europe-west3"]) != 2 {
+		t.Errorf("Expected the imported type added to the catalog, got %v", catalog)
+	}
+}
+
+const gcpCatalogFile = `{
+  "skus": [
+    {"description": "N1 Predefined Instance Core running in Frankfurt",
+      "category": {"resourceFamily": "Compute", "resourceGroup": "N1Standard", "usageType": "OnDemand"},
+      "serviceRegions": ["europe-west3"],
+      "pricingInfo": [{"pricingExpression": {"usageUnit": "h",
+        "tieredRates": [{"startUsageAmount": 0, "unitPrice": {"currencyCode": "USD", "units": "0", "nanos": 40000000}}]}}]},
+    {"description": "N1 Predefined Instance Ram running in Frankfurt",
+      "category": {"resourceFamily": "Compute", "resourceGroup": "N1Standard", "usageType": "OnDemand"},
+      "serviceRegions": ["europe-west3"],
+      "pricingInfo": [{"pricingExpression": {"usageUnit": "GiBy.h",
+        "tieredRates": [{"startUsageAmount": 0, "unitPrice": {"currencyCode": "USD", "units": "0", "nanos": 5000000}}]}}]},
+    {"description": "Preemptible N1 Predefined Instance Core running in Frankfurt",
+      "category": {"resourceFamily": "Compute", "resourceGroup": "N1Standard", "usageType": "Preemptible"},
+      "serviceRegions": ["europe-west3"],
+      "pricingInfo": [{"pricingExpression": {"usageUnit": "h",
+        "tieredRates": [{"startUsageAmount": 0, "unitPrice": {"currencyCode": "USD", "units": "0", "nanos": 8000000}}]}}]},
+    {"description": "N1 Custom Instance Core running in Frankfurt",
+      "category": {"resourceFamily": "Compute", "resourceGroup": "CPU", "usageType": "OnDemand"},
+      "serviceRegions": ["europe-west3"],
+      "pricingInfo": [{"pricingExpression": {"usageUnit": "h",
+        "tieredRates": [{"startUsageAmount": 0, "unitPrice": {"currencyCode": "USD", "units": "1", "nanos": 0}}]}}]}
+  ]
+}`
+
+const gcpMachineTypesFile = `[
+  {"name": "n1-standard-1", "guestCpus": 1, "memoryMb": 3840, "zone": "europe-west3-a", "isSharedCpu": false},
+  {"name": "n1-standard-1", "guestCpus": 1, "memoryMb": 3840, "zone": "europe-west3-b", "isSharedCpu": false},
+  {"name": "n1-standard-2", "guestCpus": 2, "memoryMb": 7680, "zone": "europe-west3-a", "isSharedCpu": false},
+  {"name": "f1-micro", "guestCpus": 1, "memoryMb": 614, "zone": "europe-west3-a", "isSharedCpu": true},
+  {"name": "e2-standard-2", "guestCpus": 2, "memoryMb": 8192, "zone": "europe-west3-a", "isSharedCpu": false}
+]`
+
+func TestReadGCPBillingCatalog(t *testing.T) {
+	filter := ImportFilter{Region: "europe-west3", OS: "Linux"}
+	vmProfiles, err := ReadGCPBillingCatalog(strings.NewReader(gcpCatalogFile), strings.NewReader(gcpMachineTypesFile), filter)
+	//e2 has no prices in the catalog and f1-micro has a shared core
+	if err != nil || len(vmProfiles) != 2 {
+		t.Fatalf("Expected 2 n1 machine types, got %v %v", vmProfiles, err)
+	}
+	//1 core * 0.04 + 3.75 GB * 0.005
+	if vmProfiles[0].Type != "n1-standard-1" || math.Abs(vmProfiles[0].Pricing.Price-0.05875) > 1e-9 ||
+		vmProfiles[0].Memory != 3.75 || vmProfiles[0].CPUCores != 1 {
+		t.Errorf("Unexpected first VM type %v", vmProfiles[0])
+	}
+
+	filter.Region = "us-central1"
+	if _, err = ReadGCPBillingCatalog(strings.NewReader(gcpCatalogFile), strings.NewReader(gcpMachineTypesFile), filter); err == nil {
+		t.Error("Expected an error without prices for the region")
+	}
+}
+
+const azurePricesFile = `{
+  "BillingCurrency": "USD",
+  "Items": [
+    {"retailPrice": 0.096, "unitOfMeasure": "1 Hour", "armRegionName": "westeurope", "productName": "Virtual Machines Dv3 Series",
+      "skuName": "D2 v3", "serviceName": "Virtual Machines", "armSkuName": "Standard_D2_v3", "type": "Consumption"},
+    {"retailPrice": 0.188, "unitOfMeasure": "1 Hour", "armRegionName": "westeurope", "productName": "Virtual Machines Dv3 Series Windows",
+      "skuName": "D2 v3", "serviceName": "Virtual Machines", "armSkuName": "Standard_D2_v3", "type": "Consumption"},
+    {"retailPrice": 0.019, "unitOfMeasure": "1 Hour", "armRegionName": "westeurope", "productName": "Virtual Machines Dv3 Series",
+      "skuName": "D2 v3 Spot", "serviceName": "Virtual Machines", "armSkuName": "Standard_D2_v3", "type": "Consumption"},
+    {"retailPrice": 500, "unitOfMeasure": "1 Hour", "armRegionName": "westeurope", "productName": "Virtual Machines Dv3 Series",
+      "skuName": "D2 v3", "serviceName": "Virtual Machines", "armSkuName": "Standard_D2_v3", "type": "Reservation"},
+    {"retailPrice": 0.077, "unitOfMeasure": "1 Hour", "armRegionName": "westeurope", "productName": "Virtual Machines Dpsv5 Series",
+      "skuName": "D2ps v5", "serviceName": "Virtual Machines", "armSkuName": "Standard_D2ps_v5", "type": "Consumption"},
+    {"retailPrice": 0.5, "unitOfMeasure": "1 Hour", "armRegionName": "westeurope", "productName": "Virtual Machines Unknown Series",
+      "skuName": "X1", "serviceName": "Virtual Machines", "armSkuName": "Standard_X1", "type": "Consumption"}
+  ]
+}`
+
+const azureSizesFile = `[
+  {"name": "Standard_D2_v3", "numberOfCores": 2, "memoryInMb": 8192, "maxDataDiskCount": 4},
+  {"name": "Standard_D2ps_v5", "numberOfCores": 2, "memoryInMb": 8192, "maxDataDiskCount": 4}
+]`
+
+func TestReadAzureRetailPrices(t *testing.T) {
+	filter := ImportFilter{Region: "westeurope", OS: "Linux"}
+	vmProfiles, err := ReadAzureRetailPrices(strings.NewReader(azurePricesFile), strings.NewReader(azureSizesFile), filter)
+	if err != nil || len(vmProfiles) != 2 {
+		t.Fatalf("Expected 2 Linux VM types, got %v %v", vmProfiles, err)
+	}
+	if vmProfiles[1].Type != "Standard_D2_v3" || vmProfiles[1].Pricing.Price != 0.096 || vmProfiles[1].Memory != 8 {
+		t.Errorf("Unexpected pay as you go price of Standard_D2_v3 %v", vmProfiles[1])
+	}
+
+	filter.Architecture = ARCHITECTURE_ARM
+	vmProfiles, err = ReadAzureRetailPrices(strings.NewReader(azurePricesFile), strings.NewReader(azureSizesFile), filter)
+	if err != nil || len(vmProfiles) != 1 || vmProfiles[0].Type != "Standard_D2ps_v5" {
+		t.Errorf("Expected only Standard_D2ps_v5, got %v %v", vmProfiles, err)
+	}
+
+	filter = ImportFilter{Region: "westeurope", OS: "Windows", Families: []string{"dv3"}}
+	vmProfiles, err = ReadAzureRetailPrices(strings.NewReader(azurePricesFile), strings.NewReader(azureSizesFile), filter)
+	if err != nil || len(vmProfiles) != 1 || vmProfiles[0].Pricing.Price != 0.188 {
+		t.Errorf("Expected the Windows price of Standard_D2_v3, got %v %v", vmProfiles, err)
+	}
+}
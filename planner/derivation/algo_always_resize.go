@@ -14,6 +14,7 @@ type AlwaysResizePolicy struct {
 	mapVMProfiles map[string]types.VmProfile
 	sysConfiguration	util.SystemConfiguration
 	currentState	types.State			 //Current State
+	reservedVMs	types.VMScale			//VMs reserved at the start of the interval being derived
 }
 
 
@@ -48,7 +49,7 @@ func (p AlwaysResizePolicy) CreatePolicies(processedForecast types.ProcessedFore
 	@VMScale with the suggested number of VMs for that type
 */
 func (p AlwaysResizePolicy) FindSuitableVMs(numberReplicas int, limits types.Limit) (types.VMScale,error) {
-	vmSet,err := buildHomogeneousVMSet(numberReplicas,limits, p.mapVMProfiles)
+	vmSet,err := buildHomogeneousVMSet(numberReplicas,limits, p.reservedVMs, p.mapVMProfiles)
 	/*hetVMSet,_ := buildHeterogeneousVMSet(numberReplicas, limits, p.mapVMProfiles)
 	costi := hetVMSet.Cost(p.mapVMProfiles)
 	costj := vmSet.Cost(p.mapVMProfiles)
@@ -73,6 +74,7 @@ func (p AlwaysResizePolicy)deriveCandidatePolicy(criticalIntervals []types.Criti
 	scalingSteps := []types.ScalingAction{}
 
 	for _, it := range criticalIntervals {
+		p.reservedVMs = ReservedVMs(p.sysConfiguration.PricingModel.Reservations, it.TimeStart)
 		totalLoad := it.Requests
 		performanceProfile, _ := selectProfileUnderVMLimits(totalLoad, vmLimits)
 		vmSet, _ := p.FindSuitableVMs(performanceProfile.MSCSetting.Replicas, performanceProfile.Limits)
@@ -21,6 +21,7 @@ type BestResourcePairPolicy struct {
 	sortedVMProfiles []types.VmProfile
 	mapVMProfiles   map[string]types.VmProfile
 	sysConfiguration	util.SystemConfiguration
+	reservedVMs	types.VMScale			//VMs reserved at the start of the interval being derived
 }
 
 /* Derive a list of policies using the Best Instance Approach approach
@@ -135,6 +136,8 @@ func (p BestResourcePairPolicy) findBestPair(forecastedValues []types.CriticalIn
 	for _,v := range forecastedValues {
 		if v.Requests > max {
 			max = v.Requests
+			//The pair is sized for the peak, so the VM sets are compared with the reservations at its start
+			p.reservedVMs = ReservedVMs(p.sysConfiguration.PricingModel.Reservations, v.TimeStart)
 		}
 	}
 	biggestVMType := p.sortedVMProfiles[len(p.sortedVMProfiles)-1]
@@ -149,11 +152,8 @@ func (p BestResourcePairPolicy) findBestPair(forecastedValues []types.CriticalIn
 			servicePerformanceProfile,_ := estimatePodsConfiguration(max, vl.Limit)
 			replicas := servicePerformanceProfile.MSCSetting.Replicas
 			vmSetCandidate,_ := p.FindSuitableVMs(replicas,vl.Limit, vmType)
-			vmSetCost := 0.0
 			if len(vmSetCandidate) > 0 {
-				for _,v := range vmSetCandidate {
-					vmSetCost += p.mapVMProfiles[vmType].Pricing.Price * float64(v)
-				}
+				vmSetCost := unreservedCost(vmSetCandidate, p.reservedVMs, p.mapVMProfiles)
 				if vmSetCost < bestCost {
 					bestLimit = vl.Limit
 					bestVMProfile = p.mapVMProfiles[vmType]
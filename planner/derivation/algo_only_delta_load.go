@@ -19,6 +19,7 @@ type DeltaLoadPolicy struct {
 	currentState	types.State			 //Current State
 	mapVMProfiles map[string]types.VmProfile
 	sysConfiguration	util.SystemConfiguration
+	reservedVMs	types.VMScale			//VMs reserved at the start of the interval being derived
 }
 
 /* Derive a list of policies using this approach
@@ -42,6 +43,7 @@ func (p DeltaLoadPolicy) CreatePolicies(processedForecast types.ProcessedForecas
 		var podLimits types.Limit
 		var totalServicesBootingTime float64
 		var stateLoadCapacity float64
+		p.reservedVMs = ReservedVMs(p.sysConfiguration.PricingModel.Reservations, it.TimeStart)
 
 		//Current configuration
 		totalLoad := it.Requests
@@ -130,7 +132,7 @@ func (p DeltaLoadPolicy) CreatePolicies(processedForecast types.ProcessedForecas
 	@VMScale with the suggested number of VMs for that type
 */
 func (p DeltaLoadPolicy) FindSuitableVMs(numberReplicas int, limits types.Limit) types.VMScale {
-	vmSet, _ := buildHomogeneousVMSet(numberReplicas,limits, p.mapVMProfiles)
+	vmSet, _ := buildHomogeneousVMSet(numberReplicas,limits, p.reservedVMs, p.mapVMProfiles)
 	/*hetVMSet,_ := buildHeterogeneousVMSet(numberReplicas, limits, p.mapVMProfiles)
 	costi := hetVMSet.Cost(p.mapVMProfiles)
 	costj := vmSet.Cost(p.mapVMProfiles)
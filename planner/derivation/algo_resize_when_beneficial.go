@@ -19,6 +19,7 @@ type ResizeWhenBeneficialPolicy struct {
 	sortedVMProfiles []types.VmProfile    			//List of VM profiles sorted by price
 	mapVMProfiles    map[string]types.VmProfile		//Map with VM profiles with VM.Type as key
 	sysConfiguration	util.SystemConfiguration
+	reservedVMs	types.VMScale			//VMs reserved at the start of the interval being derived
 }
 
 /* Derive a list of policies
@@ -43,6 +44,7 @@ func (p ResizeWhenBeneficialPolicy) CreatePolicies(processedForecast types.Proce
 
 	for i, it := range processedForecast.CriticalIntervals {
 		resourcesConfiguration := types.ContainersConfig{}
+		p.reservedVMs = ReservedVMs(p.sysConfiguration.PricingModel.Reservations, it.TimeStart)
 
 		//Load in terms of number of requests
 		totalLoad := it.Requests
@@ -133,7 +135,7 @@ func (p ResizeWhenBeneficialPolicy) CreatePolicies(processedForecast types.Proce
 	@VMScale with the suggested number of VMs
 */
 func (p ResizeWhenBeneficialPolicy) FindSuitableVMs(numberReplicas int, resourceLimits types.Limit) types.VMScale {
-	vmSet, _ := buildHomogeneousVMSet(numberReplicas,resourceLimits, p.mapVMProfiles)
+	vmSet, _ := buildHomogeneousVMSet(numberReplicas,resourceLimits, p.reservedVMs, p.mapVMProfiles)
 	/*hetVMSet,_ := buildHeterogeneousVMSet(numberReplicas, resourceLimits, p.mapVMProfiles)
 	costi := hetVMSet.Cost(p.mapVMProfiles)
 	costj := vmSet.Cost(p.mapVMProfiles)
@@ -212,8 +214,8 @@ func(p ResizeWhenBeneficialPolicy) calculateReconfigurationCost(newSet types.VMS
 	@bool = flag to indicate whether reconfiguration should be performed
 */
 func(p ResizeWhenBeneficialPolicy) shouldRepackVMSet(currentOption types.ContainersConfig, candidateOption types.ContainersConfig, indexTimeInterval int, timeIntervals[]types.CriticalInterval) (types.ContainersConfig, bool) {
-	currentCost := currentOption.VMSet.Cost(p.mapVMProfiles)
-	candidateCost := candidateOption.VMSet.Cost(p.mapVMProfiles)
+	currentCost := unreservedCost(currentOption.VMSet, p.reservedVMs, p.mapVMProfiles)
+	candidateCost := unreservedCost(candidateOption.VMSet, p.reservedVMs, p.mapVMProfiles)
 
 	if candidateCost <= currentCost {
 		//By default the transition policy would be to shut down VMs after launch new ones
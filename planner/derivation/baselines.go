@@ -0,0 +1,251 @@
+package derivation
+
+import (
+	"github.com/Cloud-Pie/SPDT/types"
+	"github.com/Cloud-Pie/SPDT/util"
+	"math"
+	"sort"
+	"time"
+)
+
+//VM type with which the baselines are provisioned
+type baselineVM struct {
+	vmType   string
+	capacity float64 //Requests per second served by a VM
+}
+
+//Number of VMs to serve a load at a utilization percentage
+func (b baselineVM) vmsFor(requests float64, utilization float64) int {
+	return int(math.Max(1, math.Ceil(requests*100/(b.capacity*utilization))))
+}
+
+//Scaling action of a baseline with n VMs, launched at launch and ready at start
+func (b baselineVM) scalingAction(n int, launch time.Time, start time.Time) types.ScalingAction {
+	return types.ScalingAction{
+		TimeStartTransition: launch,
+		TimeStart:           start,
+		DesiredState:        types.State{VMs: types.VMScale{b.vmType: n}},
+		Metrics:             types.ConfigMetrics{RequestsCapacity: float64(n) * b.capacity},
+	}
+}
+
+//Boot times of the VMs of each type by number of VMs, so the stored profiles are read once per derivation
+type bootTimeCache map[string]map[int]float64
+
+//Boot time of n VMs of a type, looked up in the stored profiles the first time it is requested
+func (c bootTimeCache) bootTime(vmType string, n int, sysConfiguration util.SystemConfiguration) float64 {
+	if _, ok := c[vmType]; !ok {
+		c[vmType] = map[int]float64{}
+	}
+	if _, ok := c[vmType][n]; !ok {
+		c[vmType][n] = vmTransitionTimes(vmType, n, sysConfiguration).BootTime
+	}
+	return c[vmType][n]
+}
+
+/* Compare a policy with the static and reactive baselines for the same forecast
+	in:
+		@policy types.Policy	- Policy with its metrics computed
+		@forecast []types.ForecastedValue
+		@sysConfiguration util.SystemConfiguration
+		@mapVMProfiles map[string]types.VmProfile
+	out:
+		@[]types.BaselineMetrics
+*/
+func ComputeBaselines(policy types.Policy, forecast []types.ForecastedValue, sysConfiguration util.SystemConfiguration,
+	mapVMProfiles map[string]types.VmProfile) []types.BaselineMetrics {
+	return computeBaselines(policy.ScalingActions, forecast, policy.Metrics, sysConfiguration, mapVMProfiles,
+		bootTimeCache{})
+}
+
+/* Compare each candidate policy with the static and reactive baselines for the same forecast.
+	The boot times looked up for a policy are reused for the others
+	in:
+		@policies []types.Policy	- Candidate policies with their metrics computed, updated with their baselines
+		@forecast []types.ForecastedValue
+		@sysConfiguration util.SystemConfiguration
+		@mapVMProfiles map[string]types.VmProfile
+*/
+func ComputeCandidatesBaselines(policies []types.Policy, forecast []types.ForecastedValue,
+	sysConfiguration util.SystemConfiguration, mapVMProfiles map[string]types.VmProfile) {
+	bootTimes := bootTimeCache{}
+	for i := range policies {
+		policies[i].Metrics.Baselines = computeBaselines(policies[i].ScalingActions, forecast, policies[i].Metrics,
+			sysConfiguration, mapVMProfiles, bootTimes)
+	}
+}
+
+/* Compare the scaling actions of a policy with the static and reactive baselines.
+	The baselines use the VM type with more VMs in the scaling action of the policy with the highest capacity,
+	and they are billed and evaluated as the policy
+	in:
+		@scalingActions []types.ScalingAction	- Scaling actions of the policy
+		@forecast []types.ForecastedValue
+		@metrics types.PolicyMetrics	- Metrics of the policy
+		@sysConfiguration util.SystemConfiguration
+		@mapVMProfiles map[string]types.VmProfile
+		@bootTimes bootTimeCache	- Boot times already looked up
+	out:
+		@[]types.BaselineMetrics	- Empty if the policy has no capacity
+*/
+func computeBaselines(scalingActions []types.ScalingAction, forecast []types.ForecastedValue, metrics types.PolicyMetrics,
+	sysConfiguration util.SystemConfiguration, mapVMProfiles map[string]types.VmProfile,
+	bootTimes bootTimeCache) []types.BaselineMetrics {
+	baselines := []types.BaselineMetrics{}
+	unit, ok := peakVM(scalingActions)
+	if !ok {
+		return baselines
+	}
+	from := scalingActions[0].TimeStart
+	to := scalingActions[len(scalingActions)-1].TimeEnd
+	samples := forecastBetween(forecast, from, to)
+	config := baselinesConfiguration(sysConfiguration.Baselines)
+
+	peak := 0.0
+	for _, v := range samples {
+		peak = math.Max(peak, v.Requests)
+	}
+	percentile := requestsPercentile(samples, config.Percentile)
+	bootTime := func(n int) float64 {
+		return bootTimes.bootTime(unit.vmType, n, sysConfiguration)
+	}
+	candidates := map[string][]types.ScalingAction{
+		types.BASELINE_STATIC_PEAK:       {unit.scalingAction(unit.vmsFor(peak, 100), from, from)},
+		types.BASELINE_STATIC_PERCENTILE: {unit.scalingAction(unit.vmsFor(percentile, 100), from, from)},
+		types.BASELINE_REACTIVE:          reactiveBaseline(samples, unit, from, config, bootTime),
+	}
+	for _, name := range []string{types.BASELINE_STATIC_PEAK, types.BASELINE_STATIC_PERCENTILE, types.BASELINE_REACTIVE} {
+		actions := candidates[name]
+		for i := range actions {
+			actions[i].TimeEnd = to
+			if i < len(actions)-1 {
+				actions[i].TimeEnd = actions[i+1].TimeStart
+			}
+		}
+		cost, _ := BillingCosts(actions, sysConfiguration.PricingModel, mapVMProfiles)
+		totalOver, totalUnder := 0.0, 0.0
+		index := 0
+		for _, a := range actions {
+			over, under := actionProvisioning(a.Metrics.RequestsCapacity, a.TimeEnd, samples, &index)
+			totalOver += over
+			totalUnder += under
+		}
+		baseline := types.BaselineMetrics{
+			Name:                 name,
+			Cost:                 util.RoundN(cost, 2.0),
+			OverProvision:        util.RoundN(totalOver/float64(len(actions)), 2.0),
+			UnderProvision:       util.RoundN(totalUnder/float64(len(actions)), 2.0),
+			NumberScalingActions: len(actions),
+		}
+		baseline.Savings = util.RoundN(baseline.Cost-metrics.Cost, 2.0)
+		if baseline.Cost > 0 {
+			baseline.SavingsPercentage = util.RoundN(baseline.Savings*100/baseline.Cost, 2.0)
+		}
+		baseline.OverProvisionDiff = util.RoundN(metrics.OverProvision-baseline.OverProvision, 2.0)
+		baseline.UnderProvisionDiff = util.RoundN(metrics.UnderProvision-baseline.UnderProvision, 2.0)
+		baselines = append(baselines, baseline)
+	}
+	return baselines
+}
+
+/* Simulate a reactive autoscaler that observes the load of each forecasted value.
+	When the utilization is over the scale out threshold it launches the VMs to reach the middle of the thresholds,
+	which serve load once they booted. It does not scale again while VMs are booting. When the utilization is under
+	the scale in threshold it removes VMs immediately
+	in:
+		@samples []types.ForecastedValue	- Forecasted values of the time window
+		@unit baselineVM
+		@from time.Time	- Start of the time window
+		@config util.Baselines
+		@bootTime func(int) float64	- Seconds to boot a number of VMs
+	out:
+		@[]types.ScalingAction	- Without end times
+*/
+func reactiveBaseline(samples []types.ForecastedValue, unit baselineVM, from time.Time, config util.Baselines,
+	bootTime func(int) float64) []types.ScalingAction {
+	target := (config.ScaleOutThreshold + config.ScaleInThreshold) / 2
+	active := 1
+	if len(samples) > 0 {
+		active = unit.vmsFor(samples[0].Requests, target)
+	}
+	scalingActions := []types.ScalingAction{unit.scalingAction(active, from, from)}
+	booting := false
+	var ready time.Time
+	for _, v := range samples {
+		t := v.TimeStamp
+		if booting && !t.Before(ready) {
+			booting = false
+			active = scalingActions[len(scalingActions)-1].DesiredState.VMs[unit.vmType]
+		}
+		if booting {
+			continue
+		}
+		utilization := v.Requests * 100 / (float64(active) * unit.capacity)
+		n := unit.vmsFor(v.Requests, target)
+		switch {
+		case utilization > config.ScaleOutThreshold && n > active:
+			ready = t.Add(time.Duration(bootTime(n-active) * float64(time.Second)))
+			scalingActions = append(scalingActions, unit.scalingAction(n, t, ready))
+			booting = true
+		case utilization < config.ScaleInThreshold && n < active:
+			active = n
+			scalingActions = append(scalingActions, unit.scalingAction(n, t, t))
+		}
+	}
+	for i := 1; i < len(scalingActions); i++ {
+		scalingActions[i].InitialState = scalingActions[i-1].DesiredState
+	}
+	return scalingActions
+}
+
+//VM type with more VMs in the scaling action with the highest capacity, and the capacity per VM of that action
+func peakVM(scalingActions []types.ScalingAction) (baselineVM, bool) {
+	var peakAction *types.ScalingAction
+	for i, a := range scalingActions {
+		if a.DesiredState.VMs.TotalVMs() > 0 &&
+			(peakAction == nil || a.Metrics.RequestsCapacity > peakAction.Metrics.RequestsCapacity) {
+			peakAction = &scalingActions[i]
+		}
+	}
+	if peakAction == nil || peakAction.Metrics.RequestsCapacity <= 0 {
+		return baselineVM{}, false
+	}
+	vms := onDemandVMs(peakAction.DesiredState.VMs)
+	vmTypes := []string{}
+	for vmType := range vms {
+		vmTypes = append(vmTypes, vmType)
+	}
+	sort.Strings(vmTypes)
+	unit := baselineVM{capacity: peakAction.Metrics.RequestsCapacity / float64(vms.TotalVMs())}
+	for _, vmType := range vmTypes {
+		if unit.vmType == "" || vms[vmType] > vms[unit.vmType] {
+			unit.vmType = vmType
+		}
+	}
+	return unit, true
+}
+
+//Forecasted values between two times, the end excluded
+func forecastBetween(forecast []types.ForecastedValue, from time.Time, to time.Time) []types.ForecastedValue {
+	values := []types.ForecastedValue{}
+	for _, v := range forecast {
+		if !v.TimeStamp.Before(from) && v.TimeStamp.Before(to) {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+//Configuration of the baselines with the default values for the missing parameters
+func baselinesConfiguration(config util.Baselines) util.Baselines {
+	if config.Percentile <= 0 {
+		config.Percentile = util.DEFAULT_BASELINE_PERCENTILE
+	}
+	if config.ScaleOutThreshold <= 0 {
+		config.ScaleOutThreshold = util.DEFAULT_SCALE_OUT_THRESHOLD
+	}
+	if config.ScaleInThreshold <= 0 || config.ScaleInThreshold >= config.ScaleOutThreshold {
+		config.ScaleInThreshold = math.Min(util.DEFAULT_SCALE_IN_THRESHOLD, config.ScaleOutThreshold/2)
+	}
+	return config
+}
@@ -0,0 +1,82 @@
+package derivation
+
+import (
+	"github.com/Cloud-Pie/SPDT/types"
+	"github.com/Cloud-Pie/SPDT/util"
+	"testing"
+	"time"
+)
+
+func TestReactiveBaseline(t *testing.T) {
+	start := time.Date(2018, 11, 1, 7, 0, 0, 0, time.UTC)
+	scalingActions := []types.ScalingAction{{TimeStart: start, TimeEnd: start.Add(time.Hour),
+		DesiredState: types.State{VMs: types.VMScale{"t2.large": 3, types.SpotType("t2.large"): 1, "t2.micro": 2}},
+		Metrics:      types.ConfigMetrics{RequestsCapacity: 600}}}
+	unit, ok := peakVM(scalingActions)
+	if !ok || unit.vmType != "t2.large" || unit.capacity != 100 {
+		t.Fatal(
+			"For: ", scalingActions[0].DesiredState.VMs,
+			"expected: ", "t2.large serving 100 requests per VM",
+			"got: ", unit,
+		)
+	}
+
+	samples := []types.ForecastedValue{}
+	for i, requests := range []float64{100, 300, 300, 300, 60} {
+		samples = append(samples, types.ForecastedValue{TimeStamp: start.Add(time.Duration(i) * 10 * time.Minute), Requests: requests})
+	}
+	config := baselinesConfiguration(util.Baselines{})
+	actions := reactiveBaseline(samples, unit, start, config, func(n int) float64 { return 900 })
+
+	//Scale out to 5 VMs ready after 15 minutes, then scale in to 1 VM
+	var expected = []struct {
+		vms       int
+		timeStart time.Time
+	}{
+		{2, start},
+		{5, start.Add(25 * time.Minute)},
+		{1, start.Add(40 * time.Minute)},
+	}
+	if len(actions) != len(expected) {
+		t.Fatal(
+			"For: ", samples,
+			"expected: ", len(expected), "scaling actions",
+			"got: ", actions,
+		)
+	}
+	for i, a := range actions {
+		e := expected[i]
+		if n := a.DesiredState.VMs["t2.large"]; n != e.vms || !a.TimeStart.Equal(e.timeStart) {
+			t.Error(
+				"For: ", "scaling action ", i,
+				"expected: ", e.vms, e.timeStart,
+				"got: ", n, a.TimeStart,
+			)
+		}
+	}
+}
+
+func TestBootTimeCache(t *testing.T) {
+	//Boot times already looked up are not requested again
+	bootTimes := bootTimeCache{"t2.large": {1: 90, 5: 300}, "t2.micro": {5: 60}}
+
+	var tests = []struct {
+		vmType   string
+		n        int
+		bootTime float64
+	}{
+		{"t2.large", 1, 90},
+		{"t2.large", 5, 300},
+		{"t2.micro", 5, 60},
+	}
+	for _, test := range tests {
+		bootTime := bootTimes.bootTime(test.vmType, test.n, util.SystemConfiguration{})
+		if bootTime != test.bootTime {
+			t.Error(
+				"For: ", test.vmType, test.n,
+				"expected: ", test.bootTime,
+				"got: ", bootTime,
+			)
+		}
+	}
+}
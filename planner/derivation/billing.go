@@ -0,0 +1,241 @@
+package derivation
+
+import (
+	"github.com/Cloud-Pie/SPDT/types"
+	"github.com/Cloud-Pie/SPDT/util"
+	"math"
+	"sort"
+	"time"
+)
+
+//Time a VM instance runs in a policy
+type instanceLifetime struct {
+	VMType      string
+	Launch      time.Time
+	Termination time.Time
+	Running     bool //The instance is still running at the end of the policy
+}
+
+//Rules to bill the lifetime of an instance
+type billingModel struct {
+	period  float64 //Seconds, the billed time is rounded up to a multiple of the period
+	minimum float64 //Seconds billed at least for every instance
+}
+
+/* Billing rules of the pricing model.
+	Billing units: s (per second, 60 seconds minimum), m (per minute) and h (per started hour from the launch).
+	The minimum can be changed with minimum-billed-time
+	in:
+		@pricingModel util.PricingModel
+	out:
+		@billingModel
+*/
+func newBillingModel(pricingModel util.PricingModel) billingModel {
+	model := billingModel{}
+	switch pricingModel.BillingUnit {
+	case util.SECOND:
+		model = billingModel{period: 1, minimum: 60}
+	case util.MINUTE:
+		model = billingModel{period: 60, minimum: 60}
+	case util.HOUR:
+		model = billingModel{period: 3600, minimum: 3600}
+	}
+	if pricingModel.MinimumBilledTime != "" {
+		model.minimum = float64(util.ParseIntervalToSeconds(pricingModel.MinimumBilledTime))
+	}
+	return model
+}
+
+//Seconds billed for an instance that ran the given seconds
+func (b billingModel) billedSeconds(seconds float64) float64 {
+	billed := seconds
+	if b.period > 0 {
+		//Tolerance for the floating point error of the durations
+		billed = math.Ceil(seconds/b.period-1e-9) * b.period
+	}
+	return math.Max(billed, b.minimum)
+}
+
+//Seconds already paid and not used by an instance launched at launch, at the time t
+func (b billingModel) unusedPaidSeconds(launch time.Time, t time.Time) float64 {
+	elapsed := t.Sub(launch).Seconds()
+	return b.billedSeconds(elapsed) - elapsed
+}
+
+/* Follow the lifetime of every VM instance along the scaling actions.
+	Instances are launched when the transition to the state that needs them starts, and terminated when the
+	state without them starts. On scale in, the instances with the least paid time left are terminated first
+	in:
+		@scalingActions []types.ScalingAction
+		@model billingModel
+	out:
+		@[]instanceLifetime	- Sorted by launch time
+*/
+func instanceLifetimes(scalingActions []types.ScalingAction, model billingModel) []instanceLifetime {
+	lifetimes := []instanceLifetime{}
+	if len(scalingActions) == 0 {
+		return lifetimes
+	}
+	running := types.VMTimeRecord{}
+	for _, a := range scalingActions {
+		launch := a.TimeStart
+		if !a.TimeStartTransition.IsZero() && a.TimeStartTransition.Before(launch) {
+			launch = a.TimeStartTransition
+		}
+		for vmType, n := range a.DesiredState.VMs {
+			for len(running[vmType]) < n {
+				running[vmType] = append(running[vmType], launch)
+			}
+		}
+		for vmType, launches := range running {
+			for len(launches) > a.DesiredState.VMs[vmType] {
+				j := 0
+				for k := range launches {
+					if model.unusedPaidSeconds(launches[k], a.TimeStart) < model.unusedPaidSeconds(launches[j], a.TimeStart) {
+						j = k
+					}
+				}
+				lifetimes = append(lifetimes, instanceLifetime{VMType: vmType, Launch: launches[j], Termination: a.TimeStart})
+				launches = append(launches[:j], launches[j+1:]...)
+			}
+			running[vmType] = launches
+		}
+	}
+	end := scalingActions[len(scalingActions)-1].TimeEnd
+	for vmType, launches := range running {
+		for _, launch := range launches {
+			lifetimes = append(lifetimes, instanceLifetime{VMType: vmType, Launch: launch, Termination: end, Running: true})
+		}
+	}
+	sort.Slice(lifetimes, func(i, j int) bool {
+		if !lifetimes[i].Launch.Equal(lifetimes[j].Launch) {
+			return lifetimes[i].Launch.Before(lifetimes[j].Launch)
+		}
+		return lifetimes[i].VMType < lifetimes[j].VMType
+	})
+	return lifetimes
+}
+
+/* Compute the cost of the scaling actions billing the lifetime of each VM instance.
+	The time an instance runs is charged to the scaling actions it runs in, and the time billed beyond it
+	(minimum and rounding) to the action in which it is terminated. Instances still running at the end of
+	the policy continue in the next window, so their time is not rounded. The hours of reserved VMs are not charged.
+	Sustained use or tiered discounts are applied to the usage of each VM type per calendar month
+	in:
+		@scalingActions []types.ScalingAction
+		@pricingModel util.PricingModel
+		@mapVMProfiles map[string]types.VmProfile
+	out:
+		@float64	- Total cost
+		@[]float64	- Cost of each scaling action
+*/
+func BillingCosts(scalingActions []types.ScalingAction, pricingModel util.PricingModel,
+	mapVMProfiles map[string]types.VmProfile) (float64, []float64) {
+	costs := make([]float64, len(scalingActions))
+	if len(scalingActions) == 0 {
+		return 0, costs
+	}
+	model := newBillingModel(pricingModel)
+
+	//Billed hours of each VM type per scaling action
+	billedHours := make([]map[string]float64, len(scalingActions))
+	for i := range billedHours {
+		billedHours[i] = map[string]float64{}
+	}
+	actionAt := func(t time.Time) int {
+		i := sort.Search(len(scalingActions), func(i int) bool { return scalingActions[i].TimeStart.After(t) })
+		return int(math.Max(float64(i-1), 0))
+	}
+	for _, l := range instanceLifetimes(scalingActions, model) {
+		last := actionAt(l.Termination)
+		for i := actionAt(l.Launch); i <= last; i++ {
+			from, to := l.Launch, l.Termination
+			if i > 0 && scalingActions[i].TimeStart.After(from) {
+				from = scalingActions[i].TimeStart
+			}
+			if i < len(scalingActions)-1 && scalingActions[i+1].TimeStart.Before(to) {
+				to = scalingActions[i+1].TimeStart
+			}
+			if to.After(from) {
+				billedHours[i][l.VMType] += to.Sub(from).Hours()
+			}
+		}
+		if !l.Running {
+			seconds := l.Termination.Sub(l.Launch).Seconds()
+			billedHours[last][l.VMType] += (model.billedSeconds(seconds) - seconds) / 3600
+		}
+	}
+
+	//Reserved VMs are already paid
+	for i, a := range scalingActions {
+		hours := actionHours(scalingActions, i)
+		for vmType, n := range reservedInSet(a.DesiredState.VMs, ReservedVMs(pricingModel.Reservations, a.TimeStart)) {
+			billedHours[i][vmType] = math.Max(billedHours[i][vmType]-float64(n)*hours, 0)
+		}
+	}
+
+	//Hours used per month and VM type, to apply the discount tiers
+	usedHours := map[string]float64{}
+	totalCost := 0.0
+	for i, a := range scalingActions {
+		vmTypes := []string{}
+		for vmType := range billedHours[i] {
+			vmTypes = append(vmTypes, vmType)
+		}
+		sort.Strings(vmTypes)
+		month := a.TimeStart.Format("2006-01")
+		for _, vmType := range vmTypes {
+			hours := billedHours[i][vmType]
+			key := month + vmType
+			chargedHours := discountedHours(usedHours[key], hours, pricingModel, a.TimeStart)
+			usedHours[key] += hours
+			costs[i] += vmProfileOf(mapVMProfiles, vmType).Pricing.Price * chargedHours
+		}
+		totalCost += costs[i]
+	}
+	return totalCost, costs
+}
+
+/* Hours charged at full price for the hours used after the hours already used in the month.
+	Each discount tier applies to the hours from its start to the start of the next tier. The start of
+	the tiers is a fraction of the hours of the month for sustained use discounts and a number of hours
+	for tiered discounts
+	in:
+		@usedHours float64	- Hours of the VM type already used in the month
+		@hours float64	- New hours used
+		@pricingModel util.PricingModel
+		@t time.Time	- Time in the month
+	out:
+		@float64
+*/
+func discountedHours(usedHours float64, hours float64, pricingModel util.PricingModel, t time.Time) float64 {
+	scale := 1.0
+	switch pricingModel.DiscountModel {
+	case util.DISCOUNT_SUSTAINED_USE:
+		monthStart := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+		scale = monthStart.AddDate(0, 1, 0).Sub(monthStart).Hours()
+	case util.DISCOUNT_TIERED:
+	default:
+		return hours
+	}
+	tiers := append([]util.DiscountTier{}, pricingModel.DiscountTiers...)
+	sort.Slice(tiers, func(i, j int) bool { return tiers[i].From < tiers[j].From })
+
+	from, to := usedHours, usedHours+hours
+	charged := 0.0
+	tierStart, discount := 0.0, 0.0
+	for i := 0; i <= len(tiers); i++ {
+		tierEnd := math.Inf(1)
+		if i < len(tiers) {
+			tierEnd = tiers[i].From * scale
+		}
+		overlap := math.Min(to, tierEnd) - math.Max(from, tierStart)
+		if overlap > 0 {
+			charged += overlap * (1 - discount)
+		}
+		if i < len(tiers) {
+			tierStart, discount = tierEnd, tiers[i].Discount
+		}
+	}
+	return charged
+}
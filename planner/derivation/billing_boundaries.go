@@ -0,0 +1,182 @@
+package derivation
+
+import (
+	"github.com/Cloud-Pie/SPDT/types"
+	"github.com/Cloud-Pie/SPDT/util"
+	"math"
+	"sort"
+	"time"
+)
+
+//VM instance scheduled for removal that is kept until its paid period ends
+type pendingRemoval struct {
+	launch   time.Time
+	removeAt time.Time
+}
+
+/* Keep the VMs that the scaling actions remove until the end of their paid period.
+	Removing a VM before its paid period ends saves nothing, so the removal is postponed to the end of the
+	period with a new scaling action. If a later action needs a VM of the same type before that, the kept VM
+	is used instead of launching a new one. VMs whose paid period ends after the last action are removed as planned
+	in:
+		@scalingActions []types.ScalingAction
+		@pricingModel util.PricingModel
+		@shutdownTime func(string, int) float64	- Seconds to terminate a number of VMs of a type
+	out:
+		@[]types.ScalingAction
+*/
+func KeepPaidVMs(scalingActions []types.ScalingAction, pricingModel util.PricingModel,
+	shutdownTime func(string, int) float64) []types.ScalingAction {
+	model := newBillingModel(pricingModel)
+	processed := []types.ScalingAction{}
+	active := types.VMTimeRecord{}
+	pending := map[string][]pendingRemoval{}
+
+	for i, a := range scalingActions {
+		t := a.TimeStart
+		boundary := a.TimeEnd
+		if i < len(scalingActions)-1 {
+			boundary = scalingActions[i+1].TimeStart
+		}
+		launch := t
+		if !a.TimeStartTransition.IsZero() && a.TimeStartTransition.Before(launch) {
+			launch = a.TimeStartTransition
+		}
+
+		vmTypes := map[string]bool{}
+		for vmType := range a.DesiredState.VMs {
+			vmTypes[vmType] = true
+		}
+		for vmType := range active {
+			vmTypes[vmType] = true
+		}
+		for vmType := range pending {
+			vmTypes[vmType] = true
+		}
+		for vmType := range vmTypes {
+			n := a.DesiredState.VMs[vmType]
+			kept := []pendingRemoval{}
+			for _, p := range pending[vmType] {
+				if p.removeAt.After(t) {
+					kept = append(kept, p)
+				}
+			}
+			//Kept VMs with more paid time left are used first
+			sort.Slice(kept, func(i, j int) bool { return kept[i].removeAt.After(kept[j].removeAt) })
+			for len(active[vmType]) < n && len(kept) > 0 {
+				active[vmType] = append(active[vmType], kept[0].launch)
+				kept = kept[1:]
+			}
+			for len(active[vmType]) < n {
+				active[vmType] = append(active[vmType], launch)
+			}
+			for len(active[vmType]) > n {
+				launches := active[vmType]
+				j := 0
+				for k := range launches {
+					if model.unusedPaidSeconds(launches[k], t) < model.unusedPaidSeconds(launches[j], t) {
+						j = k
+					}
+				}
+				removeAt := t.Add(time.Duration(model.unusedPaidSeconds(launches[j], t) * float64(time.Second)))
+				if removeAt.After(t) && removeAt.Before(scalingActions[len(scalingActions)-1].TimeEnd) {
+					kept = append(kept, pendingRemoval{launch: launches[j], removeAt: removeAt})
+				}
+				active[vmType] = append(launches[:j], launches[j+1:]...)
+			}
+			pending[vmType] = kept
+		}
+
+		action := a
+		action.DesiredState.VMs = runningVMs(active, pending)
+		if len(processed) > 0 {
+			action.InitialState.VMs = processed[len(processed)-1].DesiredState.VMs
+		}
+		action.DesiredState.Hash = stateHash(action.DesiredState)
+		processed = append(processed, action)
+
+		//Split the action at the end of the paid period of the kept VMs
+		for _, removeAt := range removalTimes(pending, t, boundary) {
+			removed := types.VMScale{}
+			for vmType, kept := range pending {
+				remaining := []pendingRemoval{}
+				for _, p := range kept {
+					if p.removeAt.Equal(removeAt) {
+						removed[vmType]++
+					} else {
+						remaining = append(remaining, p)
+					}
+				}
+				pending[vmType] = remaining
+			}
+			shutdown := 0.0
+			for vmType, n := range removed {
+				shutdown += shutdownTime(vmType, n)
+			}
+			last := &processed[len(processed)-1]
+			split := *last
+			last.TimeEnd = removeAt
+			split.InitialState = last.DesiredState
+			split.DesiredState.VMs = runningVMs(active, pending)
+			split.DesiredState.Hash = stateHash(split.DesiredState)
+			split.TimeStart = removeAt
+			split.TimeStartTransition = removeAt.Add(-time.Duration(shutdown * float64(time.Second)))
+			split.TimeEnd = a.TimeEnd
+			split.Execution = types.ActionExecution{}
+			processed = append(processed, split)
+		}
+	}
+	return processed
+}
+
+/* Apply KeepPaidVMs to a policy unless it increases the cost, recording the saving in its metrics
+	in:
+		@policy *types.Policy
+		@sysConfiguration util.SystemConfiguration
+		@mapVMProfiles map[string]types.VmProfile
+*/
+func applyBillingBoundaries(policy *types.Policy, sysConfiguration util.SystemConfiguration,
+	mapVMProfiles map[string]types.VmProfile) {
+	scalingActions := KeepPaidVMs(policy.ScalingActions, sysConfiguration.PricingModel, func(vmType string, n int) float64 {
+		return vmTransitionTimes(vmType, n, sysConfiguration).ShutDownTime
+	})
+	cost, _ := BillingCosts(policy.ScalingActions, sysConfiguration.PricingModel, mapVMProfiles)
+	newCost, _ := BillingCosts(scalingActions, sysConfiguration.PricingModel, mapVMProfiles)
+	//Tolerance for the floating point error of the costs
+	if newCost <= cost+1e-9 {
+		policy.ScalingActions = scalingActions
+		policy.Metrics.BillingBoundarySavings = util.RoundN(math.Max(cost-newCost, 0), 2.0)
+	}
+}
+
+//Number of VMs of each type running, including the VMs kept until their paid period ends
+func runningVMs(active types.VMTimeRecord, pending map[string][]pendingRemoval) types.VMScale {
+	vms := types.VMScale{}
+	for vmType, launches := range active {
+		if len(launches) > 0 {
+			vms[vmType] += len(launches)
+		}
+	}
+	for vmType, kept := range pending {
+		if len(kept) > 0 {
+			vms[vmType] += len(kept)
+		}
+	}
+	return vms
+}
+
+//Sorted times in (from, to) at which kept VMs have to be removed
+func removalTimes(pending map[string][]pendingRemoval, from time.Time, to time.Time) []time.Time {
+	times := []time.Time{}
+	seen := map[int64]bool{}
+	for _, kept := range pending {
+		for _, p := range kept {
+			if p.removeAt.After(from) && p.removeAt.Before(to) && !seen[p.removeAt.UnixNano()] {
+				seen[p.removeAt.UnixNano()] = true
+				times = append(times, p.removeAt)
+			}
+		}
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+	return times
+}
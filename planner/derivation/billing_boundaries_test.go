@@ -0,0 +1,72 @@
+package derivation
+
+import (
+	"github.com/Cloud-Pie/SPDT/types"
+	"github.com/Cloud-Pie/SPDT/util"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestKeepPaidVMs(t *testing.T) {
+	start := time.Date(2018, 11, 1, 7, 0, 0, 0, time.UTC)
+	action := func(from time.Duration, to time.Duration, n int) types.ScalingAction {
+		return types.ScalingAction{TimeStart: start.Add(from), TimeEnd: start.Add(to),
+			DesiredState: types.State{VMs: types.VMScale{"t2.large": n}}}
+	}
+	mapVMProfiles := map[string]types.VmProfile{"t2.large": {Type: "t2.large", Pricing: types.Pricing{Price: 1}}}
+	pricingModel := util.PricingModel{BillingUnit: util.HOUR}
+	shutdownTime := func(string, int) float64 { return 30 }
+
+	//The VM removed at 0:20 is used again at 0:40 instead of launching a new one
+	scalingActions := []types.ScalingAction{
+		action(0, 20*time.Minute, 2),
+		action(20*time.Minute, 40*time.Minute, 1),
+		action(40*time.Minute, 2*time.Hour, 2),
+	}
+	processed := KeepPaidVMs(scalingActions, pricingModel, shutdownTime)
+	if len(processed) != 3 || processed[1].DesiredState.VMs["t2.large"] != 2 {
+		t.Error(
+			"For: ", "VM removed and needed again within its paid hour",
+			"expected: ", "2 VMs kept at 0:20",
+			"got: ", processed,
+		)
+	}
+	cost, _ := BillingCosts(scalingActions, pricingModel, mapVMProfiles)
+	newCost, _ := BillingCosts(processed, pricingModel, mapVMProfiles)
+	if newCost >= cost || math.Abs(newCost-4) > 1e-9 {
+		t.Error(
+			"For: ", "VM removed and needed again within its paid hour",
+			"expected: ", 4, "lower than ", cost,
+			"got: ", newCost,
+		)
+	}
+
+	//Without scale out the VM is removed when its paid period ends
+	var tests = []struct {
+		name         string
+		pricingModel util.PricingModel
+		removedAt    time.Duration //0 if the VM is removed as planned
+	}{
+		{"Per hour", util.PricingModel{BillingUnit: util.HOUR}, time.Hour},
+		{"Per hour with minimum billed time", util.PricingModel{BillingUnit: util.HOUR, MinimumBilledTime: "90m"},
+			90 * time.Minute},
+		{"Per second", util.PricingModel{BillingUnit: util.SECOND}, 0},
+	}
+	for _, test := range tests {
+		processed = KeepPaidVMs([]types.ScalingAction{action(0, 20*time.Minute, 2), action(20*time.Minute, 2*time.Hour, 1)},
+			test.pricingModel, shutdownTime)
+		removed := len(processed) == 2 && processed[1].DesiredState.VMs["t2.large"] == 1
+		if test.removedAt > 0 {
+			removed = len(processed) == 3 && processed[1].DesiredState.VMs["t2.large"] == 2 &&
+				processed[2].TimeStart.Equal(start.Add(test.removedAt)) && processed[2].DesiredState.VMs["t2.large"] == 1
+		}
+		if !removed {
+			t.Error(
+				"For: ", test.name,
+				"expected: ", "VM removed after ", test.removedAt,
+				"got: ", processed,
+			)
+		}
+	}
+}
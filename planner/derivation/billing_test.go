@@ -0,0 +1,90 @@
+package derivation
+
+import (
+	"github.com/Cloud-Pie/SPDT/types"
+	"github.com/Cloud-Pie/SPDT/util"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestBillingCosts(t *testing.T) {
+	start := time.Date(2018, 11, 1, 7, 0, 0, 0, time.UTC)
+	scalingActions := []types.ScalingAction{
+		{TimeStart: start, TimeEnd: start.Add(30 * time.Minute),
+			DesiredState: types.State{VMs: types.VMScale{"t2.large": 2}}},
+		{TimeStart: start.Add(30 * time.Minute), TimeEnd: start.Add(time.Hour),
+			DesiredState: types.State{VMs: types.VMScale{"t2.large": 1}}},
+	}
+	mapVMProfiles := map[string]types.VmProfile{"t2.large": {Type: "t2.large", Pricing: types.Pricing{Price: 1}}}
+
+	var tests = []struct {
+		name         string
+		pricingModel util.PricingModel
+		total        float64
+		costs        []float64
+	}{
+		//The instance removed after 30 minutes is billed for the whole hour
+		{"Per hour", util.PricingModel{BillingUnit: util.HOUR}, 2, []float64{1, 1}},
+		{"Per second", util.PricingModel{BillingUnit: util.SECOND}, 1.5, []float64{1, 0.5}},
+		//The removed instance is billed two hours, the one still running continues in the next window
+		{"Per hour with minimum billed time", util.PricingModel{BillingUnit: util.HOUR, MinimumBilledTime: "2h"},
+			3, []float64{1, 2}},
+		{"Per second with minimum billed time", util.PricingModel{BillingUnit: util.SECOND, MinimumBilledTime: "45m"},
+			1.75, []float64{1, 0.75}},
+	}
+	for _, test := range tests {
+		total, costs := BillingCosts(scalingActions, test.pricingModel, mapVMProfiles)
+		if math.Abs(total-test.total) > 1e-9 || len(costs) != len(test.costs) ||
+			math.Abs(costs[0]-test.costs[0]) > 1e-9 || math.Abs(costs[1]-test.costs[1]) > 1e-9 {
+			t.Error(
+				"For: ", test.name,
+				"expected: ", test.total, test.costs,
+				"got: ", total, costs,
+			)
+		}
+	}
+}
+
+func TestDiscountedHours(t *testing.T) {
+	november := time.Date(2018, 11, 10, 0, 0, 0, 0, time.UTC)
+	february := time.Date(2019, 2, 10, 0, 0, 0, 0, time.UTC)
+	sustainedUse := util.PricingModel{
+		DiscountModel: util.DISCOUNT_SUSTAINED_USE,
+		DiscountTiers: []util.DiscountTier{{From: 0.25, Discount: 0.2}, {From: 0.5, Discount: 0.4}},
+	}
+	//The tiers of a tiered discount start after a number of hours, in any order
+	tiered := util.PricingModel{
+		DiscountModel: util.DISCOUNT_TIERED,
+		DiscountTiers: []util.DiscountTier{{From: 200, Discount: 0.3}, {From: 100, Discount: 0.1}},
+	}
+
+	var tests = []struct {
+		name         string
+		usedHours    float64
+		hours        float64
+		pricingModel util.PricingModel
+		t            time.Time
+		charged      float64
+	}{
+		//November has 720 hours, the first 180 are charged at full price
+		{"Sustained use from the start of the month", 0, 360, sustainedUse, november, 324},
+		{"Sustained use in the last tier", 360, 10, sustainedUse, november, 6},
+		//February 2019 has 672 hours, the first tier starts at 168
+		{"Sustained use in a shorter month", 160, 20, sustainedUse, february, 17.6},
+		{"Tiered before the first tier", 0, 100, tiered, november, 100},
+		{"Tiered across two tiers", 150, 100, tiered, november, 80},
+		{"Tiered in the last tier", 300, 10, tiered, november, 7},
+		{"No discount model", 300, 10, util.PricingModel{DiscountTiers: tiered.DiscountTiers}, november, 10},
+	}
+	for _, test := range tests {
+		charged := discountedHours(test.usedHours, test.hours, test.pricingModel, test.t)
+		if math.Abs(charged-test.charged) > 1e-9 {
+			t.Error(
+				"For: ", test.name,
+				"expected: ", test.charged,
+				"got: ", charged,
+			)
+		}
+	}
+}
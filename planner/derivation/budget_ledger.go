@@ -0,0 +1,168 @@
+package derivation
+
+import (
+	"github.com/Cloud-Pie/SPDT/storage"
+	"github.com/Cloud-Pie/SPDT/types"
+	"github.com/Cloud-Pie/SPDT/util"
+	"gopkg.in/mgo.v2/bson"
+	"math"
+	"time"
+)
+
+//Format of the months of the budget ledger
+const LEDGER_MONTH_FORMAT = "2006-01"
+
+/* Build the budget ledger entries of the scaling actions of a policy.
+	The cost of a scaling action is spent from its start to the start of the next action, and it is split
+	proportionally between the calendar months (UTC) of that time
+	in:
+		@policy types.Policy
+		@recordedAt time.Time
+	out:
+		@[]types.BudgetLedgerEntry	- Sorted by time
+*/
+func LedgerEntries(policy types.Policy, recordedAt time.Time) []types.BudgetLedgerEntry {
+	entries := []types.BudgetLedgerEntry{}
+	for i, a := range policy.ScalingActions {
+		from := a.TimeStart.UTC()
+		to := from.Add(time.Duration(actionHours(policy.ScalingActions, i) * float64(time.Hour)))
+		hours := to.Sub(from).Hours()
+		for start := from; ; {
+			end := monthStart(start).AddDate(0, 1, 0)
+			if end.After(to) {
+				end = to
+			}
+			cost := a.Metrics.Cost
+			if hours > 0 {
+				cost = a.Metrics.Cost * end.Sub(start).Hours() / hours
+			}
+			entries = append(entries, types.BudgetLedgerEntry{
+				ID:         bson.NewObjectId(),
+				PolicyID:   policy.ID.Hex(),
+				Month:      start.Format(LEDGER_MONTH_FORMAT),
+				TimeStart:  start,
+				TimeEnd:    end,
+				Cost:       cost,
+				RecordedAt: recordedAt,
+			})
+			if !end.Before(to) {
+				break
+			}
+			start = end
+		}
+	}
+	return entries
+}
+
+/* Compute the budget status of a month from its ledger entries
+	in:
+		@entries []types.BudgetLedgerEntry
+		@month string	- YYYY-MM
+		@budget float64
+		@now time.Time
+	out:
+		@types.MonthlySpend
+		@error	- Invalid month
+*/
+func MonthlySpend(entries []types.BudgetLedgerEntry, month string, budget float64, now time.Time) (types.MonthlySpend, error) {
+	spend := types.MonthlySpend{Month: month, Budget: budget}
+	start, err := time.Parse(LEDGER_MONTH_FORMAT, month)
+	if err != nil {
+		return spend, err
+	}
+	end := start.AddDate(0, 1, 0)
+	total := 0.0
+	for _, e := range entries {
+		if e.Month != month {
+			continue
+		}
+		total += e.Cost
+		spend.Committed += spendBefore(e, now)
+		if e.TimeEnd.After(spend.ScheduledUntil) {
+			spend.ScheduledUntil = e.TimeEnd
+		}
+	}
+	spend.Forecast = total - spend.Committed
+	spend.Remaining = budget - total
+
+	//The burn rate projects the spend of the time of the month without scheduled scaling actions
+	elapsed := math.Min(now.Sub(start).Hours(), end.Sub(start).Hours())
+	if elapsed > 0 {
+		spend.BurnRate = spend.Committed / elapsed
+	}
+	unscheduledFrom := now
+	if spend.ScheduledUntil.After(unscheduledFrom) {
+		unscheduledFrom = spend.ScheduledUntil
+	}
+	spend.ProjectedSpend = total + spend.BurnRate*math.Max(end.Sub(unscheduledFrom).Hours(), 0)
+
+	spend.Committed = util.RoundN(spend.Committed, 2.0)
+	spend.Forecast = util.RoundN(spend.Forecast, 2.0)
+	spend.Remaining = util.RoundN(spend.Remaining, 2.0)
+	spend.BurnRate = util.RoundN(spend.BurnRate, 4.0)
+	spend.ProjectedSpend = util.RoundN(spend.ProjectedSpend, 2.0)
+	return spend, nil
+}
+
+/* Check if a policy fits in the monthly budget remaining after the spend already in the ledger.
+	The ledger spend in the time of the policy is not counted, because the policy replaces it
+	in:
+		@policy types.Policy
+		@monthlyBudget float64
+		@ledger []types.BudgetLedgerEntry
+	out:
+		@bool	- The policy fits in the budget
+		@time.Time	- Time at which the budget is exceeded
+*/
+func withinMonthlyBudget(policy types.Policy, monthlyBudget float64, ledger []types.BudgetLedgerEntry) (bool, time.Time) {
+	entries := LedgerEntries(policy, time.Now())
+	if len(entries) == 0 {
+		return true, policy.TimeWindowEnd
+	}
+	from, to := entries[0].TimeStart, entries[len(entries)-1].TimeEnd
+	spent := map[string]float64{}
+	for _, e := range ledger {
+		spent[e.Month] += e.Cost - (spendBefore(e, to) - spendBefore(e, from))
+	}
+	for _, e := range entries {
+		spent[e.Month] += e.Cost
+		//Tolerance for the floating point error of the costs
+		if spent[e.Month] > monthlyBudget+1e-9 {
+			return false, e.TimeStart
+		}
+	}
+	return true, policy.TimeWindowEnd
+}
+
+//Read the ledger entries of the months of a policy
+func policyLedger(serviceName string, policy types.Policy) []types.BudgetLedgerEntry {
+	n := len(policy.ScalingActions)
+	if n == 0 {
+		return []types.BudgetLedgerEntry{}
+	}
+	from := policy.ScalingActions[0].TimeStart.UTC().Format(LEDGER_MONTH_FORMAT)
+	to := policy.ScalingActions[n-1].TimeEnd.UTC().Format(LEDGER_MONTH_FORMAT)
+	entries, err := storage.GetBudgetLedgerDAO(serviceName).FindByMonths(from, to)
+	if err != nil {
+		log.Error("Error reading the budget ledger: %s", err.Error())
+		return []types.BudgetLedgerEntry{}
+	}
+	return entries
+}
+
+//Part of the cost of a ledger entry spent before a time
+func spendBefore(e types.BudgetLedgerEntry, t time.Time) float64 {
+	switch {
+	case !t.After(e.TimeStart):
+		return 0
+	case !t.Before(e.TimeEnd):
+		return e.Cost
+	}
+	return e.Cost * t.Sub(e.TimeStart).Hours() / e.TimeEnd.Sub(e.TimeStart).Hours()
+}
+
+//First instant of the calendar month (UTC) of a time
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
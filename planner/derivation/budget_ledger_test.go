@@ -0,0 +1,118 @@
+package derivation
+
+import (
+	"github.com/Cloud-Pie/SPDT/types"
+	"gopkg.in/mgo.v2/bson"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestLedgerEntries(t *testing.T) {
+	start := time.Date(2018, 11, 30, 12, 0, 0, 0, time.UTC)
+	policy := types.Policy{ID: bson.NewObjectId(), TimeWindowEnd: start.Add(48 * time.Hour),
+		ScalingActions: []types.ScalingAction{{TimeStart: start, TimeEnd: start.Add(48 * time.Hour),
+			Metrics: types.ConfigMetrics{Cost: 48}}}}
+
+	//The cost is split between November and December
+	entries := LedgerEntries(policy, start)
+	var expected = []struct {
+		month     string
+		timeStart time.Time
+		cost      float64
+	}{
+		{"2018-11", start, 12},
+		{"2018-12", time.Date(2018, 12, 1, 0, 0, 0, 0, time.UTC), 36},
+	}
+	if len(entries) != len(expected) {
+		t.Fatal(
+			"For: ", "scaling action across the end of November",
+			"expected: ", len(expected), "entries",
+			"got: ", entries,
+		)
+	}
+	for i, e := range entries {
+		if e.Month != expected[i].month || !e.TimeStart.Equal(expected[i].timeStart) ||
+			math.Abs(e.Cost-expected[i].cost) > 1e-9 || e.PolicyID != policy.ID.Hex() {
+			t.Error(
+				"For: ", "entry ", i,
+				"expected: ", expected[i],
+				"got: ", e,
+			)
+		}
+	}
+}
+
+func TestMonthlySpend(t *testing.T) {
+	start := time.Date(2018, 11, 30, 12, 0, 0, 0, time.UTC)
+	policy := types.Policy{ID: bson.NewObjectId(), ScalingActions: []types.ScalingAction{{TimeStart: start,
+		TimeEnd: start.Add(48 * time.Hour), Metrics: types.ConfigMetrics{Cost: 48}}}}
+	entries := LedgerEntries(policy, start)
+	december := time.Date(2018, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	var tests = []struct {
+		name     string
+		month    string
+		now      time.Time
+		expected types.MonthlySpend
+	}{
+		//The month has 744 hours, 36 are scheduled
+		{"During the scheduled time", "2018-12", december.Add(12 * time.Hour),
+			types.MonthlySpend{Committed: 12, Forecast: 24, Remaining: 64, BurnRate: 1, ProjectedSpend: 744}},
+		//At the month boundary the spend of November is committed and nothing is left to project
+		{"End of the month", "2018-11", december,
+			types.MonthlySpend{Committed: 12, Forecast: 0, Remaining: 88, BurnRate: 0.0167, ProjectedSpend: 12}},
+		{"Month not started", "2018-12", start,
+			types.MonthlySpend{Committed: 0, Forecast: 36, Remaining: 64, BurnRate: 0, ProjectedSpend: 36}},
+		{"Month without entries", "2019-01", december,
+			types.MonthlySpend{Committed: 0, Forecast: 0, Remaining: 100, BurnRate: 0, ProjectedSpend: 0}},
+	}
+	for _, test := range tests {
+		spend, err := MonthlySpend(entries, test.month, 100, test.now)
+		if err != nil || spend.Committed != test.expected.Committed || spend.Forecast != test.expected.Forecast ||
+			spend.Remaining != test.expected.Remaining || spend.BurnRate != test.expected.BurnRate ||
+			spend.ProjectedSpend != test.expected.ProjectedSpend {
+			t.Error(
+				"For: ", test.name,
+				"expected: ", test.expected,
+				"got: ", spend, err,
+			)
+		}
+	}
+
+	if _, err := MonthlySpend(entries, "2018-13", 100, december); err == nil {
+		t.Error(
+			"For: ", "2018-13",
+			"expected: ", "error",
+			"got: ", nil,
+		)
+	}
+}
+
+func TestWithinMonthlyBudget(t *testing.T) {
+	start := time.Date(2018, 11, 30, 12, 0, 0, 0, time.UTC)
+	policy := types.Policy{ID: bson.NewObjectId(), ScalingActions: []types.ScalingAction{{TimeStart: start,
+		TimeEnd: start.Add(48 * time.Hour), Metrics: types.ConfigMetrics{Cost: 48}}}}
+	//Another window already spent most of the December budget
+	other := types.Policy{ID: bson.NewObjectId(), ScalingActions: []types.ScalingAction{{
+		TimeStart: start.Add(72 * time.Hour), TimeEnd: start.Add(96 * time.Hour), Metrics: types.ConfigMetrics{Cost: 70}}}}
+	ledger := append(LedgerEntries(other, start), LedgerEntries(policy, start)...)
+
+	var tests = []struct {
+		budget float64
+		within bool
+	}{
+		{100, false},
+		//The entries of the policy itself are replaced, so they are not counted twice
+		{110, true},
+	}
+	for _, test := range tests {
+		if within, _ := withinMonthlyBudget(policy, test.budget, ledger); within != test.within {
+			t.Error(
+				"For: ", test.budget,
+				"expected: ", test.within,
+				"got: ", within,
+			)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package derivation
+
+import (
+	"github.com/Cloud-Pie/SPDT/types"
+	"github.com/Cloud-Pie/SPDT/util"
+	"math"
+)
+
+//Upper bound of replicas searched when the number of replicas is predicted for a number of requests
+const MAX_PREDICTED_REPLICAS = 10000
+
+//Linear model MSC = Intercept + Slope * replicas fitted for the settings of one container limit
+type MSCRegression struct {
+	Intercept    float64
+	Slope        float64
+	StdError     float64
+	NumPoints    int
+	MeanReplicas float64
+	SxxReplicas  float64
+	BootTimeSec  float64
+}
+
+//Prediction of the capacity of a number of replicas with its confidence bounds
+type MSCPrediction struct {
+	Replicas     int
+	MSCPerSecond float64
+	LowerBound   float64
+	UpperBound   float64
+}
+
+/* Fit a linear regression of the maximum service capacity over the number of replicas
+	using the settings stored in the performance profile of a container limit
+	in:
+		@settings []types.MSCSimpleSetting	- Known settings for a container limit
+	out:
+		@MSCRegression
+		@bool	- False if there are not enough points with different number of replicas
+*/
+func FitMSCRegression(settings []types.MSCSimpleSetting) (MSCRegression, bool) {
+	var regression MSCRegression
+	var sumX, sumY, sumBootTime float64
+	replicas := map[int]bool{}
+	for _, s := range settings {
+		if s.Replicas <= 0 || s.MSCPerSecond <= 0 {
+			continue
+		}
+		replicas[s.Replicas] = true
+		sumX += float64(s.Replicas)
+		sumY += s.MSCPerSecond
+		sumBootTime += s.BootTimeSec
+		regression.NumPoints++
+	}
+	if len(replicas) < 2 {
+		return regression, false
+	}
+
+	n := float64(regression.NumPoints)
+	meanX := sumX / n
+	meanY := sumY / n
+	var sxx, sxy float64
+	for _, s := range settings {
+		if s.Replicas <= 0 || s.MSCPerSecond <= 0 {
+			continue
+		}
+		dx := float64(s.Replicas) - meanX
+		sxx += dx * dx
+		sxy += dx * (s.MSCPerSecond - meanY)
+	}
+	regression.Slope = sxy / sxx
+	regression.Intercept = meanY - regression.Slope*meanX
+	regression.MeanReplicas = meanX
+	regression.SxxReplicas = sxx
+	regression.BootTimeSec = sumBootTime / n
+
+	//The residual standard error needs at least one degree of freedom
+	if regression.NumPoints > 2 {
+		var sse float64
+		for _, s := range settings {
+			if s.Replicas <= 0 || s.MSCPerSecond <= 0 {
+				continue
+			}
+			residual := s.MSCPerSecond - regression.Intercept - regression.Slope*float64(s.Replicas)
+			sse += residual * residual
+		}
+		regression.StdError = math.Sqrt(sse / (n - 2))
+	}
+	return regression, regression.Slope > 0
+}
+
+/* Predict the maximum service capacity of a number of replicas
+	in:
+		@replicas int
+	out:
+		@MSCPrediction	- Estimated capacity with the bounds of the prediction interval
+*/
+func (r MSCRegression) PredictMSC(replicas int) MSCPrediction {
+	x := float64(replicas)
+	msc := r.Intercept + r.Slope*x
+	dx := x - r.MeanReplicas
+	margin := util.MSC_PREDICTION_CONFIDENCE_Z * r.StdError *
+		math.Sqrt(1 + 1/float64(r.NumPoints) + dx*dx/r.SxxReplicas)
+	return MSCPrediction{
+		Replicas:     replicas,
+		MSCPerSecond: msc,
+		LowerBound:   msc - margin,
+		UpperBound:   msc + margin,
+	}
+}
+
+/* Predict the minimum number of replicas whose capacity lower bound serves a number of requests
+	in:
+		@requests float64
+	out:
+		@MSCPrediction
+		@bool	- False if no number of replicas up to MAX_PREDICTED_REPLICAS serves the requests
+*/
+func (r MSCRegression) PredictReplicas(requests float64) (MSCPrediction, bool) {
+	replicas := int(math.Ceil((requests - r.Intercept) / r.Slope))
+	if replicas < 1 {
+		replicas = 1
+	}
+	for ; replicas <= MAX_PREDICTED_REPLICAS; replicas++ {
+		prediction := r.PredictMSC(replicas)
+		if prediction.LowerBound >= requests {
+			return prediction, true
+		}
+	}
+	return MSCPrediction{}, false
+}
+
+/* Build the setting used for planning from a prediction.
+	The lower bound is used as capacity to avoid overestimating what the replicas can serve
+	in:
+		@prediction MSCPrediction
+	out:
+		@types.MSCSimpleSetting
+*/
+func (r MSCRegression) toMSCSetting(prediction MSCPrediction) types.MSCSimpleSetting {
+	bootTime := r.BootTimeSec
+	if bootTime == 0 {
+		bootTime = util.DEFAULT_POD_BOOT_TIME
+	}
+	return types.MSCSimpleSetting{
+		Replicas:     prediction.Replicas,
+		MSCPerSecond: prediction.LowerBound,
+		BootTimeSec:  bootTime,
+	}
+}
@@ -0,0 +1,44 @@
+package derivation
+
+import (
+	"github.com/Cloud-Pie/SPDT/types"
+	"testing"
+)
+
+func TestFitMSCRegression(t *testing.T) {
+	settings := []types.MSCSimpleSetting{
+		{Replicas: 1, MSCPerSecond: 100},
+		{Replicas: 2, MSCPerSecond: 210},
+		{Replicas: 3, MSCPerSecond: 290},
+		{Replicas: 4, MSCPerSecond: 400},
+	}
+	regression, ok := FitMSCRegression(settings)
+	if !ok {
+		t.Fatalf("Expected a regression for %d settings", len(settings))
+	}
+	prediction := regression.PredictMSC(6)
+	if prediction.LowerBound >= prediction.MSCPerSecond || prediction.UpperBound <= prediction.MSCPerSecond {
+		t.Errorf("Expected bounds around %f, got [%f, %f]", prediction.MSCPerSecond, prediction.LowerBound, prediction.UpperBound)
+	}
+
+	replicas, ok := regression.PredictReplicas(500)
+	if !ok {
+		t.Fatalf("Expected a number of replicas for 500 requests")
+	}
+	if replicas.LowerBound < 500 {
+		t.Errorf("Expected lower bound over 500 requests, got %f", replicas.LowerBound)
+	}
+	if previous := regression.PredictMSC(replicas.Replicas - 1); previous.LowerBound >= 500 {
+		t.Errorf("Expected the minimum number of replicas, got %d", replicas.Replicas)
+	}
+}
+
+func TestFitMSCRegressionNotEnoughPoints(t *testing.T) {
+	settings := []types.MSCSimpleSetting{
+		{Replicas: 2, MSCPerSecond: 200},
+		{Replicas: 2, MSCPerSecond: 210},
+	}
+	if _, ok := FitMSCRegression(settings); ok {
+		t.Errorf("Expected no regression for a single number of replicas")
+	}
+}
@@ -38,10 +38,6 @@ type PolicyDerivation interface {
 		@[]types.Policy
 */
 func Policies(sortedVMProfiles []types.VmProfile, sysConfiguration util.SystemConfiguration, forecast types.Forecast) ([]types.Policy, error) {
-	var policies []types.Policy
-	systemConfiguration = sysConfiguration
-	mapVMProfiles := VMListToMap(sortedVMProfiles)
-
 	log.Info("Request current state" )
 	currentState,err := execution.RetrieveCurrentState(sysConfiguration.SchedulerComponent.Endpoint + util.ENDPOINT_CURRENT_STATE)
 
@@ -50,6 +46,35 @@ func Policies(sortedVMProfiles []types.VmProfile, sysConfiguration util.SystemCo
 	} else {
 		log.Info("Finish request for current state" )
 	}
+	policies, derivationErr := PoliciesFromState(sortedVMProfiles, sysConfiguration, forecast, currentState)
+	if derivationErr != nil {
+		return policies, derivationErr
+	}
+	return policies, err
+}
+
+/* Derive scaling policies starting from a given state instead of the current state of the infrastructure
+	in:
+		@sortedVMProfiles []VmProfile
+		@sysConfiguration SystemConfiguration
+		@forecast types.Forecast
+		@currentState types.State	- State from which the first scaling action starts
+	out:
+		@[]types.Policy
+		@error
+*/
+func PoliciesFromState(sortedVMProfiles []types.VmProfile, sysConfiguration util.SystemConfiguration, forecast types.Forecast,
+	currentState types.State) ([]types.Policy, error) {
+	var policies []types.Policy
+	var err error
+	systemConfiguration = sysConfiguration
+	mapVMProfiles := VMListToMap(sortedVMProfiles)
+
+	if len(forecast.ForecastedValues) == 0 {
+		return policies, errors.New("The forecast does not contain values")
+	}
+	//The algorithms derive on-demand VM sets, spot VMs are mixed afterwards
+	currentState.VMs = onDemandVMs(currentState.VMs)
 	if currentState.Services[systemConfiguration.MainServiceName].Scale == 0 {
 		return policies, errors.New("Service "+ systemConfiguration.MainServiceName +" is not deployed")
 	}
@@ -115,9 +140,24 @@ func Policies(sortedVMProfiles []types.VmProfile, sysConfiguration util.SystemCo
 		policies6 := tree.CreatePolicies(processedForecast)
 		policies = append(policies, policies6...)
 	}
+	baseRequests := onDemandBaseRequests(forecast, sysConfiguration)
+	for i := range policies {
+		if sysConfiguration.SpotInstances.Enabled {
+			policies[i].ScalingActions = MixSpotVMs(policies[i].ScalingActions, baseRequests,
+				sysConfiguration.PricingModel.Reservations, mapVMProfiles)
+		}
+		applyBillingBoundaries(&policies[i], sysConfiguration, mapVMProfiles)
+	}
 	return policies, err
 }
 
+//Name of a state for the scheduler, a hash of its services and VMs
+func stateHash(state types.State) string {
+	state.Hash = ""
+	name,_ := structhash.Hash(state, 1)
+	return strings.Replace(name, "v1_", "", -1)
+}
+
 /* Compute the booting time that will take a set of VMS
 	in:
 		@vmsScale types.VMScale
@@ -127,28 +167,8 @@ func Policies(sortedVMProfiles []types.VmProfile, sysConfiguration util.SystemCo
 */
 func computeVMBootingTime(vmsScale types.VMScale, sysConfiguration util.SystemConfiguration) float64 {
 	bootTime := 0.0
-	//Check in db if already data is stored
-	vmBootingProfileDAO := storage.GetVMBootingProfileDAO()
-
-	//Call API
 	for vmType, n := range vmsScale {
-		times, err := vmBootingProfileDAO.BootingShutdownTime(vmType, n)
-		if err != nil {
-			url := sysConfiguration.PerformanceProfilesComponent.Endpoint + util.ENDPOINT_VM_TIMES
-			csp := sysConfiguration.CSP
-			region := sysConfiguration.Region
-			times, err = performance_profiles.GetBootShutDownProfileByType(url,vmType, n, csp, region)
-			if err != nil {
-				log.Error("Error in bootingTime query  type %s %d VMS. Details: %s", vmType, n, err.Error())
-				log.Warning("Takes the biggest time available")
-				times.BootTime = util.DEFAULT_VM_BOOT_TIME
-			}else {
-				vmBootingProfile,_ := vmBootingProfileDAO.FindByType(vmType)
-				vmBootingProfile.InstancesValues = append(vmBootingProfile.InstancesValues, times)
-				vmBootingProfileDAO.UpdateByType(vmType, vmBootingProfile)
-			}
-		}
-		bootTime += times.BootTime
+		bootTime += vmTransitionTimes(vmType, n, sysConfiguration).BootTime
 	}
 	return bootTime
 }
@@ -163,28 +183,8 @@ func computeVMBootingTime(vmsScale types.VMScale, sysConfiguration util.SystemCo
 */
 func computeVMTerminationTime(vmsScale types.VMScale, sysConfiguration util.SystemConfiguration) float64 {
 	terminationTime := 0.0
-	//Check in db if already data is stored
-	vmBootingProfileDAO := storage.GetVMBootingProfileDAO()
-
-	//Call API
 	for vmType, n := range vmsScale {
-		times, err := vmBootingProfileDAO.BootingShutdownTime(vmType, n)
-		if err != nil {
-			url := sysConfiguration.PerformanceProfilesComponent.Endpoint + util.ENDPOINT_VM_TIMES
-			csp := sysConfiguration.CSP
-			region := sysConfiguration.Region
-			times, err = performance_profiles.GetBootShutDownProfileByType(url,vmType, n, csp, region)
-			if err != nil {
-				log.Error("Error in terminationTime query for type %s %d VMS. Details: %s", vmType, n, err.Error())
-				log.Warning("Takes default shutdown")
-				times.ShutDownTime = util.DEFAULT_VM_SHUTDOWN_TIME
-			} else {
-				vmBootingProfile,_ := vmBootingProfileDAO.FindByType(vmType)
-				vmBootingProfile.InstancesValues = append(vmBootingProfile.InstancesValues, times)
-				vmBootingProfileDAO.UpdateByType(vmType, vmBootingProfile)
-			}
-		}
-		terminationTime += times.ShutDownTime
+		terminationTime += vmTransitionTimes(vmType, n, sysConfiguration).ShutDownTime
 	}
 	return terminationTime
 }
@@ -216,52 +216,76 @@ func maxPodsCapacityInVM(vmProfile types.VmProfile, resourceLimit types.Limit) i
 */
 func estimatePodsConfiguration(requests float64, limits types.Limit) (types.ContainersConfig, error){
 	var containerConfig types.ContainersConfig
-	var err error
 	serviceProfileDAO := storage.GetPerformanceProfileDAO(systemConfiguration.MainServiceName)
 
-	performanceProfileBase,_ := serviceProfileDAO.FindByLimitsAndReplicas(limits.CPUCores, limits.MemoryGB, 1)
-	estimatedReplicas := int(math.Ceil(requests / performanceProfileBase.MSCSettings[0].MSCPerSecond))
-	performanceProfileCandidate,err1 := serviceProfileDAO.FindByLimitsAndReplicas(limits.CPUCores, limits.MemoryGB, estimatedReplicas)
+	performanceProfileBase,err1 := serviceProfileDAO.FindByLimitsAndReplicas(limits.CPUCores, limits.MemoryGB, 1)
+	if err1 == nil && len(performanceProfileBase.MSCSettings) > 0 {
+		estimatedReplicas := int(math.Ceil(requests / performanceProfileBase.MSCSettings[0].MSCPerSecond))
+		performanceProfileCandidate,err2 := serviceProfileDAO.FindByLimitsAndReplicas(limits.CPUCores, limits.MemoryGB, estimatedReplicas)
+		if err2 == nil && performanceProfileCandidate.MSCSettings[0].MSCPerSecond >= requests {
+			containerConfig.MSCSetting.Replicas = performanceProfileCandidate.MSCSettings[0].Replicas
+			containerConfig.MSCSetting.MSCPerSecond = performanceProfileCandidate.MSCSettings[0].MSCPerSecond
+			containerConfig.Limits = limits
+			return containerConfig, nil
+		}
+	}
 
-	if err1 == nil && performanceProfileCandidate.MSCSettings[0].MSCPerSecond >= requests {
-		containerConfig.MSCSetting.Replicas = performanceProfileCandidate.MSCSettings[0].Replicas
-		containerConfig.MSCSetting.MSCPerSecond = performanceProfileCandidate.MSCSettings[0].MSCPerSecond
-		containerConfig.Limits = limits
-	} else {
-		url := systemConfiguration.PerformanceProfilesComponent.Endpoint + util.ENDPOINT_SERVICE_PROFILE_BY_MSC
-		appName := systemConfiguration.AppName
-		appType := systemConfiguration.AppType
-		mainServiceName := systemConfiguration.MainServiceName
-		mscSetting,err := performance_profiles.GetPredictedReplicas(url,appName,appType,mainServiceName,requests,limits.CPUCores, limits.MemoryGB)
-
-		newMSCSetting := types.MSCSimpleSetting{}
-		if err == nil {
-			containerConfig.MSCSetting.Replicas = mscSetting.Replicas
-			containerConfig.MSCSetting.MSCPerSecond = mscSetting.MSCPerSecond.RegBruteForce
+	//Predict locally from the stored settings of the same limits before requesting the profiler
+	if regression, ok := localMSCRegression(limits); ok {
+		if prediction, ok := regression.PredictReplicas(requests); ok {
+			containerConfig.MSCSetting = regression.toMSCSetting(prediction)
 			containerConfig.Limits = limits
+			return containerConfig, nil
+		}
+	}
 
-			newMSCSetting.Replicas = mscSetting.Replicas
-			newMSCSetting.MSCPerSecond = mscSetting.MSCPerSecond.RegBruteForce
-			if mscSetting.BootTimeMs > 0 {
-				newMSCSetting.BootTimeSec = util.MillisecondsToSeconds(mscSetting.BootTimeMs)
-			} else {
-				newMSCSetting.BootTimeSec = util.DEFAULT_POD_BOOT_TIME
-			}
-			profile,err3 := serviceProfileDAO.FindByLimitsAndReplicas(limits.CPUCores, limits.MemoryGB, mscSetting.Replicas)
-			if err3 != nil {
-				profile,_= serviceProfileDAO.FindProfileByLimits(limits)
-				profile.MSCSettings = append(profile.MSCSettings,newMSCSetting)
-				err3 = serviceProfileDAO.UpdateById(profile.ID, profile)
-				if err3 != nil{
-					log.Error("Performance profile not updated")
-				}
-			}
-		} else {
-			return containerConfig, err
+	url := systemConfiguration.PerformanceProfilesComponent.Endpoint + util.ENDPOINT_SERVICE_PROFILE_BY_MSC
+	appName := systemConfiguration.AppName
+	appType := systemConfiguration.AppType
+	mainServiceName := systemConfiguration.MainServiceName
+	mscSetting,err := performance_profiles.GetPredictedReplicas(url,appName,appType,mainServiceName,requests,limits.CPUCores, limits.MemoryGB)
+	if err != nil {
+		return containerConfig, err
+	}
+
+	containerConfig.MSCSetting.Replicas = mscSetting.Replicas
+	containerConfig.MSCSetting.MSCPerSecond = mscSetting.MSCPerSecond.RegBruteForce
+	containerConfig.Limits = limits
+
+	newMSCSetting := types.MSCSimpleSetting{}
+	newMSCSetting.Replicas = mscSetting.Replicas
+	newMSCSetting.MSCPerSecond = mscSetting.MSCPerSecond.RegBruteForce
+	if mscSetting.BootTimeMs > 0 {
+		newMSCSetting.BootTimeSec = util.MillisecondsToSeconds(mscSetting.BootTimeMs)
+	} else {
+		newMSCSetting.BootTimeSec = util.DEFAULT_POD_BOOT_TIME
+	}
+	profile,err3 := serviceProfileDAO.FindByLimitsAndReplicas(limits.CPUCores, limits.MemoryGB, mscSetting.Replicas)
+	if err3 != nil {
+		profile,_= serviceProfileDAO.FindProfileByLimits(limits)
+		profile.MSCSettings = append(profile.MSCSettings,newMSCSetting)
+		err3 = serviceProfileDAO.UpdateById(profile.ID, profile)
+		if err3 != nil{
+			log.Error("Performance profile not updated")
 		}
 	}
+	return containerConfig, nil
+}
 
-	return containerConfig, err
+/* Regression of the maximum service capacity fitted with the stored settings of a container limit
+	in:
+		@limits types.Limit
+	out:
+		@MSCRegression
+		@bool	- False if there are not enough stored settings
+*/
+func localMSCRegression(limits types.Limit) (MSCRegression, bool) {
+	serviceProfileDAO := storage.GetPerformanceProfileDAO(systemConfiguration.MainServiceName)
+	profile, err := serviceProfileDAO.FindProfileByLimits(limits)
+	if err != nil {
+		return MSCRegression{}, false
+	}
+	return FitMSCRegression(profile.MSCSettings)
 }
 
 /* Select the service profile for any limit resources that satisfies the number of requests
@@ -310,37 +334,75 @@ func selectProfileUnderVMLimits(requests float64,  limits types.Limit) (types.Co
 func getStateLoadCapacity(numberReplicas int, limits types.Limit) types.MSCSimpleSetting {
 	serviceProfileDAO := storage.GetPerformanceProfileDAO(systemConfiguration.MainServiceName)
 	profile,_ := serviceProfileDAO.FindByLimitsAndReplicas(limits.CPUCores, limits.MemoryGB, numberReplicas)
-	newMSCSetting := types.MSCSimpleSetting{}
 	if len(profile.MSCSettings) > 0 {
 		return profile.MSCSettings[0]
-	}else {
-		url := systemConfiguration.PerformanceProfilesComponent.Endpoint + util.ENDPOINT_SERVICE_PROFILE_BY_REPLICAS
-		appName := systemConfiguration.AppName
-		appType := systemConfiguration.AppType
-		mainServiceName := systemConfiguration.MainServiceName
-		mscCompleteSetting,_ := performance_profiles.GetPredictedMSCByReplicas(url,appName,appType,mainServiceName,numberReplicas,limits.CPUCores, limits.MemoryGB)
-		newMSCSetting = types.MSCSimpleSetting{
-			MSCPerSecond:mscCompleteSetting.MSCPerSecond.RegBruteForce,
-			BootTimeSec:mscCompleteSetting.BootTimeMs,
-			Replicas:mscCompleteSetting.Replicas,
-			StandDevBootTimeSec:mscCompleteSetting.StandDevBootTimeMS/1000,
+	}
+
+	//Predict locally from the stored settings of the same limits before requesting the profiler
+	if regression, ok := localMSCRegression(limits); ok {
+		prediction := regression.PredictMSC(numberReplicas)
+		if prediction.LowerBound > 0 {
+			return regression.toMSCSetting(prediction)
 		}
-		if newMSCSetting.BootTimeSec == 0 {
-			newMSCSetting.BootTimeSec = util.DEFAULT_POD_BOOT_TIME
+	}
+
+	url := systemConfiguration.PerformanceProfilesComponent.Endpoint + util.ENDPOINT_SERVICE_PROFILE_BY_REPLICAS
+	appName := systemConfiguration.AppName
+	appType := systemConfiguration.AppType
+	mainServiceName := systemConfiguration.MainServiceName
+	mscCompleteSetting,err := performance_profiles.GetPredictedMSCByReplicas(url,appName,appType,mainServiceName,numberReplicas,limits.CPUCores, limits.MemoryGB)
+	if err != nil || mscCompleteSetting.MSCPerSecond.RegBruteForce <= 0 {
+		//A failed prediction is not stored, otherwise the profile keeps a setting without capacity
+		log.Error("Error in capacity prediction for %d replicas. Scales the closest stored setting", numberReplicas)
+		profile,_ = serviceProfileDAO.FindProfileByLimits(limits)
+		return scaleClosestMSCSetting(profile.MSCSettings, numberReplicas)
+	}
+	newMSCSetting := types.MSCSimpleSetting{
+		MSCPerSecond:mscCompleteSetting.MSCPerSecond.RegBruteForce,
+		BootTimeSec:util.MillisecondsToSeconds(mscCompleteSetting.BootTimeMs),
+		Replicas:mscCompleteSetting.Replicas,
+		StandDevBootTimeSec:util.MillisecondsToSeconds(mscCompleteSetting.StandDevBootTimeMS),
+	}
+	if newMSCSetting.BootTimeSec == 0 {
+		newMSCSetting.BootTimeSec = util.DEFAULT_POD_BOOT_TIME
+	}
+	//update in db
+	profile,_= serviceProfileDAO.FindByLimitsAndReplicas(limits.CPUCores, limits.MemoryGB, numberReplicas)
+	if profile.ID == "" {
+		profile,_= serviceProfileDAO.FindProfileByLimits(limits)
+		profile.MSCSettings = append(profile.MSCSettings,newMSCSetting)
+		err3 := serviceProfileDAO.UpdateById(profile.ID, profile)
+		if err3 != nil{
+			log.Error("Performance profile not updated")
 		}
-		//update in db
-
-		profile,_:= serviceProfileDAO.FindByLimitsAndReplicas(limits.CPUCores, limits.MemoryGB, numberReplicas)
-		if profile.ID == "" {
-			profile,_= serviceProfileDAO.FindProfileByLimits(limits)
-			profile.MSCSettings = append(profile.MSCSettings,newMSCSetting)
-			err3 := serviceProfileDAO.UpdateById(profile.ID, profile)
-			if err3 != nil{
-				log.Error("Performance profile not updated")
+	}
+	return newMSCSetting
+}
+
+/* Estimate the capacity of a number of replicas from the stored setting with the closest number of replicas,
+	assuming the same capacity per replica
+	in:
+		@settings []types.MSCSimpleSetting	- Stored settings for the limits
+		@numberReplicas int
+	out:
+		@types.MSCSimpleSetting	- Setting without capacity if there are no stored settings
+*/
+func scaleClosestMSCSetting(settings []types.MSCSimpleSetting, numberReplicas int) types.MSCSimpleSetting {
+	newMSCSetting := types.MSCSimpleSetting{Replicas:numberReplicas, BootTimeSec:util.DEFAULT_POD_BOOT_TIME}
+	closestDistance := math.Inf(1)
+	for _,s := range settings {
+		distance := math.Abs(float64(s.Replicas - numberReplicas))
+		if s.Replicas > 0 && s.MSCPerSecond > 0 && distance < closestDistance {
+			closestDistance = distance
+			newMSCSetting.MSCPerSecond = s.MSCPerSecond / float64(s.Replicas) * float64(numberReplicas)
+			if s.BootTimeSec > 0 {
+				newMSCSetting.BootTimeSec = s.BootTimeSec
 			}
 		}
 	}
-	//defer serviceProfileDAO.Session.Close()
+	if newMSCSetting.MSCPerSecond == 0 {
+		log.Error("No capacity known for %d replicas", numberReplicas)
+	}
 	return newMSCSetting
 }
 
@@ -379,8 +441,7 @@ func setScalingSteps(scalingSteps *[]types.ScalingAction, currentState types.Sta
 		}
 
 		//newState.LaunchTime = startTransitionTime
-		name,_ := structhash.Hash(newState, 1)
-		newState.Hash = strings.Replace(name, "v1_", "", -1)
+		newState.Hash = stateHash(newState)
 		*scalingSteps = append(*scalingSteps,
 			types.ScalingAction{
 				InitialState:currentState,
@@ -397,11 +458,12 @@ func setScalingSteps(scalingSteps *[]types.ScalingAction, currentState types.Sta
 	in:
 		@numberReplicas	int - number of replicas
 		@limits bool types.Limits - limits constraints(cpu cores and memory gb) per replica
+		@reserved - VMs reserved at the start of the interval
 		@mapVMProfiles - map with the profiles of VMs available
 	out:
 		@VMScale	- Map with the type of VM as key and the number of vms as value
 */
-func buildHeterogeneousVMSet(numberReplicas int, limits types.Limit, mapVMProfiles map[string]types.VmProfile) (types.VMScale,error) {
+func buildHeterogeneousVMSet(numberReplicas int, limits types.Limit, reserved types.VMScale, mapVMProfiles map[string]types.VmProfile) (types.VMScale,error) {
 	var err error
 	tree := &Tree{}
 	node := new(Node)
@@ -416,8 +478,8 @@ func buildHeterogeneousVMSet(numberReplicas int, limits types.Limit, mapVMProfil
 	fmt.Println(len(candidateVMSets))
 	if len(candidateVMSets)> 0{
 		sort.Slice(candidateVMSets, func(i, j int) bool {
-			costi := candidateVMSets[i].Cost(mapVMProfiles)
-			costj := candidateVMSets[j].Cost(mapVMProfiles)
+			costi := unreservedCost(candidateVMSets[i], reserved, mapVMProfiles)
+			costj := unreservedCost(candidateVMSets[j], reserved, mapVMProfiles)
 			if costi < costj {
 				return true
 			} else if costi ==  costj {
@@ -479,11 +541,12 @@ func buildTree(node *Node, numberReplicas int, vmScaleList *[]types.VMScale, map
 	in:
 		@numberReplicas	int - number of replicas
 		@limits bool types.Limits - limits constraints(cpu cores and memory gb) per replica
+		@reserved - VMs reserved at the start of the interval
 		@mapVMProfiles - map with the profiles of VMs available
 	out:
 		@VMScale	- Map with the type of VM as key and the number of vms as value
 */
-func buildHomogeneousVMSet(numberReplicas int, limits types.Limit, mapVMProfiles map[string]types.VmProfile) (types.VMScale,error) {
+func buildHomogeneousVMSet(numberReplicas int, limits types.Limit, reserved types.VMScale, mapVMProfiles map[string]types.VmProfile) (types.VMScale,error) {
 	var err error
 	candidateVMSets := []types.VMScale{}
 	for _,v := range mapVMProfiles {
@@ -497,8 +560,8 @@ func buildHomogeneousVMSet(numberReplicas int, limits types.Limit, mapVMProfiles
 	}
 	if len(candidateVMSets) > 0 {
 		sort.Slice(candidateVMSets, func(i, j int) bool {
-			costi := candidateVMSets[i].Cost(mapVMProfiles)
-			costj := candidateVMSets[j].Cost(mapVMProfiles)
+			costi := unreservedCost(candidateVMSets[i], reserved, mapVMProfiles)
+			costj := unreservedCost(candidateVMSets[j], reserved, mapVMProfiles)
 			if costi < costj {
 				return true
 			} else if costi ==  costj {
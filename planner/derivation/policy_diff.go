@@ -0,0 +1,143 @@
+package derivation
+
+import (
+	"github.com/Cloud-Pie/SPDT/planner/execution"
+	"github.com/Cloud-Pie/SPDT/types"
+	"github.com/Cloud-Pie/SPDT/util"
+	"sort"
+	"time"
+)
+
+/* Compare two policies aligning their scaling actions by time.
+	Each interval of the output keeps the same state in both policies and only the intervals
+	where the policies differ are listed
+	in:
+		@policyA types.Policy	- Reference policy
+		@policyB types.Policy	- Policy compared against the reference
+		@mainService string	- Name of the scaled service
+		@mapVMProfiles map[string]types.VmProfile
+	out:
+		@types.PolicyDiff
+*/
+func DiffPolicies(policyA types.Policy, policyB types.Policy, mainService string, mapVMProfiles map[string]types.VmProfile) types.PolicyDiff {
+	diff := types.PolicyDiff{
+		PolicyA:   policyA.ID.Hex(),
+		PolicyB:   policyB.ID.Hex(),
+		Intervals: []types.IntervalDiff{},
+	}
+
+	timestamps := scalingActionBoundaries(policyA.ScalingActions, policyB.ScalingActions)
+	var previousA, previousB *types.ScalingAction
+	for i := 0; i+1 < len(timestamps); i++ {
+		timeStart := timestamps[i]
+		timeEnd := timestamps[i+1]
+		actionA := execution.ActiveScalingAction(policyA.ScalingActions, timeStart)
+		actionB := execution.ActiveScalingAction(policyB.ScalingActions, timeStart)
+		nDiffs := len(diff.Intervals)
+
+		//Extend the last interval while both policies keep the same states
+		if nDiffs > 0 && actionA == previousA && actionB == previousB &&
+			diff.Intervals[nDiffs-1].TimeEnd.Equal(timeStart) {
+			interval := &diff.Intervals[nDiffs-1]
+			interval.TimeEnd = timeEnd
+			hours := timeEnd.Sub(interval.TimeStart).Hours()
+			interval.CostA = util.RoundN(stateCost(actionA, mapVMProfiles)*hours, 2)
+			interval.CostB = util.RoundN(stateCost(actionB, mapVMProfiles)*hours, 2)
+			interval.CostDelta = util.RoundN(interval.CostB-interval.CostA, 2)
+			continue
+		}
+		previousA, previousB = actionA, actionB
+
+		interval, changed := diffScalingActions(actionA, actionB, mainService)
+		if !changed {
+			continue
+		}
+		hours := timeEnd.Sub(timeStart).Hours()
+		interval.TimeStart = timeStart
+		interval.TimeEnd = timeEnd
+		interval.CostA = util.RoundN(stateCost(actionA, mapVMProfiles)*hours, 2)
+		interval.CostB = util.RoundN(stateCost(actionB, mapVMProfiles)*hours, 2)
+		interval.CostDelta = util.RoundN(interval.CostB-interval.CostA, 2)
+		diff.Intervals = append(diff.Intervals, interval)
+	}
+
+	changedTime := 0.0
+	for _, interval := range diff.Intervals {
+		changedTime += interval.TimeEnd.Sub(interval.TimeStart).Seconds()
+	}
+	costDelta := policyB.Metrics.Cost - policyA.Metrics.Cost
+	costDeltaPercent := 0.0
+	if policyA.Metrics.Cost > 0 {
+		costDeltaPercent = costDelta * 100.0 / policyA.Metrics.Cost
+	}
+	diff.Summary = types.PolicyDiffSummary{
+		CostA:            policyA.Metrics.Cost,
+		CostB:            policyB.Metrics.Cost,
+		CostDelta:        util.RoundN(costDelta, 2),
+		CostDeltaPercent: util.RoundN(costDeltaPercent, 2),
+		ScalingActionsA:  len(policyA.ScalingActions),
+		ScalingActionsB:  len(policyB.ScalingActions),
+		ChangedIntervals: len(diff.Intervals),
+		ChangedTimeSec:   changedTime,
+	}
+	return diff
+}
+
+//Sorted list without duplicates of the times where any of the scaling actions starts or ends
+func scalingActionBoundaries(actionsA []types.ScalingAction, actionsB []types.ScalingAction) []time.Time {
+	var timestamps []time.Time
+	seen := make(map[int64]bool)
+	for _, actions := range [][]types.ScalingAction{actionsA, actionsB} {
+		for _, a := range actions {
+			for _, t := range []time.Time{a.TimeStart, a.TimeEnd} {
+				if !seen[t.UnixNano()] {
+					seen[t.UnixNano()] = true
+					timestamps = append(timestamps, t)
+				}
+			}
+		}
+	}
+	sort.Slice(timestamps, func(i, j int) bool { return timestamps[i].Before(timestamps[j]) })
+	return timestamps
+}
+
+//Compare the desired states of two scaling actions, any of them can be nil
+func diffScalingActions(actionA *types.ScalingAction, actionB *types.ScalingAction, mainService string) (types.IntervalDiff, bool) {
+	interval := types.IntervalDiff{VMsA: types.VMScale{}, VMsB: types.VMScale{}}
+	var serviceA, serviceB types.ServiceInfo
+	if actionA != nil {
+		interval.VMsA = actionA.DesiredState.VMs
+		interval.CapacityA = actionA.Metrics.RequestsCapacity
+		serviceA = actionA.DesiredState.Services[mainService]
+	}
+	if actionB != nil {
+		interval.VMsB = actionB.DesiredState.VMs
+		interval.CapacityB = actionB.Metrics.RequestsCapacity
+		serviceB = actionB.DesiredState.Services[mainService]
+	}
+	vmsA := copyMap(interval.VMsA)
+	vmsB := copyMap(interval.VMsB)
+	cleanKeys(vmsA)
+	cleanKeys(vmsB)
+	interval.VMsAdded, interval.VMsRemoved = DeltaVMSet(vmsA, vmsB)
+	interval.ReplicasA = serviceA.Scale
+	interval.ReplicasB = serviceB.Scale
+	interval.LimitsA = types.Limit{CPUCores: serviceA.CPU, MemoryGB: serviceA.Memory}
+	interval.LimitsB = types.Limit{CPUCores: serviceB.CPU, MemoryGB: serviceB.Memory}
+
+	changed := len(interval.VMsAdded) > 0 || len(interval.VMsRemoved) > 0 ||
+		!serviceA.Equal(serviceB) || interval.CapacityA != interval.CapacityB
+	return interval, changed
+}
+
+//Price per hour of the desired VM set of a scaling action
+func stateCost(action *types.ScalingAction, mapVMProfiles map[string]types.VmProfile) float64 {
+	if action == nil {
+		return 0
+	}
+	cost := 0.0
+	for vmType, n := range action.DesiredState.VMs {
+		cost += vmProfileOf(mapVMProfiles, vmType).Pricing.Price * float64(n)
+	}
+	return cost
+}
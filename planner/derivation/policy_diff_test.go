@@ -0,0 +1,67 @@
+package derivation
+
+import (
+	"github.com/Cloud-Pie/SPDT/types"
+	"gopkg.in/mgo.v2/bson"
+	"testing"
+	"time"
+)
+
+func diffAction(vms int, replicas int, start time.Time, end time.Time) types.ScalingAction {
+	return types.ScalingAction{
+		DesiredState: types.State{
+			VMs:      types.VMScale{"t2.micro": vms},
+			Services: types.Service{"main": types.ServiceInfo{Scale: replicas, CPU: 0.5, Memory: 1}},
+		},
+		TimeStart: start,
+		TimeEnd:   end,
+	}
+}
+
+func TestDiffPolicies(t *testing.T) {
+	t0 := time.Date(2018, 11, 1, 0, 0, 0, 0, time.UTC)
+	h := func(n int) time.Time { return t0.Add(time.Duration(n) * time.Hour) }
+	mapVMProfiles := map[string]types.VmProfile{"t2.micro": {Type: "t2.micro", Pricing: types.Pricing{Price: 0.5}}}
+
+	policyA := types.Policy{ID: bson.NewObjectId(), Metrics: types.PolicyMetrics{Cost: 10},
+		ScalingActions: []types.ScalingAction{diffAction(1, 2, h(0), h(2)), diffAction(2, 4, h(2), h(4))}}
+	policyB := types.Policy{ID: bson.NewObjectId(), Metrics: types.PolicyMetrics{Cost: 12},
+		ScalingActions: []types.ScalingAction{diffAction(1, 2, h(0), h(1)), diffAction(2, 4, h(1), h(3)),
+			diffAction(2, 3, h(3), h(4))}}
+
+	diff := DiffPolicies(policyA, policyB, "main", mapVMProfiles)
+	if len(diff.Intervals) != 2 {
+		t.Fatalf("Expected 2 changed intervals, got %+v", diff.Intervals)
+	}
+
+	//B scales out one hour earlier
+	out := diff.Intervals[0]
+	if !out.TimeStart.Equal(h(1)) || !out.TimeEnd.Equal(h(2)) || out.VMsAdded["t2.micro"] != 1 ||
+		len(out.VMsRemoved) != 0 || out.ReplicasA != 2 || out.ReplicasB != 4 ||
+		out.CostA != 0.5 || out.CostB != 1 || out.CostDelta != 0.5 {
+		t.Errorf("Expected B to add one VM and two replicas from %s to %s, got %+v", h(1), h(2), out)
+	}
+
+	//Only the replicas differ
+	replicas := diff.Intervals[1]
+	if !replicas.TimeStart.Equal(h(3)) || !replicas.TimeEnd.Equal(h(4)) || len(replicas.VMsAdded) != 0 ||
+		len(replicas.VMsRemoved) != 0 || replicas.ReplicasA != 4 || replicas.ReplicasB != 3 || replicas.CostDelta != 0 {
+		t.Errorf("Expected only the replicas to change from %s to %s, got %+v", h(3), h(4), replicas)
+	}
+
+	expected := types.PolicyDiffSummary{CostA: 10, CostB: 12, CostDelta: 2, CostDeltaPercent: 20,
+		ScalingActionsA: 2, ScalingActionsB: 3, ChangedIntervals: 2, ChangedTimeSec: 7200}
+	if diff.Summary != expected {
+		t.Error(
+			"For: ", "DiffPolicies summary",
+			"expected: ", expected,
+			"got: ", diff.Summary,
+		)
+	}
+
+	//A policy compared with itself has no differences
+	same := DiffPolicies(policyA, policyA, "main", mapVMProfiles)
+	if len(same.Intervals) != 0 || same.Summary.CostDelta != 0 {
+		t.Errorf("Expected no differences, got %+v", same)
+	}
+}
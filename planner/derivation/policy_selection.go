@@ -4,6 +4,8 @@ import (
 	"sort"
 	"errors"
 	"github.com/Cloud-Pie/SPDT/util"
+	"github.com/Cloud-Pie/SPDT/monitoring"
+	"time"
 )
 
 /*Evaluates and select the most suitable policy for the given system configurations and forecast
@@ -30,8 +32,10 @@ func SelectPolicy(policies *[]types.Policy, sysConfig util.SystemConfiguration,
 		policyMetrics, vmTypes:= ComputePolicyMetrics(&(*policies)[i].ScalingActions,forecast.ForecastedValues, systemConfiguration, mapVMProfiles )
 		policyMetrics.StartTimeDerivation = (*policies)[i].Metrics.StartTimeDerivation
 		policyMetrics.FinishTimeDerivation = (*policies)[i].Metrics.FinishTimeDerivation
+		policyMetrics.BillingBoundarySavings = (*policies)[i].Metrics.BillingBoundarySavings
 		duration := (*policies)[i].Metrics.FinishTimeDerivation.Sub((*policies)[i].Metrics.StartTimeDerivation).Seconds()
 		policyMetrics.DerivationDuration = util.RoundN(duration, 2.0)
+		monitoring.ObserveDerivation(sysConfig.MainServiceName, (*policies)[i].Algorithm, duration)
 		(*policies)[i].Metrics = policyMetrics
 		(*policies)[i].Parameters[types.VMTYPES] = MapKeysToString(vmTypes)
 	}
@@ -49,12 +53,16 @@ func SelectPolicy(policies *[]types.Policy, sysConfig util.SystemConfiguration,
 	})
 
 	if len(*policies) >0 {
-		remainBudget, time := isEnoughBudget(sysConfig.PricingModel.Budget, (*policies)[0])
+		ledger := policyLedger(sysConfig.MainServiceName, (*policies)[0])
+		remainBudget, time := withinMonthlyBudget((*policies)[0], sysConfig.PricingModel.Budget, ledger)
 		if remainBudget {
 			(*policies)[0].Status = types.SELECTED
-			return (*policies)[0], nil
+			selected := (*policies)[0]
+			monitoring.SetSelectedPolicy(sysConfig.MainServiceName, selected.Algorithm, selected.Metrics.Cost,
+				selected.Metrics.OverProvision, selected.Metrics.UnderProvision, len(selected.ScalingActions))
+			return selected, nil
 		} else {
-			return (*policies)[0], errors.New("Budget is not enough for time window, you should increase the budget to ensure resources after " +time.String())
+			return (*policies)[0], errors.New("The remaining monthly budget is not enough for time window, you should increase the budget to ensure resources after " +time.String())
 		}
 	} else {
 		return types.Policy{}, errors.New("No suitable policy found")
@@ -82,9 +90,9 @@ func ComputePolicyMetrics(scalingActions *[]types.ScalingAction, forecast []type
 	totalTransitionTime := 0.0
 	totalShadowTime := 0.0
 
+	_, actionCosts := BillingCosts(*scalingActions, sysConfiguration.PricingModel, mapVMProfiles)
 	index := 0
 	numberScalingActions := len(*scalingActions)
-	nPredictedValues := len(forecast)
 	for i, _ := range *scalingActions {
 		scalingAction := (*scalingActions)[i]
 		var underProvision float64
@@ -97,29 +105,11 @@ func ComputePolicyMetrics(scalingActions *[]types.ScalingAction, forecast []type
 		var memUtilization float64
 
 		//Capacity
-		scaleActionOverProvision := 0.0
-		scaleActionUnderProvision := 0.0
-		numSamplesOver := 0.0
-		numSamplesUnder := 0.0
-		for  index < nPredictedValues && scalingAction.TimeEnd.After(forecast[index].TimeStamp) {
-			deltaLoad := scalingAction.Metrics.RequestsCapacity - forecast[index].Requests
-			if deltaLoad > 0 {
-				scaleActionOverProvision += deltaLoad*100.0/ forecast[index].Requests
-				numSamplesOver++
-			} else if deltaLoad < 0 {
-				scaleActionUnderProvision += -1*deltaLoad*100.0/ forecast[index].Requests
-				numSamplesUnder++
-			}
-			index++
-		}
-		if numSamplesUnder > 0 {
-			underProvision = util.RoundN(scaleActionUnderProvision/numSamplesUnder, 2.0)
-			totalUnder += scaleActionUnderProvision /numSamplesUnder
-		}
-		if numSamplesOver > 0 {
-			overProvision = util.RoundN(scaleActionOverProvision/numSamplesOver, 2.0)
-			totalOver += scaleActionOverProvision /numSamplesOver
-		}
+		actionOver, actionUnder := actionProvisioning(scalingAction.Metrics.RequestsCapacity, scalingAction.TimeEnd, forecast, &index)
+		underProvision = util.RoundN(actionUnder, 2.0)
+		overProvision = util.RoundN(actionOver, 2.0)
+		totalUnder += actionUnder
+		totalOver += actionOver
 
 		//Other metrics
 		vmSetDesired := scalingAction.DesiredState.VMs
@@ -136,14 +126,12 @@ func ComputePolicyMetrics(scalingActions *[]types.ScalingAction, forecast []type
 
 		totalCPUCoresInVMSet := 0.0
 		totalMemGBInVMSet := 0.0
-		deltaTime := BilledTime(scalingAction.TimeStart, scalingAction.TimeEnd, sysConfiguration.PricingModel.BillingUnit)
 		for k,v := range vmSetDesired {
 			vmTypes[k] = true
-			totalCPUCoresInVMSet += mapVMProfiles[k].CPUCores * float64(v)
-			totalMemGBInVMSet += mapVMProfiles[k].Memory * float64(v)
-			totalPrice := mapVMProfiles [k].Pricing.Price * float64(v) * deltaTime
-			cost +=  util.RoundN(totalPrice, 2.0)
+			totalCPUCoresInVMSet += vmProfileOf(mapVMProfiles, k).CPUCores * float64(v)
+			totalMemGBInVMSet += vmProfileOf(mapVMProfiles, k).Memory * float64(v)
 		}
+		cost = util.RoundN(actionCosts[i], 2.0)
 		totalCost += cost
 
 		if i>1 {
@@ -173,13 +161,14 @@ func ComputePolicyMetrics(scalingActions *[]types.ScalingAction, forecast []type
 		(*scalingActions)[i].Metrics = configMetrics
 	}
 
+	risk := computeSpotRisk(*scalingActions, totalCost, mapVMProfiles)
 	avgOverProvision = totalOver/ float64(numberScalingActions)
 	avgUnderProvision = totalUnder / float64(numberScalingActions)
 	avgElapsedTime = totalElapsedTime / float64(numberScalingActions)
 	avgTransitionTime = totalTransitionTime / float64(numberScalingActions)
 	avgShadowTime = totalShadowTime / float64(numberScalingActions)
 
-	return types.PolicyMetrics {
+	metrics := types.PolicyMetrics {
 		Cost:	util.RoundN(totalCost, 2.0),
 		OverProvision:	util.RoundN(avgOverProvision, 2.0),
 		UnderProvision:	util.RoundN(avgUnderProvision, 2.0),
@@ -189,5 +178,46 @@ func ComputePolicyMetrics(scalingActions *[]types.ScalingAction, forecast []type
 		AvgElapsedTime:	util.RoundN(avgElapsedTime, 2.0),
 		AvgShadowTime:	util.RoundN(avgShadowTime, 2.0),
 		AvgTransitionTime:	util.RoundN(avgTransitionTime, 2.0),
-	}, vmTypes
+		ExpectedCost:	util.RoundN(risk.ExpectedCost, 2.0),
+		ExpectedInterruptions:	util.RoundN(risk.ExpectedInterruptions, 2.0),
+		CapacityAtRisk:	util.RoundN(risk.CapacityAtRisk, 2.0),
+		ReservedUtilization:	util.RoundN(reservedUtilization(*scalingActions, sysConfiguration.PricingModel.Reservations), 2.0),
+	}
+	return metrics, vmTypes
+}
+
+/* Compute the average over and under provision of a scaling action for the forecasted values until its end
+	in:
+		@requestsCapacity float64	- Requests per second served by the scaling action
+		@timeEnd time.Time
+		@forecast []types.ForecastedValue
+		@index *int	- First forecasted value not evaluated yet, it is moved past the end of the action
+	out:
+		@float64	- Average percentage over provisioned, 0 if the load is never under the capacity
+		@float64	- Average percentage under provisioned, 0 if the load is never over the capacity
+*/
+func actionProvisioning(requestsCapacity float64, timeEnd time.Time, forecast []types.ForecastedValue, index *int) (float64, float64) {
+	scaleActionOverProvision := 0.0
+	scaleActionUnderProvision := 0.0
+	numSamplesOver := 0.0
+	numSamplesUnder := 0.0
+	for  *index < len(forecast) && timeEnd.After(forecast[*index].TimeStamp) {
+		deltaLoad := requestsCapacity - forecast[*index].Requests
+		if deltaLoad > 0 {
+			scaleActionOverProvision += deltaLoad*100.0/ forecast[*index].Requests
+			numSamplesOver++
+		} else if deltaLoad < 0 {
+			scaleActionUnderProvision += -1*deltaLoad*100.0/ forecast[*index].Requests
+			numSamplesUnder++
+		}
+		*index++
+	}
+	over, under := 0.0, 0.0
+	if numSamplesOver > 0 {
+		over = scaleActionOverProvision/numSamplesOver
+	}
+	if numSamplesUnder > 0 {
+		under = scaleActionUnderProvision/numSamplesUnder
+	}
+	return over, under
 }
\ No newline at end of file
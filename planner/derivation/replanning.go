@@ -0,0 +1,144 @@
+package derivation
+
+import (
+	"github.com/Cloud-Pie/SPDT/types"
+	"time"
+)
+
+/* Number of scaling actions of a policy that can not be changed anymore.
+	All the actions before the violated one are kept, as well as the actions whose transition already started.
+	The violated action may be one of them, ReplanningStart tells then from when its interval is planned again
+	in:
+		@policy types.Policy
+		@violatedIndex int	- Index of the first scaling action that violates the thresholds
+		@now time.Time
+	out:
+		@int	- Length of the committed prefix
+*/
+func CommittedScalingActions(policy types.Policy, violatedIndex int, now time.Time) int {
+	committed := violatedIndex
+	for committed < len(policy.ScalingActions) && !policy.ScalingActions[committed].TimeStartTransition.After(now) {
+		committed++
+	}
+	return committed
+}
+
+/* Time from which a policy is derived again after a threshold violation.
+	The violated action can not be replaced when its transition already started, then the rest of its
+	interval is planned again from now
+	in:
+		@policy types.Policy
+		@violatedIndex int	- Index of the first scaling action that violates the thresholds
+		@committed int	- Length of the committed prefix
+		@now time.Time
+	out:
+		@time.Time
+*/
+func ReplanningStart(policy types.Policy, violatedIndex int, committed int, now time.Time) time.Time {
+	if committed > violatedIndex {
+		return now
+	}
+	return policy.ScalingActions[committed].TimeStart
+}
+
+/* Keep only the forecasted values from a given time
+	in:
+		@forecast types.Forecast
+		@timeStart time.Time
+	out:
+		@types.Forecast	- Forecast whose window starts at timeStart
+*/
+func ForecastFrom(forecast types.Forecast, timeStart time.Time) types.Forecast {
+	values := []types.ForecastedValue{}
+	for _, v := range forecast.ForecastedValues {
+		if !v.TimeStamp.Before(timeStart) {
+			values = append(values, v)
+		}
+	}
+	forecast.ForecastedValues = values
+	forecast.TimeWindowStart = timeStart
+	return forecast
+}
+
+/* Replace the scaling actions of a policy after its committed prefix with the actions of a re-derived policy.
+	The last committed action ends when the new actions start, and when the first new action keeps
+	the last committed state, both are joined in a single action
+	in:
+		@policy types.Policy	- Current policy
+		@committed int	- Length of the committed prefix
+		@suffix types.Policy	- Policy derived from the last committed state
+	out:
+		@types.Policy	- Policy with the same ID and the new scaling actions
+*/
+func ReplaceScalingActions(policy types.Policy, committed int, suffix types.Policy) types.Policy {
+	scalingActions := make([]types.ScalingAction, committed)
+	copy(scalingActions, policy.ScalingActions[:committed])
+	newActions := suffix.ScalingActions
+	if committed > 0 && len(newActions) > 0 && scalingActions[committed-1].TimeEnd.After(newActions[0].TimeStart) {
+		scalingActions[committed-1].TimeEnd = newActions[0].TimeStart
+	}
+	if committed > 0 && len(newActions) > 0 && newActions[0].DesiredState.Equal(scalingActions[committed-1].DesiredState) {
+		scalingActions[committed-1].TimeEnd = newActions[0].TimeEnd
+		newActions = newActions[1:]
+	}
+	scalingActions = append(scalingActions, newActions...)
+
+	parameters := make(map[string]string)
+	for k, v := range policy.Parameters {
+		parameters[k] = v
+	}
+	if len(newActions) > 0 {
+		parameters[types.REPLANNED_FROM] = newActions[0].TimeStart.Format(time.RFC3339)
+	}
+	policy.Parameters = parameters
+	policy.ScalingActions = scalingActions
+	return policy
+}
+
+/* Append the scaling actions of a new segment to a schedule.
+	The last action of the schedule is extended until the segment starts, and it is joined with the first
+	action of the segment when both keep the same state, then there are neither gaps nor duplicated states
+	in:
+		@schedule []types.ScalingAction
+		@segment []types.ScalingAction	- Actions derived from the last state of the schedule
+	out:
+		@[]types.ScalingAction	- New schedule, the input slices are not modified
+*/
+func StitchScalingActions(schedule []types.ScalingAction, segment []types.ScalingAction) []types.ScalingAction {
+	scalingActions := make([]types.ScalingAction, len(schedule), len(schedule)+len(segment))
+	copy(scalingActions, schedule)
+	n := len(scalingActions)
+	if n > 0 && len(segment) > 0 {
+		last := &scalingActions[n-1]
+		if segment[0].TimeStart.After(last.TimeEnd) {
+			last.TimeEnd = segment[0].TimeStart
+		}
+		if segment[0].DesiredState.Equal(last.DesiredState) {
+			if segment[0].TimeEnd.After(last.TimeEnd) {
+				last.TimeEnd = segment[0].TimeEnd
+			}
+			segment = segment[1:]
+		}
+	}
+	return append(scalingActions, segment...)
+}
+
+/* Keep the scaling actions of a segment that start before the end of the commit horizon
+	in:
+		@segment []types.ScalingAction
+		@commitEnd time.Time
+	out:
+		@[]types.ScalingAction	- Committed actions, the last one ends at commitEnd
+*/
+func CommitSegment(segment []types.ScalingAction, commitEnd time.Time) []types.ScalingAction {
+	committed := []types.ScalingAction{}
+	for _, a := range segment {
+		if a.TimeStart.Before(commitEnd) {
+			committed = append(committed, a)
+		}
+	}
+	if n := len(committed); n > 0 {
+		committed[n-1].TimeEnd = commitEnd
+	}
+	return committed
+}
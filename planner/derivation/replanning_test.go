@@ -0,0 +1,172 @@
+package derivation
+
+import (
+	"github.com/Cloud-Pie/SPDT/types"
+	"testing"
+	"time"
+)
+
+func stitchingAction(vms int, start time.Time, end time.Time) types.ScalingAction {
+	return types.ScalingAction{
+		DesiredState: types.State{VMs: types.VMScale{"t2.micro": vms}},
+		TimeStart:    start,
+		TimeEnd:      end,
+	}
+}
+
+func TestStitchScalingActions(t *testing.T) {
+	t0 := time.Date(2018, 11, 1, 0, 0, 0, 0, time.UTC)
+	h := func(n int) time.Time { return t0.Add(time.Duration(n) * time.Hour) }
+
+	schedule := CommitSegment([]types.ScalingAction{
+		stitchingAction(1, h(0), h(2)),
+		stitchingAction(2, h(2), h(5)),
+		stitchingAction(3, h(5), h(8)),
+	}, h(4))
+	if len(schedule) != 2 || !schedule[1].TimeEnd.Equal(h(4)) {
+		t.Error(
+			"For: ", "CommitSegment",
+			"expected: ", 2, h(4),
+			"got: ", len(schedule), schedule[len(schedule)-1].TimeEnd,
+		)
+	}
+
+	//The first action of the segment keeps the last committed state
+	stitched := StitchScalingActions(schedule, []types.ScalingAction{
+		stitchingAction(2, h(4), h(6)),
+		stitchingAction(1, h(6), h(9)),
+	})
+	if len(stitched) != 3 || !stitched[1].TimeEnd.Equal(h(6)) || !stitched[2].TimeStart.Equal(h(6)) {
+		t.Error(
+			"For: ", "StitchScalingActions with equal states",
+			"expected: ", 3, h(6),
+			"got: ", len(stitched), stitched[1].TimeEnd,
+		)
+	}
+	if !schedule[1].TimeEnd.Equal(h(4)) {
+		t.Error("StitchScalingActions modified the input schedule")
+	}
+
+	//A gap between the schedule and the segment is closed
+	stitched = StitchScalingActions(stitched, []types.ScalingAction{stitchingAction(4, h(10), h(12))})
+	if len(stitched) != 4 || !stitched[2].TimeEnd.Equal(h(10)) {
+		t.Error(
+			"For: ", "StitchScalingActions with gap",
+			"expected: ", 4, h(10),
+			"got: ", len(stitched), stitched[2].TimeEnd,
+		)
+	}
+}
+
+func TestCommittedScalingActions(t *testing.T) {
+	t0 := time.Date(2018, 11, 1, 0, 0, 0, 0, time.UTC)
+	h := func(n int) time.Time { return t0.Add(time.Duration(n) * time.Hour) }
+	policy := types.Policy{ScalingActions: []types.ScalingAction{
+		stitchingAction(1, h(0), h(2)),
+		stitchingAction(2, h(2), h(4)),
+		stitchingAction(3, h(4), h(6)),
+	}}
+	for i := range policy.ScalingActions {
+		policy.ScalingActions[i].TimeStartTransition = policy.ScalingActions[i].TimeStart.Add(-10 * time.Minute)
+	}
+
+	var tests = []struct {
+		violatedIndex int
+		now           time.Time
+		committed     int
+		timeStart     time.Time
+	}{
+		{0, h(-1), 0, h(0)},
+		{1, h(1), 1, h(2)},
+		//The transition of the violated action already started
+		{1, h(2), 2, h(2)},
+		{1, h(3).Add(30 * time.Minute), 2, h(3).Add(30 * time.Minute)},
+		{0, h(5), 3, h(5)},
+	}
+	for _, test := range tests {
+		committed := CommittedScalingActions(policy, test.violatedIndex, test.now)
+		timeStart := ReplanningStart(policy, test.violatedIndex, committed, test.now)
+		if committed != test.committed || !timeStart.Equal(test.timeStart) {
+			t.Error(
+				"For: ", test.violatedIndex, test.now,
+				"expected: ", test.committed, test.timeStart,
+				"got: ", committed, timeStart,
+			)
+		}
+	}
+}
+
+func TestForecastFrom(t *testing.T) {
+	t0 := time.Date(2018, 11, 1, 0, 0, 0, 0, time.UTC)
+	forecast := types.Forecast{TimeWindowStart: t0}
+	for i := 0; i < 4; i++ {
+		forecast.ForecastedValues = append(forecast.ForecastedValues,
+			types.ForecastedValue{TimeStamp: t0.Add(time.Duration(i) * time.Hour), Requests: float64(i)})
+	}
+
+	suffix := ForecastFrom(forecast, t0.Add(90*time.Minute))
+	if len(suffix.ForecastedValues) != 2 || suffix.ForecastedValues[0].Requests != 2 ||
+		!suffix.TimeWindowStart.Equal(t0.Add(90*time.Minute)) {
+		t.Error(
+			"For: ", "ForecastFrom",
+			"expected: ", 2, 2.0,
+			"got: ", len(suffix.ForecastedValues), suffix.ForecastedValues,
+		)
+	}
+	if len(forecast.ForecastedValues) != 4 || !forecast.TimeWindowStart.Equal(t0) {
+		t.Error("ForecastFrom modified the input forecast")
+	}
+}
+
+func TestReplaceScalingActions(t *testing.T) {
+	t0 := time.Date(2018, 11, 1, 0, 0, 0, 0, time.UTC)
+	h := func(n int) time.Time { return t0.Add(time.Duration(n) * time.Hour) }
+	policy := types.Policy{
+		Parameters: map[string]string{"algorithm": "naive"},
+		ScalingActions: []types.ScalingAction{
+			stitchingAction(1, h(0), h(2)),
+			stitchingAction(2, h(2), h(4)),
+			stitchingAction(3, h(4), h(6)),
+		},
+	}
+
+	var tests = []struct {
+		name          string
+		committed     int
+		suffix        []types.ScalingAction
+		timeEnds      []time.Time
+		replannedFrom time.Time
+	}{
+		{"Different states", 2,
+			[]types.ScalingAction{stitchingAction(4, h(4), h(6))},
+			[]time.Time{h(2), h(4), h(6)}, h(4)},
+		{"Equal states are joined", 2,
+			[]types.ScalingAction{stitchingAction(2, h(4), h(5)), stitchingAction(4, h(5), h(6))},
+			[]time.Time{h(2), h(5), h(6)}, h(5)},
+		{"Committed action in transition is cut", 2,
+			[]types.ScalingAction{stitchingAction(4, h(3), h(6))},
+			[]time.Time{h(2), h(3), h(6)}, h(3)},
+	}
+	for _, test := range tests {
+		replaced := ReplaceScalingActions(policy, test.committed, types.Policy{ScalingActions: test.suffix})
+		timeEnds := []time.Time{}
+		for _, a := range replaced.ScalingActions {
+			timeEnds = append(timeEnds, a.TimeEnd)
+		}
+		ok := len(timeEnds) == len(test.timeEnds)
+		for i := 0; ok && i < len(timeEnds); i++ {
+			ok = timeEnds[i].Equal(test.timeEnds[i])
+		}
+		replannedFrom, _ := time.Parse(time.RFC3339, replaced.Parameters[types.REPLANNED_FROM])
+		if !ok || !replannedFrom.Equal(test.replannedFrom) || replaced.Parameters["algorithm"] != "naive" {
+			t.Error(
+				"For: ", test.name,
+				"expected: ", test.timeEnds, test.replannedFrom,
+				"got: ", timeEnds, replaced.Parameters,
+			)
+		}
+	}
+	if !policy.ScalingActions[1].TimeEnd.Equal(h(4)) || policy.Parameters[types.REPLANNED_FROM] != "" {
+		t.Error("ReplaceScalingActions modified the input policy")
+	}
+}
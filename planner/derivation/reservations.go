@@ -0,0 +1,93 @@
+package derivation
+
+import (
+	"github.com/Cloud-Pie/SPDT/types"
+	"github.com/Cloud-Pie/SPDT/util"
+	"math"
+	"time"
+)
+
+/* Number of VMs of each type reserved at a given time
+	in:
+		@reservations []util.Reservation
+		@t time.Time
+	out:
+		@types.VMScale
+*/
+func ReservedVMs(reservations []util.Reservation, t time.Time) types.VMScale {
+	reserved := types.VMScale{}
+	for _, r := range reservations {
+		if !r.Start.IsZero() && t.Before(r.Start) {
+			continue
+		}
+		if !r.Start.IsZero() && r.Term != "" &&
+			!t.Before(r.Start.Add(time.Duration(util.ParseIntervalToSeconds(r.Term))*time.Second)) {
+			continue
+		}
+		reserved[r.VMType] += r.Count
+	}
+	return reserved
+}
+
+//Number of VMs of a set covered by the reservations
+func reservedInSet(vmSet types.VMScale, reserved types.VMScale) types.VMScale {
+	covered := types.VMScale{}
+	for vmType, n := range vmSet {
+		if r := reserved[vmType]; r > 0 {
+			covered[vmType] = int(math.Min(float64(n), float64(r)))
+		}
+	}
+	return covered
+}
+
+/* Price per hour of a VM set for the algorithms. Reserved VMs are already paid, so only the VMs over
+	the reservations are charged and VM sets that fill the reservations are preferred
+	in:
+		@vmSet types.VMScale
+		@reserved types.VMScale	- VMs reserved at the start of the interval
+		@mapVMProfiles map[string]types.VmProfile
+	out:
+		@float64
+*/
+func unreservedCost(vmSet types.VMScale, reserved types.VMScale, mapVMProfiles map[string]types.VmProfile) float64 {
+	covered := reservedInSet(vmSet, reserved)
+	cost := 0.0
+	for vmType, n := range vmSet {
+		cost += vmProfileOf(mapVMProfiles, vmType).Pricing.Price * float64(n-covered[vmType])
+	}
+	return cost
+}
+
+/* Compute the percentage of the reserved VM hours used by the scaling actions
+	in:
+		@scalingActions []types.ScalingAction
+		@reservations []util.Reservation
+	out:
+		@float64	- 0 if there are no reservations
+*/
+func reservedUtilization(scalingActions []types.ScalingAction, reservations []util.Reservation) float64 {
+	usedHours, reservedHours := 0.0, 0.0
+	for i, a := range scalingActions {
+		hours := actionHours(scalingActions, i)
+		reserved := ReservedVMs(reservations, a.TimeStart)
+		for _, n := range reserved {
+			reservedHours += float64(n) * hours
+		}
+		for _, n := range reservedInSet(a.DesiredState.VMs, reserved) {
+			usedHours += float64(n) * hours
+		}
+	}
+	if reservedHours == 0 {
+		return 0
+	}
+	return usedHours * 100 / reservedHours
+}
+
+//Hours from the start of a scaling action to the start of the next one, or to its end for the last action
+func actionHours(scalingActions []types.ScalingAction, i int) float64 {
+	end := scalingActions[i].TimeEnd
+	if i < len(scalingActions)-1 {
+		end = scalingActions[i+1].TimeStart
+	}
+	return math.Max(end.Sub(scalingActions[i].TimeStart).Hours(), 0)
+}
@@ -0,0 +1,104 @@
+package derivation
+
+import (
+	"github.com/Cloud-Pie/SPDT/types"
+	"github.com/Cloud-Pie/SPDT/util"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReservedVMs(t *testing.T) {
+	start := time.Date(2018, 11, 1, 7, 0, 0, 0, time.UTC)
+	reservations := []util.Reservation{
+		{VMType: "t2.large", Count: 2},
+		{VMType: "t2.large", Count: 1, Start: start.AddDate(-2, 0, 0), Term: "12M"},
+		{VMType: "t2.micro", Count: 4, Start: start.Add(-23 * time.Hour), Term: "1D"},
+		{VMType: "t2.medium", Count: 1, Start: start.Add(time.Hour)},
+	}
+
+	var tests = []struct {
+		name     string
+		t        time.Time
+		reserved types.VMScale
+	}{
+		//The second reservation expired and the last one did not start
+		{"Start of the window", start, types.VMScale{"t2.large": 2, "t2.micro": 4}},
+		//The t2.micro reservation expires one hour after the start of the window
+		{"Expiration of a reservation", start.Add(time.Hour), types.VMScale{"t2.large": 2, "t2.medium": 1}},
+	}
+	for _, test := range tests {
+		reserved := ReservedVMs(reservations, test.t)
+		if !reflect.DeepEqual(reserved, test.reserved) {
+			t.Error(
+				"For: ", test.name,
+				"expected: ", test.reserved,
+				"got: ", reserved,
+			)
+		}
+	}
+}
+
+func TestReservationsCost(t *testing.T) {
+	start := time.Date(2018, 11, 1, 7, 0, 0, 0, time.UTC)
+	scalingActions := []types.ScalingAction{
+		{TimeStart: start, TimeEnd: start.Add(time.Hour),
+			DesiredState: types.State{VMs: types.VMScale{"t2.large": 3}}},
+		{TimeStart: start.Add(time.Hour), TimeEnd: start.Add(2 * time.Hour),
+			DesiredState: types.State{VMs: types.VMScale{"t2.large": 1}}},
+	}
+	mapVMProfiles := map[string]types.VmProfile{"t2.large": {Type: "t2.large", Pricing: types.Pricing{Price: 1}}}
+
+	var tests = []struct {
+		name         string
+		reservations []util.Reservation
+		total        float64
+		utilization  float64
+	}{
+		//Only the VM over the reservation in the first hour is charged
+		{"Reservation for the whole window", []util.Reservation{{VMType: "t2.large", Count: 2}}, 1, 75},
+		//After the expiration the VM of the second hour is charged and the reserved hours end
+		{"Reservation expiring in the window",
+			[]util.Reservation{{VMType: "t2.large", Count: 2, Start: start.Add(-23 * time.Hour), Term: "1D"}}, 2, 100},
+	}
+	for _, test := range tests {
+		pricingModel := util.PricingModel{BillingUnit: util.HOUR, Reservations: test.reservations}
+		total, _ := BillingCosts(scalingActions, pricingModel, mapVMProfiles)
+		utilization := reservedUtilization(scalingActions, test.reservations)
+		if math.Abs(total-test.total) > 1e-9 || math.Abs(utilization-test.utilization) > 1e-9 {
+			t.Error(
+				"For: ", test.name,
+				"expected: ", test.total, test.utilization,
+				"got: ", total, utilization,
+			)
+		}
+	}
+}
+
+func TestUnreservedCost(t *testing.T) {
+	mapVMProfiles := map[string]types.VmProfile{
+		"t2.large": {Type: "t2.large", Pricing: types.Pricing{Price: 1}},
+		"t2.micro": {Type: "t2.micro", Pricing: types.Pricing{Price: 0.1}},
+	}
+	vmSet := types.VMScale{"t2.large": 3, "t2.micro": 2}
+
+	var tests = []struct {
+		reserved types.VMScale
+		cost     float64
+	}{
+		{types.VMScale{}, 3.2},
+		{types.VMScale{"t2.large": 2}, 1.2},
+		{types.VMScale{"t2.large": 5, "t2.micro": 1}, 0.1},
+	}
+	for _, test := range tests {
+		cost := unreservedCost(vmSet, test.reserved, mapVMProfiles)
+		if math.Abs(cost-test.cost) > 1e-9 {
+			t.Error(
+				"For: ", test.reserved,
+				"expected: ", test.cost,
+				"got: ", cost,
+			)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package derivation
+
+import (
+	"github.com/Cloud-Pie/SPDT/types"
+	"github.com/Cloud-Pie/SPDT/util"
+	"math"
+	"sort"
+)
+
+//Expected cost and interruption risk of the spot VMs of a policy
+type spotRisk struct {
+	ExpectedCost          float64
+	ExpectedInterruptions float64
+	CapacityAtRisk        float64
+}
+
+/* Profile of a VM type. Spot variants take the profile of their on-demand type with the spot discount applied
+	in:
+		@mapVMProfiles map[string]types.VmProfile
+		@vmType string
+	out:
+		@types.VmProfile
+*/
+func vmProfileOf(mapVMProfiles map[string]types.VmProfile, vmType string) types.VmProfile {
+	if profile, ok := mapVMProfiles[vmType]; ok || !types.IsSpotType(vmType) {
+		return profile
+	}
+	profile := mapVMProfiles[types.OnDemandType(vmType)]
+	profile.Type = vmType
+	profile.Pricing.Price = profile.Pricing.Price * (1 - profile.Spot.Discount)
+	return profile
+}
+
+//VM set with the spot variants replaced by their on-demand type
+func onDemandVMs(vms types.VMScale) types.VMScale {
+	onDemand := types.VMScale{}
+	for vmType, n := range vms {
+		onDemand[types.OnDemandType(vmType)] += n
+	}
+	return onDemand
+}
+
+/* Replace part of the VMs of the scaling actions with their spot variants.
+	Each action keeps on demand the share of its VMs needed to serve the base load and the reserved VMs,
+	and uses spot VMs for the rest if the VM type has a spot variant
+	in:
+		@scalingActions []types.ScalingAction
+		@baseRequests float64	- Load served with on-demand VMs
+		@reservations []util.Reservation
+		@mapVMProfiles map[string]types.VmProfile
+	out:
+		@[]types.ScalingAction
+*/
+func MixSpotVMs(scalingActions []types.ScalingAction, baseRequests float64, reservations []util.Reservation,
+	mapVMProfiles map[string]types.VmProfile) []types.ScalingAction {
+	mixed := make([]types.ScalingAction, len(scalingActions))
+	for i, a := range scalingActions {
+		onDemandShare := 1.0
+		if a.Metrics.RequestsCapacity > 0 {
+			onDemandShare = math.Min(1, baseRequests/a.Metrics.RequestsCapacity)
+		}
+		reserved := ReservedVMs(reservations, a.TimeStart)
+		vms := types.VMScale{}
+		for vmType, n := range a.DesiredState.VMs {
+			if mapVMProfiles[vmType].Spot.Discount <= 0 {
+				vms[vmType] += n
+				continue
+			}
+			onDemand := int(math.Max(math.Ceil(float64(n)*onDemandShare), math.Min(float64(n), float64(reserved[vmType]))))
+			if onDemand > 0 {
+				vms[vmType] += onDemand
+			}
+			if n > onDemand {
+				vms[types.SpotType(vmType)] += n - onDemand
+			}
+		}
+		a.DesiredState.VMs = vms
+		a.DesiredState.Hash = stateHash(a.DesiredState)
+		if i > 0 {
+			a.InitialState.VMs = mixed[i-1].DesiredState.VMs
+		}
+		mixed[i] = a
+	}
+	return mixed
+}
+
+/* Compute the expected cost and interruption risk of the spot VMs of the scaling actions.
+	Interruptions follow a Poisson process with the interruption rate of the VM type, and an interrupted
+	VM is replaced by an on-demand VM for the rest of the scaling action
+	in:
+		@scalingActions []types.ScalingAction
+		@cost float64	- Cost of the policy without interruptions
+		@mapVMProfiles map[string]types.VmProfile
+	out:
+		@spotRisk
+*/
+func computeSpotRisk(scalingActions []types.ScalingAction, cost float64, mapVMProfiles map[string]types.VmProfile) spotRisk {
+	risk := spotRisk{ExpectedCost: cost}
+	for _, a := range scalingActions {
+		hours := a.TimeEnd.Sub(a.TimeStart).Hours()
+		totalVMs, spotVMs := 0, 0
+		for vmType, n := range a.DesiredState.VMs {
+			totalVMs += n
+			if !types.IsSpotType(vmType) {
+				continue
+			}
+			spotVMs += n
+			onDemand := mapVMProfiles[types.OnDemandType(vmType)]
+			rate := onDemand.Spot.InterruptionRate
+			if rate <= 0 || hours <= 0 {
+				continue
+			}
+			//Expected hours between the interruption and the end of the action
+			replacedHours := hours - (1-math.Exp(-rate*hours))/rate
+			extraPrice := onDemand.Pricing.Price - vmProfileOf(mapVMProfiles, vmType).Pricing.Price
+			risk.ExpectedCost += float64(n) * replacedHours * extraPrice
+			risk.ExpectedInterruptions += float64(n) * rate * hours
+		}
+		if totalVMs > 0 {
+			risk.CapacityAtRisk = math.Max(risk.CapacityAtRisk, float64(spotVMs)*100/float64(totalVMs))
+		}
+	}
+	return risk
+}
+
+/* Compute a percentile of the forecasted requests, using the nearest rank
+	in:
+		@forecast []types.ForecastedValue
+		@percentile float64	- Between 0 and 100
+	out:
+		@float64
+*/
+func requestsPercentile(forecast []types.ForecastedValue, percentile float64) float64 {
+	if len(forecast) == 0 {
+		return 0
+	}
+	requests := make([]float64, len(forecast))
+	for i, v := range forecast {
+		requests[i] = v.Requests
+	}
+	sort.Float64s(requests)
+	rank := int(math.Ceil(percentile/100*float64(len(requests)))) - 1
+	rank = int(math.Max(0, math.Min(float64(rank), float64(len(requests)-1))))
+	return requests[rank]
+}
+
+//Load served with on-demand VMs when spot VMs are used
+func onDemandBaseRequests(forecast types.Forecast, sysConfiguration util.SystemConfiguration) float64 {
+	percentile := sysConfiguration.SpotInstances.OnDemandBasePercentile
+	if percentile <= 0 {
+		percentile = util.DEFAULT_ON_DEMAND_BASE_PERCENTILE
+	}
+	return requestsPercentile(forecast.ForecastedValues, percentile)
+}
@@ -0,0 +1,106 @@
+package derivation
+
+import (
+	"github.com/Cloud-Pie/SPDT/types"
+	"github.com/Cloud-Pie/SPDT/util"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMixSpotVMs(t *testing.T) {
+	start := time.Date(2018, 11, 1, 7, 0, 0, 0, time.UTC)
+	mapVMProfiles := map[string]types.VmProfile{
+		"t2.large": {Type: "t2.large", Pricing: types.Pricing{Price: 1},
+			Spot: types.SpotVariant{Discount: 0.7, InterruptionRate: 0.1}},
+		"t2.micro": {Type: "t2.micro", Pricing: types.Pricing{Price: 0.1}},
+	}
+	scalingActions := []types.ScalingAction{{TimeStart: start, TimeEnd: start.Add(2 * time.Hour),
+		DesiredState: types.State{VMs: types.VMScale{"t2.large": 4, "t2.micro": 2}},
+		Metrics:      types.ConfigMetrics{RequestsCapacity: 400}}}
+
+	//The base load needs half of the capacity on demand
+	mixed := MixSpotVMs(scalingActions, 200, nil, mapVMProfiles)
+	vms := mixed[0].DesiredState.VMs
+	expected := types.VMScale{"t2.large": 2, types.SpotType("t2.large"): 2, "t2.micro": 2}
+	if !reflect.DeepEqual(vms, expected) {
+		t.Error(
+			"For: ", "base load of half of the capacity",
+			"expected: ", expected,
+			"got: ", vms,
+		)
+	}
+	if scalingActions[0].DesiredState.VMs["t2.large"] != 4 {
+		t.Error(
+			"For: ", "original scaling actions",
+			"expected: ", 4,
+			"got: ", scalingActions[0].DesiredState.VMs["t2.large"],
+		)
+	}
+
+	//Reserved VMs stay on demand
+	reservations := []util.Reservation{{VMType: "t2.large", Count: 3}}
+	reservedMix := MixSpotVMs(scalingActions, 200, reservations, mapVMProfiles)
+	expected = types.VMScale{"t2.large": 3, types.SpotType("t2.large"): 1, "t2.micro": 2}
+	if vms := reservedMix[0].DesiredState.VMs; !reflect.DeepEqual(vms, expected) {
+		t.Error(
+			"For: ", reservations,
+			"expected: ", expected,
+			"got: ", vms,
+		)
+	}
+
+	spotPrice := vmProfileOf(mapVMProfiles, types.SpotType("t2.large")).Pricing.Price
+	if math.Abs(spotPrice-0.3) > 1e-9 {
+		t.Error(
+			"For: ", "spot price of t2.large",
+			"expected: ", 0.3,
+			"got: ", spotPrice,
+		)
+	}
+	risk := computeSpotRisk(mixed, 10, mapVMProfiles)
+	if math.Abs(risk.ExpectedInterruptions-0.4) > 1e-9 || math.Abs(risk.CapacityAtRisk-100.0/3) > 1e-9 {
+		t.Error(
+			"For: ", "spot risk",
+			"expected: ", 0.4, 100.0/3,
+			"got: ", risk.ExpectedInterruptions, risk.CapacityAtRisk,
+		)
+	}
+	//Each spot VM is expected to run 2 - (1 - e^-0.2)/0.1 hours on demand after an interruption
+	replacedHours := 2 - (1-math.Exp(-0.2))/0.1
+	if math.Abs(risk.ExpectedCost-(10+2*replacedHours*0.7)) > 1e-9 {
+		t.Error(
+			"For: ", "expected cost with replacements",
+			"expected: ", 10+2*replacedHours*0.7,
+			"got: ", risk.ExpectedCost,
+		)
+	}
+}
+
+func TestMixSpotVMsWithoutSpotVariant(t *testing.T) {
+	start := time.Date(2018, 11, 1, 7, 0, 0, 0, time.UTC)
+	mapVMProfiles := map[string]types.VmProfile{"t2.micro": {Type: "t2.micro", Pricing: types.Pricing{Price: 0.1}}}
+	scalingActions := []types.ScalingAction{{TimeStart: start, TimeEnd: start.Add(time.Hour),
+		DesiredState: types.State{VMs: types.VMScale{"t2.micro": 4}},
+		Metrics:      types.ConfigMetrics{RequestsCapacity: 400}}}
+
+	var tests = []struct {
+		baseRequests float64
+		expected     types.VMScale
+	}{
+		//VM types without spot variant stay on demand
+		{0, types.VMScale{"t2.micro": 4}},
+		{400, types.VMScale{"t2.micro": 4}},
+	}
+	for _, test := range tests {
+		vms := MixSpotVMs(scalingActions, test.baseRequests, nil, mapVMProfiles)[0].DesiredState.VMs
+		if !reflect.DeepEqual(vms, test.expected) {
+			t.Error(
+				"For: ", test.baseRequests,
+				"expected: ", test.expected,
+				"got: ", vms,
+			)
+		}
+	}
+}
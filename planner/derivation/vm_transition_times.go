@@ -0,0 +1,216 @@
+package derivation
+
+import (
+	"github.com/Cloud-Pie/SPDT/rest_clients/performance_profiles"
+	"github.com/Cloud-Pie/SPDT/storage"
+	"github.com/Cloud-Pie/SPDT/types"
+	"github.com/Cloud-Pie/SPDT/util"
+	"math"
+	"sort"
+)
+
+/* Booting and shutdown time of a number of VMs of the same type.
+	The stored values are used when there is one for the exact number of instances, otherwise they are estimated
+	from the values stored for other numbers of instances. The performance profiles component is requested only if
+	there are no stored values for the type
+	in:
+		@vmType string
+		@numInstances int
+		@sysConfiguration SystemConfiguration
+	out:
+		@types.BootShutDownTime	- Times in seconds
+*/
+func vmTransitionTimes(vmType string, numInstances int, sysConfiguration util.SystemConfiguration) types.BootShutDownTime {
+	//Spot VMs boot and shut down as their on-demand type
+	vmType = types.OnDemandType(vmType)
+	vmBootingProfileDAO := storage.GetVMBootingProfileDAO()
+	times, err := vmBootingProfileDAO.BootingShutdownTime(vmType, numInstances)
+	if err == nil {
+		return times
+	}
+	vmBootingProfile, err := vmBootingProfileDAO.FindByType(vmType)
+	if err == nil {
+		if estimation, ok := EstimateBootShutdownTime(vmBootingProfile.InstancesValues, numInstances); ok {
+			return estimation
+		}
+	}
+
+	url := sysConfiguration.PerformanceProfilesComponent.Endpoint + util.ENDPOINT_VM_TIMES
+	times, err = performance_profiles.GetBootShutDownProfileByType(url, vmType, numInstances, sysConfiguration.CSP, sysConfiguration.Region)
+	if err != nil {
+		log.Error("Error in booting and shutdown time query for type %s %d VMS. Details: %s", vmType, numInstances, err.Error())
+		log.Warning("Takes default booting and shutdown times")
+		return types.BootShutDownTime{NumInstances: numInstances, BootTime: util.DEFAULT_VM_BOOT_TIME,
+			ShutDownTime: util.DEFAULT_VM_SHUTDOWN_TIME}
+	}
+	vmBootingProfile.VMType = vmType
+	vmBootingProfile.InstancesValues = append(vmBootingProfile.InstancesValues, times)
+	vmBootingProfileDAO.UpdateByType(vmType, vmBootingProfile)
+	return times
+}
+
+/* Estimate the booting and shutdown time for a number of instances from the values known for other numbers.
+	Values between two known points are interpolated linearly and values outside the known range are
+	extrapolated with a linear regression, never going below the smallest known time
+	in:
+		@points []types.BootShutDownTime	- Known times
+		@numInstances int
+	out:
+		@types.BootShutDownTime
+		@bool	- False if there are no known points
+*/
+func EstimateBootShutdownTime(points []types.BootShutDownTime, numInstances int) (types.BootShutDownTime, bool) {
+	if len(points) == 0 {
+		return types.BootShutDownTime{}, false
+	}
+	sorted := make([]types.BootShutDownTime, len(points))
+	copy(sorted, points)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].NumInstances < sorted[j].NumInstances })
+
+	estimation := types.BootShutDownTime{NumInstances: numInstances}
+	bootTimes := func(p types.BootShutDownTime) float64 { return p.BootTime }
+	shutdownTimes := func(p types.BootShutDownTime) float64 { return p.ShutDownTime }
+	estimation.BootTime = estimateTime(sorted, numInstances, bootTimes)
+	estimation.ShutDownTime = estimateTime(sorted, numInstances, shutdownTimes)
+	return estimation, true
+}
+
+//Interpolate or extrapolate one of the times of the points, sorted by number of instances
+func estimateTime(sorted []types.BootShutDownTime, numInstances int, value func(types.BootShutDownTime) float64) float64 {
+	n := float64(numInstances)
+	minValue := math.Inf(1)
+	for _, p := range sorted {
+		minValue = math.Min(minValue, value(p))
+	}
+	for i, p := range sorted {
+		if p.NumInstances == numInstances {
+			return value(p)
+		}
+		if i > 0 && sorted[i-1].NumInstances < numInstances && numInstances < p.NumInstances {
+			x0, y0 := float64(sorted[i-1].NumInstances), value(sorted[i-1])
+			x1, y1 := float64(p.NumInstances), value(p)
+			return y0 + (y1-y0)*(n-x0)/(x1-x0)
+		}
+	}
+
+	//Least squares regression over all the points
+	var sumX, sumY, sumXY, sumXX float64
+	for _, p := range sorted {
+		x, y := float64(p.NumInstances), value(p)
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+	count := float64(len(sorted))
+	denominator := count*sumXX - sumX*sumX
+	if denominator == 0 {
+		return sumY / count
+	}
+	slope := (count*sumXY - sumX*sumY) / denominator
+	intercept := (sumY - slope*sumX) / count
+	return math.Max(intercept+slope*n, minValue)
+}
+
+/* Merge an observed booting or shutdown time into the known points.
+	The point with the same number of instances moves towards the observation by the learning rate,
+	if there is no point for that number of instances the observation is added
+	in:
+		@points []types.BootShutDownTime
+		@observed types.BootShutDownTime	- Times that are zero were not observed
+		@learningRate float64	- Weight of the observation, between 0 and 1
+	out:
+		@[]types.BootShutDownTime	- Updated points
+*/
+func UpdateBootShutdownPoints(points []types.BootShutDownTime, observed types.BootShutDownTime, learningRate float64) []types.BootShutDownTime {
+	updated := make([]types.BootShutDownTime, len(points))
+	copy(updated, points)
+	for i, p := range updated {
+		if p.NumInstances != observed.NumInstances {
+			continue
+		}
+		if observed.BootTime > 0 {
+			updated[i].BootTime = util.RoundN(p.BootTime+learningRate*(observed.BootTime-p.BootTime), 2)
+		}
+		if observed.ShutDownTime > 0 {
+			updated[i].ShutDownTime = util.RoundN(p.ShutDownTime+learningRate*(observed.ShutDownTime-p.ShutDownTime), 2)
+		}
+		return updated
+	}
+	//Times not observed are estimated from the other points
+	if estimation, ok := EstimateBootShutdownTime(points, observed.NumInstances); ok {
+		if observed.BootTime == 0 {
+			observed.BootTime = estimation.BootTime
+		}
+		if observed.ShutDownTime == 0 {
+			observed.ShutDownTime = estimation.ShutDownTime
+		}
+	}
+	return append(updated, observed)
+}
+
+/* Learn the booting or shutdown time of VMs from the actual transition of a finished scaling action.
+	Only actions that add or remove VMs of a single type are used. For a scale out, the difference between the
+	actual and the planned transition is attributed to the booting of the VMs
+	in:
+		@scalingAction types.ScalingAction	- Action with a finished execution
+		@sysConfiguration SystemConfiguration
+	out:
+		@bool	- True if the profile was updated
+*/
+func LearnTransitionTimes(scalingAction types.ScalingAction, sysConfiguration util.SystemConfiguration) bool {
+	execution := scalingAction.Execution
+	if execution.Status != types.EXECUTION_FINISHED || execution.TransitionDurationSec <= 0 {
+		return false
+	}
+	initialVMs := copyMap(scalingAction.InitialState.VMs)
+	desiredVMs := copyMap(scalingAction.DesiredState.VMs)
+	cleanKeys(initialVMs)
+	cleanKeys(desiredVMs)
+	vmAdded, vmRemoved := DeltaVMSet(initialVMs, desiredVMs)
+
+	var vmType string
+	observed := types.BootShutDownTime{}
+	switch {
+	case len(vmAdded) == 1 && len(vmRemoved) == 0:
+		for k, n := range vmAdded {
+			vmType = k
+			observed.NumInstances = n
+		}
+		plannedTransition := scalingAction.TimeStart.Sub(scalingAction.TimeStartTransition).Seconds()
+		estimatedBoot := vmTransitionTimes(vmType, observed.NumInstances, sysConfiguration).BootTime
+		observed.BootTime = estimatedBoot + execution.TransitionDurationSec - plannedTransition
+	case len(vmRemoved) == 1 && len(vmAdded) == 0:
+		for k, n := range vmRemoved {
+			vmType = k
+			observed.NumInstances = n
+		}
+		observed.ShutDownTime = execution.TransitionDurationSec
+	default:
+		return false
+	}
+	if observed.BootTime < 0 || observed.NumInstances <= 0 {
+		return false
+	}
+
+	learningRate := sysConfiguration.BootTimeLearningRate
+	if learningRate <= 0 || learningRate > 1 {
+		learningRate = util.DEFAULT_BOOT_TIME_LEARNING_RATE
+	}
+	vmBootingProfileDAO := storage.GetVMBootingProfileDAO()
+	vmBootingProfile, err := vmBootingProfileDAO.FindByType(vmType)
+	vmBootingProfile.VMType = vmType
+	vmBootingProfile.Source = types.PROFILE_SOURCE_LEARNED
+	vmBootingProfile.InstancesValues = UpdateBootShutdownPoints(vmBootingProfile.InstancesValues, observed, learningRate)
+	if err != nil {
+		err = vmBootingProfileDAO.Insert(vmBootingProfile)
+	} else {
+		err = vmBootingProfileDAO.UpdateByType(vmType, vmBootingProfile)
+	}
+	if err != nil {
+		log.Error("Booting profile of type %s could not be updated. Details: %s", vmType, err.Error())
+		return false
+	}
+	log.Info("Booting profile of type %s updated with the transition observed for %d VMs", vmType, observed.NumInstances)
+	return true
+}
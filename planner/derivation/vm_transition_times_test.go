@@ -0,0 +1,61 @@
+package derivation
+
+import (
+	"github.com/Cloud-Pie/SPDT/types"
+	"math"
+	"testing"
+)
+
+func TestEstimateBootShutdownTime(t *testing.T) {
+	points := []types.BootShutDownTime{
+		{NumInstances: 4, BootTime: 100, ShutDownTime: 40},
+		{NumInstances: 1, BootTime: 70, ShutDownTime: 30},
+		{NumInstances: 2, BootTime: 80, ShutDownTime: 30},
+	}
+	cases := []struct {
+		numInstances int
+		bootTime     float64
+		shutdownTime float64
+	}{
+		{2, 80, 30},  //known point
+		{3, 90, 35},  //interpolation
+		{6, 120, 46.43}, //regression
+		{0, 70, 30},  //regression, not below the smallest known time
+	}
+	for _, c := range cases {
+		estimation, ok := EstimateBootShutdownTime(points, c.numInstances)
+		if !ok || math.Abs(estimation.BootTime-c.bootTime) > 0.01 || math.Abs(estimation.ShutDownTime-c.shutdownTime) > 0.01 {
+			t.Error(
+				"For: ", c.numInstances, " instances",
+				"expected: ", c.bootTime, c.shutdownTime,
+				"got: ", estimation.BootTime, estimation.ShutDownTime,
+			)
+		}
+	}
+	if _, ok := EstimateBootShutdownTime(nil, 1); ok {
+		t.Error("Estimation without known points")
+	}
+}
+
+func TestUpdateBootShutdownPoints(t *testing.T) {
+	points := []types.BootShutDownTime{
+		{NumInstances: 1, BootTime: 70, ShutDownTime: 30},
+		{NumInstances: 3, BootTime: 90, ShutDownTime: 30},
+	}
+	updated := UpdateBootShutdownPoints(points, types.BootShutDownTime{NumInstances: 1, BootTime: 80}, 0.5)
+	if updated[0].BootTime != 75 || updated[0].ShutDownTime != 30 || points[0].BootTime != 70 {
+		t.Error(
+			"For: ", "known number of instances",
+			"expected: ", 75, 30,
+			"got: ", updated[0].BootTime, updated[0].ShutDownTime,
+		)
+	}
+	updated = UpdateBootShutdownPoints(points, types.BootShutDownTime{NumInstances: 2, ShutDownTime: 50}, 0.5)
+	if len(updated) != 3 || updated[2].BootTime != 80 || updated[2].ShutDownTime != 50 {
+		t.Error(
+			"For: ", "new number of instances",
+			"expected: ", 3, 80, 50,
+			"got: ", len(updated), updated[len(updated)-1].BootTime, updated[len(updated)-1].ShutDownTime,
+		)
+	}
+}
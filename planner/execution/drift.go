@@ -0,0 +1,97 @@
+package execution
+
+import (
+	"github.com/Cloud-Pie/SPDT/types"
+	"time"
+)
+
+/* Find the scaling action whose desired state is active at time t.
+	When two actions overlap, the one that started last is the active one
+	out:
+		@*types.ScalingAction	- nil if no action covers t
+*/
+func ActiveScalingAction(scalingActions []types.ScalingAction, t time.Time) *types.ScalingAction {
+	var active *types.ScalingAction
+	for i := range scalingActions {
+		a := &scalingActions[i]
+		if !a.TimeStart.After(t) && a.TimeEnd.After(t) {
+			if active == nil || a.TimeStart.After(active.TimeStart) {
+				active = a
+			}
+		}
+	}
+	return active
+}
+
+/* Compare the state expected by a scaling action with the actual state of the infrastructure
+	in:
+		@scalingAction types.ScalingAction	- Active scaling action
+		@actualState types.State	- State retrieved from the scheduler, with the spot VMs named as in the policies
+		@mainService string
+	out:
+		@types.DriftEvent	- Event without identifiers, Kinds is empty if there is no drift
+*/
+func CompareStates(scalingAction types.ScalingAction, actualState types.State, mainService string) types.DriftEvent {
+	expectedState := scalingAction.DesiredState
+	event := types.DriftEvent{
+		ExpectedState: expectedState,
+		ActualState:   actualState,
+		MissingVMs:    types.VMScale{},
+		ExtraVMs:      types.VMScale{},
+		Kinds:         []string{},
+	}
+	for vmType, n := range expectedState.VMs {
+		if actualState.VMs[vmType] < n {
+			event.MissingVMs[vmType] = n - actualState.VMs[vmType]
+		}
+	}
+	for vmType, n := range actualState.VMs {
+		if n > expectedState.VMs[vmType] {
+			event.ExtraVMs[vmType] = n - expectedState.VMs[vmType]
+		}
+	}
+	if len(event.MissingVMs) > 0 {
+		event.Kinds = append(event.Kinds, types.DRIFT_MISSING_VMS)
+	}
+	if len(event.ExtraVMs) > 0 {
+		event.Kinds = append(event.Kinds, types.DRIFT_EXTRA_VMS)
+	}
+
+	expectedService := expectedState.Services[mainService]
+	actualService := actualState.Services[mainService]
+	event.ExpectedReplicas = expectedService.Scale
+	event.ActualReplicas = actualService.Scale
+	if expectedService.Scale != actualService.Scale {
+		event.Kinds = append(event.Kinds, types.DRIFT_WRONG_REPLICAS)
+	}
+	if expectedService.CPU != actualService.CPU || expectedService.Memory != actualService.Memory {
+		event.Kinds = append(event.Kinds, types.DRIFT_WRONG_LIMITS)
+	}
+
+	//The infrastructure is still in the state previous to the scaling action
+	if len(event.Kinds) > 0 && !scalingAction.InitialState.Equal(expectedState) &&
+		sameInfrastructure(scalingAction.InitialState, actualState) {
+		event.Kinds = append(event.Kinds, types.DRIFT_STATE_NOT_APPLIED)
+	}
+	return event
+}
+
+//Compare the VMs and services of two states ignoring VM types with zero instances
+func sameInfrastructure(state1 types.State, state2 types.State) bool {
+	for _, vms := range []types.VMScale{state1.VMs, state2.VMs} {
+		for vmType := range vms {
+			if state1.VMs[vmType] != state2.VMs[vmType] {
+				return false
+			}
+		}
+	}
+	if len(state1.Services) != len(state2.Services) {
+		return false
+	}
+	for k, v := range state1.Services {
+		if !v.Equal(state2.Services[k]) {
+			return false
+		}
+	}
+	return true
+}
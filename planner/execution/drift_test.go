@@ -0,0 +1,59 @@
+package execution
+
+import (
+	"github.com/Cloud-Pie/SPDT/types"
+	"reflect"
+	"testing"
+)
+
+func driftState(vms types.VMScale, replicas int, cpu float64) types.State {
+	return types.State{
+		VMs:      vms,
+		Services: types.Service{"main": types.ServiceInfo{Scale: replicas, CPU: cpu, Memory: 1}},
+	}
+}
+
+func TestCompareStates(t *testing.T) {
+	initialState := driftState(types.VMScale{"t2.micro": 1}, 2, 0.5)
+	action := types.ScalingAction{
+		InitialState: initialState,
+		DesiredState: driftState(types.VMScale{"t2.micro": 2, "t2.large": 1}, 4, 0.5),
+	}
+
+	var tests = []struct {
+		name        string
+		actualState types.State
+		kinds       []string
+		missingVMs  types.VMScale
+		extraVMs    types.VMScale
+	}{
+		{"No drift", driftState(types.VMScale{"t2.micro": 2, "t2.large": 1}, 4, 0.5),
+			[]string{}, types.VMScale{}, types.VMScale{}},
+		{"Missing and extra VMs", driftState(types.VMScale{"t2.micro": 1, "t2.large": 1, "m4.large": 1}, 4, 0.5),
+			[]string{types.DRIFT_MISSING_VMS, types.DRIFT_EXTRA_VMS},
+			types.VMScale{"t2.micro": 1}, types.VMScale{"m4.large": 1}},
+		{"Wrong replicas and limits", driftState(types.VMScale{"t2.micro": 2, "t2.large": 1}, 3, 1),
+			[]string{types.DRIFT_WRONG_REPLICAS, types.DRIFT_WRONG_LIMITS}, types.VMScale{}, types.VMScale{}},
+		{"State not applied", initialState,
+			[]string{types.DRIFT_MISSING_VMS, types.DRIFT_WRONG_REPLICAS, types.DRIFT_STATE_NOT_APPLIED},
+			types.VMScale{"t2.micro": 1, "t2.large": 1}, types.VMScale{}},
+	}
+	for _, test := range tests {
+		event := CompareStates(action, test.actualState, "main")
+		if !reflect.DeepEqual(event.Kinds, test.kinds) || !reflect.DeepEqual(event.MissingVMs, test.missingVMs) ||
+			!reflect.DeepEqual(event.ExtraVMs, test.extraVMs) {
+			t.Error(
+				"For: ", test.name,
+				"expected: ", test.kinds, test.missingVMs, test.extraVMs,
+				"got: ", event.Kinds, event.MissingVMs, event.ExtraVMs,
+			)
+		}
+		if event.ExpectedReplicas != 4 || event.ActualReplicas != test.actualState.Services["main"].Scale {
+			t.Error(
+				"For: ", test.name,
+				"expected: ", 4, test.actualState.Services["main"].Scale,
+				"got: ", event.ExpectedReplicas, event.ActualReplicas,
+			)
+		}
+	}
+}
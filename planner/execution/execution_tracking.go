@@ -0,0 +1,99 @@
+package execution
+
+import (
+	"errors"
+	"github.com/Cloud-Pie/SPDT/types"
+	"github.com/Cloud-Pie/SPDT/util"
+)
+
+/* Find the scaling action of a policy that corresponds to a state reported by the scheduler
+	in:
+		@policy *types.Policy
+		@event types.ExecutionEvent
+	out:
+		@*types.ScalingAction	- nil if not found
+*/
+func FindScheduledAction(policy *types.Policy, event types.ExecutionEvent) *types.ScalingAction {
+	for i := range policy.ScalingActions {
+		a := &policy.ScalingActions[i]
+		if a.DesiredState.Hash == event.Name && a.TimeStart.Equal(event.ExpectedStart) {
+			return a
+		}
+	}
+	return nil
+}
+
+/* Record in a scaling action an event reported by the scheduler
+	in:
+		@scalingAction *types.ScalingAction
+		@event types.ExecutionEvent
+	out:
+		@error	- In case the event is unknown
+*/
+func ApplyExecutionEvent(scalingAction *types.ScalingAction, event types.ExecutionEvent) error {
+	execution := &scalingAction.Execution
+	switch event.Event {
+	case types.EXECUTION_STARTED:
+		execution.StartedAt = event.Timestamp
+	case types.EXECUTION_FINISHED, types.EXECUTION_FAILED:
+		execution.FinishedAt = event.Timestamp
+		execution.Error = event.Error
+	default:
+		return errors.New("Unknown event " + event.Event)
+	}
+	execution.Status = event.Event
+	if !execution.StartedAt.IsZero() && !execution.FinishedAt.IsZero() {
+		execution.TransitionDurationSec = execution.FinishedAt.Sub(execution.StartedAt).Seconds()
+	}
+	return nil
+}
+
+/* Compare the planned and the actual timing of the scaling actions of a policy
+	in:
+		@policy types.Policy
+	out:
+		@types.PolicyTiming
+*/
+func ComputePolicyTiming(policy types.Policy) types.PolicyTiming {
+	timing := types.PolicyTiming{PolicyID: policy.ID.Hex(), Actions: []types.ActionTiming{}}
+	totalPlanned := 0.0
+	totalActual := 0.0
+	totalDelay := 0.0
+	nMeasured := 0
+	for _, a := range policy.ScalingActions {
+		actionTiming := types.ActionTiming{
+			TimeStart:              a.TimeStart,
+			PlannedStartTransition: a.TimeStartTransition,
+			PlannedTransitionSec:   a.TimeStart.Sub(a.TimeStartTransition).Seconds(),
+			Status:                 a.Execution.Status,
+			ActualStartTransition:  a.Execution.StartedAt,
+			ActualFinish:           a.Execution.FinishedAt,
+			ActualTransitionSec:    a.Execution.TransitionDurationSec,
+		}
+		totalPlanned += actionTiming.PlannedTransitionSec
+		switch a.Execution.Status {
+		case types.EXECUTION_FINISHED:
+			timing.Finished++
+			actionTiming.DelaySec = a.Execution.FinishedAt.Sub(a.TimeStart).Seconds()
+			if nMeasured == 0 || actionTiming.DelaySec > timing.MaxDelaySec {
+				timing.MaxDelaySec = actionTiming.DelaySec
+			}
+			totalActual += actionTiming.ActualTransitionSec
+			totalDelay += actionTiming.DelaySec
+			nMeasured++
+		case types.EXECUTION_FAILED:
+			timing.Failed++
+		default:
+			timing.Pending++
+		}
+		timing.Actions = append(timing.Actions, actionTiming)
+	}
+	if n := len(policy.ScalingActions); n > 0 {
+		timing.AvgPlannedTransitionSec = util.RoundN(totalPlanned/float64(n), 2)
+	}
+	if nMeasured > 0 {
+		timing.AvgActualTransitionSec = util.RoundN(totalActual/float64(nMeasured), 2)
+		timing.AvgDelaySec = util.RoundN(totalDelay/float64(nMeasured), 2)
+	}
+	return timing
+}
@@ -0,0 +1,46 @@
+package execution
+
+import (
+	"github.com/Cloud-Pie/SPDT/types"
+	"testing"
+	"time"
+)
+
+func TestComputePolicyTiming(t *testing.T) {
+	t0 := time.Date(2018, 11, 1, 7, 0, 0, 0, time.UTC)
+	policy := types.Policy{ScalingActions: []types.ScalingAction{
+		{DesiredState: types.State{Hash: "a"}, TimeStartTransition: t0.Add(-2 * time.Minute), TimeStart: t0},
+		{DesiredState: types.State{Hash: "b"}, TimeStartTransition: t0.Add(58 * time.Minute), TimeStart: t0.Add(time.Hour)},
+		{DesiredState: types.State{Hash: "c"}, TimeStartTransition: t0.Add(118 * time.Minute), TimeStart: t0.Add(2 * time.Hour)},
+	}}
+	events := []types.ExecutionEvent{
+		{Name: "a", ExpectedStart: t0, Event: types.EXECUTION_STARTED, Timestamp: t0.Add(-2 * time.Minute)},
+		{Name: "a", ExpectedStart: t0, Event: types.EXECUTION_FINISHED, Timestamp: t0.Add(time.Minute)},
+		{Name: "b", ExpectedStart: t0.Add(time.Hour), Event: types.EXECUTION_FAILED, Timestamp: t0.Add(time.Hour)},
+	}
+	for _, e := range events {
+		action := FindScheduledAction(&policy, e)
+		if action == nil {
+			t.Fatal("No scaling action found for state ", e.Name)
+		}
+		if err := ApplyExecutionEvent(action, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	timing := ComputePolicyTiming(policy)
+	if timing.Finished != 1 || timing.Failed != 1 || timing.Pending != 1 {
+		t.Error(
+			"For: ", "outcomes",
+			"expected: ", 1, 1, 1,
+			"got: ", timing.Finished, timing.Failed, timing.Pending,
+		)
+	}
+	if timing.Actions[0].ActualTransitionSec != 180 || timing.AvgDelaySec != 60 || timing.AvgPlannedTransitionSec != 120 {
+		t.Error(
+			"For: ", "timing",
+			"expected: ", 180, 60, 120,
+			"got: ", timing.Actions[0].ActualTransitionSec, timing.AvgDelaySec, timing.AvgPlannedTransitionSec,
+		)
+	}
+}
@@ -7,7 +7,16 @@ import (
 	"strings"
 )
 
-func TriggerScheduler(policy types.Policy, endpoint string)([] scheduler.StateToSchedule,error) {
+/* Send the desired states of a policy to the scheduler
+	in:
+		@policy types.Policy
+		@endpoint string	- Scheduler endpoint to create states
+		@callbackURL string	- Endpoint where the scheduler reports the execution of each state
+	out:
+		@[]scheduler.StateToSchedule	- States sent
+		@error
+*/
+func TriggerScheduler(policy types.Policy, endpoint string, callbackURL string)([] scheduler.StateToSchedule,error) {
 	var statesToSchedule  []scheduler.StateToSchedule
 	for _, conf := range policy.ScalingActions {
 		mapServicesToSchedule := make(map[string]scheduler.ServiceToSchedule)
@@ -23,13 +32,15 @@ func TriggerScheduler(policy types.Policy, endpoint string)([] scheduler.StateTo
 				Memory:memory,
 			}
 		}
-		vms := addRemovedKeys(conf.InitialState.VMs, conf.DesiredState.VMs)
+		vms, spotVMs := splitSpotVMs(addRemovedKeys(conf.InitialState.VMs, conf.DesiredState.VMs))
 		stateToSchedule := scheduler.StateToSchedule{
 			LaunchTime:conf.TimeStartTransition,
 			Services:mapServicesToSchedule,
 			Name:state.Hash,
 			VMs:vms,
+			SpotVMs:spotVMs,
 			ExpectedStart:conf.TimeStart,
+			CallbackURL:callbackURL,
 		}
 		statesToSchedule = append(statesToSchedule, stateToSchedule)
 
@@ -72,7 +83,10 @@ func stringToCPUCores(value string) float64 {
 
 func RetrieveCurrentState(endpoint string ) (types.State, error) {
 	var policyState types.State
-	stateScheduled, _ := scheduler.InfraCurrentState(endpoint)
+	stateScheduled, err := scheduler.InfraCurrentState(endpoint)
+	if err != nil {
+		return policyState, err
+	}
 	mapServicesScheduled := stateScheduled.Services
 	policyServices := make(map[string]types.ServiceInfo)
 
@@ -88,7 +102,7 @@ func RetrieveCurrentState(endpoint string ) (types.State, error) {
 	}
 
 	policyState = types.State {
-		VMs:stateScheduled.VMs,
+		VMs:joinSpotVMs(stateScheduled.VMs, stateScheduled.SpotVMs),
 		Services:policyServices,
 	}
 	return policyState,nil
@@ -103,4 +117,42 @@ func addRemovedKeys(initialVMSet types.VMScale, desiredVMSet types.VMScale) type
 		}
 	}
 	return desiredVMSet
-}
\ No newline at end of file
+}
+/* Split a VM set for the scheduler. The policies name the spot VMs after their on-demand type with
+	types.SPOT_SUFFIX, the scheduler receives them apart with the name of the VM type
+	in:
+		@vms types.VMScale
+	out:
+		@types.VMScale	- On-demand VMs
+		@types.VMScale	- Spot VMs, nil if there are none
+*/
+func splitSpotVMs(vms types.VMScale) (types.VMScale, types.VMScale) {
+	var spotVMs types.VMScale
+	onDemandVMs := types.VMScale{}
+	for vmType, n := range vms {
+		if types.IsSpotType(vmType) {
+			if spotVMs == nil {
+				spotVMs = types.VMScale{}
+			}
+			spotVMs[types.OnDemandType(vmType)] = n
+		} else {
+			onDemandVMs[vmType] = n
+		}
+	}
+	return onDemandVMs, spotVMs
+}
+
+//Join the on-demand and spot VMs reported by the scheduler, naming the spot VMs as the policies do
+func joinSpotVMs(vms types.VMScale, spotVMs types.VMScale) types.VMScale {
+	if len(spotVMs) == 0 {
+		return vms
+	}
+	joined := types.VMScale{}
+	for vmType, n := range vms {
+		joined[vmType] = n
+	}
+	for vmType, n := range spotVMs {
+		joined[types.SpotType(vmType)] = n
+	}
+	return joined
+}
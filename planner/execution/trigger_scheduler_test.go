@@ -0,0 +1,67 @@
+package execution
+
+import (
+	"github.com/Cloud-Pie/SPDT/types"
+	"reflect"
+	"testing"
+)
+
+func TestSplitSpotVMs(t *testing.T) {
+	var tests = []struct {
+		vms         types.VMScale
+		onDemandVMs types.VMScale
+		spotVMs     types.VMScale
+	}{
+		{types.VMScale{"t2.large": 2}, types.VMScale{"t2.large": 2}, nil},
+		{types.VMScale{"t2.large": 2, types.SpotType("t2.large"): 3, types.SpotType("t2.micro"): 0},
+			types.VMScale{"t2.large": 2}, types.VMScale{"t2.large": 3, "t2.micro": 0}},
+	}
+	for _, test := range tests {
+		onDemandVMs, spotVMs := splitSpotVMs(test.vms)
+		if !reflect.DeepEqual(onDemandVMs, test.onDemandVMs) || !reflect.DeepEqual(spotVMs, test.spotVMs) {
+			t.Error(
+				"For: ", test.vms,
+				"expected: ", test.onDemandVMs, test.spotVMs,
+				"got: ", onDemandVMs, spotVMs,
+			)
+		}
+		//The state reported by the scheduler is named again as in the policies
+		joined := joinSpotVMs(onDemandVMs, spotVMs)
+		if !reflect.DeepEqual(joined, test.vms) {
+			t.Error(
+				"For: ", onDemandVMs, spotVMs,
+				"expected: ", test.vms,
+				"got: ", joined,
+			)
+		}
+	}
+}
+
+func TestCompareStatesWithSpotVMs(t *testing.T) {
+	desiredVMs := types.VMScale{"t2.large": 2, types.SpotType("t2.large"): 1}
+	action := types.ScalingAction{
+		InitialState: driftState(types.VMScale{"t2.large": 2}, 2, 0.5),
+		DesiredState: driftState(desiredVMs, 3, 0.5),
+	}
+	onDemandVMs, spotVMs := splitSpotVMs(desiredVMs)
+
+	var tests = []struct {
+		name       string
+		actualVMs  types.VMScale
+		missingVMs types.VMScale
+	}{
+		{"Spot VMs applied", joinSpotVMs(onDemandVMs, spotVMs), types.VMScale{}},
+		{"Spot VM interrupted", joinSpotVMs(onDemandVMs, types.VMScale{"t2.large": 0}),
+			types.VMScale{types.SpotType("t2.large"): 1}},
+	}
+	for _, test := range tests {
+		event := CompareStates(action, driftState(test.actualVMs, 3, 0.5), "main")
+		if !reflect.DeepEqual(event.MissingVMs, test.missingVMs) || len(event.ExtraVMs) > 0 {
+			t.Error(
+				"For: ", test.name,
+				"expected: ", test.missingVMs,
+				"got: ", event.MissingVMs, event.ExtraVMs,
+			)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package forecast_processing
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/Cloud-Pie/SPDT/types"
+	"math"
+	"time"
+)
+
+/* Check that a forecast can be used to derive a policy.
+	The time window should be defined and the values should be non-empty, non-negative,
+	sorted by timestamp without duplicates and within the time window
+	in:
+		@forecast types.Forecast
+	out:
+		@error	- Description of the first invalid field
+*/
+func ValidateForecast(forecast types.Forecast) error {
+	if forecast.TimeWindowStart.IsZero() || forecast.TimeWindowEnd.IsZero() {
+		return errors.New("start_time and end_time are required")
+	}
+	if !forecast.TimeWindowEnd.After(forecast.TimeWindowStart) {
+		return errors.New("end_time should be after start_time")
+	}
+	if len(forecast.ForecastedValues) == 0 {
+		return errors.New("values should not be empty")
+	}
+	var previous time.Time
+	for i, v := range forecast.ForecastedValues {
+		switch {
+		case v.TimeStamp.IsZero():
+			return fmt.Errorf("values[%d]: timestamp is required", i)
+		case math.IsNaN(v.Requests) || math.IsInf(v.Requests, 0):
+			return fmt.Errorf("values[%d]: requests should be a number", i)
+		case v.Requests < 0:
+			return fmt.Errorf("values[%d]: requests should not be negative", i)
+		case v.TimeStamp.Before(forecast.TimeWindowStart) || v.TimeStamp.After(forecast.TimeWindowEnd):
+			return fmt.Errorf("values[%d]: timestamp %s is out of the time window", i, v.TimeStamp.Format(time.RFC3339))
+		case i > 0 && v.TimeStamp.Equal(previous):
+			return fmt.Errorf("values[%d]: duplicated timestamp %s", i, v.TimeStamp.Format(time.RFC3339))
+		case i > 0 && v.TimeStamp.Before(previous):
+			return fmt.Errorf("values[%d]: timestamps should be sorted", i)
+		}
+		previous = v.TimeStamp
+	}
+	return nil
+}
+
+//Hash of the time window and values of a forecast, used to identify repeated notifications
+func ContentHash(forecast types.Forecast) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%d-%d", forecast.TimeWindowStart.UnixNano(), forecast.TimeWindowEnd.UnixNano())
+	for _, v := range forecast.ForecastedValues {
+		fmt.Fprintf(hash, ";%d:%g", v.TimeStamp.UnixNano(), v.Requests)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,45 @@
+package forecast_processing
+
+import (
+	"github.com/Cloud-Pie/SPDT/types"
+	"testing"
+	"time"
+)
+
+func TestValidateForecast(t *testing.T) {
+	start := time.Date(2018, 11, 1, 0, 0, 0, 0, time.UTC)
+	value := func(h int, requests float64) types.ForecastedValue {
+		return types.ForecastedValue{TimeStamp: start.Add(time.Duration(h) * time.Hour), Requests: requests}
+	}
+	forecast := func(values ...types.ForecastedValue) types.Forecast {
+		return types.Forecast{TimeWindowStart: start, TimeWindowEnd: start.Add(3 * time.Hour), ForecastedValues: values}
+	}
+
+	cases := []struct {
+		name     string
+		forecast types.Forecast
+		valid    bool
+	}{
+		{"valid", forecast(value(0, 10), value(1, 20), value(3, 0)), true},
+		{"empty", forecast(), false},
+		{"negative", forecast(value(0, 10), value(1, -1)), false},
+		{"unsorted", forecast(value(1, 10), value(0, 20)), false},
+		{"duplicated", forecast(value(0, 10), value(0, 20)), false},
+		{"out of window", forecast(value(0, 10), value(4, 20)), false},
+		{"missing window", types.Forecast{ForecastedValues: []types.ForecastedValue{value(0, 10)}}, false},
+	}
+	for _, c := range cases {
+		err := ValidateForecast(c.forecast)
+		if (err == nil) != c.valid {
+			t.Error(
+				"For: ", c.name,
+				"expected valid: ", c.valid,
+				"got: ", err,
+			)
+		}
+	}
+
+	if ContentHash(forecast(value(0, 10))) == ContentHash(forecast(value(0, 11))) {
+		t.Error("Forecasts with different values have the same content hash")
+	}
+}
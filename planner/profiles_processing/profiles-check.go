@@ -0,0 +1,275 @@
+package profiles_processing
+
+import (
+	"fmt"
+	"github.com/Cloud-Pie/SPDT/types"
+	"github.com/Cloud-Pie/SPDT/util"
+	"math"
+	"sort"
+)
+
+//Kinds of issues found in the performance profiles
+const (
+	ISSUE_ZERO_CAPACITY = "zero-capacity"
+	ISSUE_NON_MONOTONIC = "non-monotonic"
+	ISSUE_OUTLIER = "outlier"
+	ISSUE_MISSING_BOOT_TIME = "missing-boot-time"
+)
+
+//Ways to handle the issues found in the performance profiles
+const (
+	REPAIR_NONE = "none"
+	REPAIR_SMOOTH = "smooth"
+	REPAIR_EXCLUDE = "exclude"
+)
+
+//Actions applied to a setting with issues
+const (
+	ACTION_REPORTED = "reported"
+	ACTION_REPAIRED = "repaired"
+	ACTION_EXCLUDED = "excluded"
+)
+
+//Modified z-score and relative deviation from the median of the capacity per replica over which a setting is an outlier
+const OUTLIER_Z_SCORE = 3.5
+const OUTLIER_MIN_DEVIATION = 0.5
+
+//Issue found in a setting of a performance profile and the action applied
+type ProfileIssue struct {
+	Limit       types.Limit `json:"limits"`
+	Replicas    int         `json:"replicas"`
+	Kind        string      `json:"kind"`
+	Action      string      `json:"action"`
+	OldValue    float64     `json:"old_value"`
+	NewValue    float64     `json:"new_value"`
+	Description string      `json:"description"`
+}
+
+/* Check the settings of the performance profiles and repair the issues found.
+	Zero capacities and outliers of the capacity per replica are excluded unless the mode is REPAIR_NONE.
+	Capacities that decrease with more replicas are smoothed with an isotonic regression (REPAIR_SMOOTH)
+	or excluded (REPAIR_EXCLUDE). Missing boot times are filled with the median boot time of the profile (REPAIR_SMOOTH)
+	or excluded (REPAIR_EXCLUDE)
+	in:
+		@profiles []types.PerformanceProfile
+		@mode string	- REPAIR_NONE, REPAIR_SMOOTH or REPAIR_EXCLUDE
+	out:
+		@[]types.PerformanceProfile	- Repaired profiles, unchanged with REPAIR_NONE
+		@[]ProfileIssue	- Issues found with the action applied
+*/
+func CheckProfiles(profiles []types.PerformanceProfile, mode string) ([]types.PerformanceProfile, []ProfileIssue) {
+	issues := []ProfileIssue{}
+	repaired := []types.PerformanceProfile{}
+	for _, p := range profiles {
+		settings := make([]types.MSCSimpleSetting, len(p.MSCSettings))
+		copy(settings, p.MSCSettings)
+		sort.Slice(settings, func(i, j int) bool { return settings[i].Replicas < settings[j].Replicas })
+
+		var profileIssues []ProfileIssue
+		settings, profileIssues = checkZeroCapacity(p.Limit, settings, mode)
+		issues = append(issues, profileIssues...)
+		settings, profileIssues = checkOutliers(p.Limit, settings, mode)
+		issues = append(issues, profileIssues...)
+		settings, profileIssues = checkMonotonicity(p.Limit, settings, mode)
+		issues = append(issues, profileIssues...)
+		settings, profileIssues = checkBootTimes(p.Limit, settings, mode)
+		issues = append(issues, profileIssues...)
+
+		if mode != REPAIR_NONE {
+			p.MSCSettings = settings
+		}
+		repaired = append(repaired, p)
+	}
+	return repaired, issues
+}
+
+//Settings without capacity, usually stored after a failed request to the profiler
+func checkZeroCapacity(limit types.Limit, settings []types.MSCSimpleSetting, mode string) ([]types.MSCSimpleSetting, []ProfileIssue) {
+	issues := []ProfileIssue{}
+	kept := []types.MSCSimpleSetting{}
+	for _, s := range settings {
+		if s.MSCPerSecond > 0 {
+			kept = append(kept, s)
+			continue
+		}
+		issues = append(issues, ProfileIssue{Limit: limit, Replicas: s.Replicas, Kind: ISSUE_ZERO_CAPACITY,
+			Action: excludedAction(mode), OldValue: s.MSCPerSecond, Description: "capacity is not greater than 0"})
+		if mode == REPAIR_NONE {
+			kept = append(kept, s)
+		}
+	}
+	return kept, issues
+}
+
+/* Settings whose capacity per replica is far from the rest of the profile.
+	Uses the modified z-score, based on the median absolute deviation, which needs at least three settings.
+	The relative deviation avoids flagging small variations when the rest of the settings are very close
+*/
+func checkOutliers(limit types.Limit, settings []types.MSCSimpleSetting, mode string) ([]types.MSCSimpleSetting, []ProfileIssue) {
+	issues := []ProfileIssue{}
+	if len(settings) < 3 {
+		return settings, issues
+	}
+	perReplica := []float64{}
+	for _, s := range settings {
+		perReplica = append(perReplica, s.MSCPerSecond/float64(s.Replicas))
+	}
+	med := median(perReplica)
+	deviations := []float64{}
+	for _, v := range perReplica {
+		deviations = append(deviations, math.Abs(v-med))
+	}
+	mad := median(deviations)
+	if mad == 0 {
+		return settings, issues
+	}
+
+	kept := []types.MSCSimpleSetting{}
+	for i, s := range settings {
+		zScore := 0.6745 * math.Abs(perReplica[i]-med) / mad
+		if zScore <= OUTLIER_Z_SCORE || math.Abs(perReplica[i]-med) <= OUTLIER_MIN_DEVIATION*med {
+			kept = append(kept, s)
+			continue
+		}
+		issues = append(issues, ProfileIssue{Limit: limit, Replicas: s.Replicas, Kind: ISSUE_OUTLIER,
+			Action: excludedAction(mode), OldValue: s.MSCPerSecond,
+			Description: fmt.Sprintf("capacity per replica %.2f far from the median %.2f", perReplica[i], med)})
+		if mode == REPAIR_NONE {
+			kept = append(kept, s)
+		}
+	}
+	return kept, issues
+}
+
+//Settings with less capacity than a setting with fewer replicas
+func checkMonotonicity(limit types.Limit, settings []types.MSCSimpleSetting, mode string) ([]types.MSCSimpleSetting, []ProfileIssue) {
+	issues := []ProfileIssue{}
+	values := []float64{}
+	for _, s := range settings {
+		values = append(values, s.MSCPerSecond)
+	}
+	smoothed := IsotonicRegression(values)
+
+	kept := []types.MSCSimpleSetting{}
+	maxCapacity := 0.0
+	for i, s := range settings {
+		decreasing := s.MSCPerSecond < maxCapacity
+		maxCapacity = math.Max(maxCapacity, s.MSCPerSecond)
+		if !decreasing && smoothed[i] == s.MSCPerSecond {
+			kept = append(kept, s)
+			continue
+		}
+		issue := ProfileIssue{Limit: limit, Replicas: s.Replicas, Kind: ISSUE_NON_MONOTONIC, OldValue: s.MSCPerSecond,
+			Description: "capacity decreases with more replicas"}
+		switch mode {
+		case REPAIR_SMOOTH:
+			issue.Action = ACTION_REPAIRED
+			issue.NewValue = smoothed[i]
+			s.MSCPerSecond = smoothed[i]
+			kept = append(kept, s)
+		case REPAIR_EXCLUDE:
+			if !decreasing {
+				//The setting only changes with the smoothing, it is the one of the pair that is kept
+				kept = append(kept, s)
+				continue
+			}
+			issue.Action = ACTION_EXCLUDED
+		default:
+			issue.Action = ACTION_REPORTED
+			kept = append(kept, s)
+		}
+		issues = append(issues, issue)
+	}
+	return kept, issues
+}
+
+//Settings without pod boot time
+func checkBootTimes(limit types.Limit, settings []types.MSCSimpleSetting, mode string) ([]types.MSCSimpleSetting, []ProfileIssue) {
+	issues := []ProfileIssue{}
+	bootTimes := []float64{}
+	for _, s := range settings {
+		if s.BootTimeSec > 0 {
+			bootTimes = append(bootTimes, s.BootTimeSec)
+		}
+	}
+	defaultBootTime := float64(util.DEFAULT_POD_BOOT_TIME)
+	if len(bootTimes) > 0 {
+		defaultBootTime = median(bootTimes)
+	}
+
+	kept := []types.MSCSimpleSetting{}
+	for _, s := range settings {
+		if s.BootTimeSec > 0 {
+			kept = append(kept, s)
+			continue
+		}
+		issue := ProfileIssue{Limit: limit, Replicas: s.Replicas, Kind: ISSUE_MISSING_BOOT_TIME,
+			OldValue: s.BootTimeSec, Description: "pod boot time is missing"}
+		switch mode {
+		case REPAIR_SMOOTH:
+			issue.Action = ACTION_REPAIRED
+			issue.NewValue = defaultBootTime
+			s.BootTimeSec = defaultBootTime
+			kept = append(kept, s)
+		case REPAIR_EXCLUDE:
+			issue.Action = ACTION_EXCLUDED
+		default:
+			issue.Action = ACTION_REPORTED
+			kept = append(kept, s)
+		}
+		issues = append(issues, issue)
+	}
+	return kept, issues
+}
+
+/* Non-decreasing sequence closest to the values in least squares, computed with the pool adjacent violators algorithm
+	in:
+		@values []float64
+	out:
+		@[]float64
+*/
+func IsotonicRegression(values []float64) []float64 {
+	type block struct {
+		mean float64
+		size int
+	}
+	blocks := []block{}
+	for _, v := range values {
+		blocks = append(blocks, block{mean: v, size: 1})
+		for len(blocks) > 1 && blocks[len(blocks)-2].mean > blocks[len(blocks)-1].mean {
+			last := blocks[len(blocks)-1]
+			previous := blocks[len(blocks)-2]
+			size := last.size + previous.size
+			blocks = blocks[:len(blocks)-2]
+			blocks = append(blocks, block{
+				mean: (last.mean*float64(last.size) + previous.mean*float64(previous.size)) / float64(size),
+				size: size,
+			})
+		}
+	}
+	result := []float64{}
+	for _, b := range blocks {
+		for i := 0; i < b.size; i++ {
+			result = append(result, b.mean)
+		}
+	}
+	return result
+}
+
+func excludedAction(mode string) string {
+	if mode == REPAIR_NONE {
+		return ACTION_REPORTED
+	}
+	return ACTION_EXCLUDED
+}
+
+func median(values []float64) float64 {
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
@@ -0,0 +1,57 @@
+package profiles_processing
+
+import (
+	"github.com/Cloud-Pie/SPDT/types"
+	"testing"
+)
+
+func TestIsotonicRegression(t *testing.T) {
+	smoothed := IsotonicRegression([]float64{100, 200, 180, 220, 300})
+	expected := []float64{100, 190, 190, 220, 300}
+	for i := range expected {
+		if smoothed[i] != expected[i] {
+			t.Fatalf("Expected %v, got %v", expected, smoothed)
+		}
+	}
+}
+
+func TestCheckProfiles(t *testing.T) {
+	profiles := []types.PerformanceProfile{{Limit: types.Limit{CPUCores: 1, MemoryGB: 2}, MSCSettings: []types.MSCSimpleSetting{
+		{Replicas: 1, MSCPerSecond: 100, BootTimeSec: 10},
+		{Replicas: 2, MSCPerSecond: 200, BootTimeSec: 10},
+		{Replicas: 3, MSCPerSecond: 180, BootTimeSec: 12},
+		{Replicas: 4, MSCPerSecond: 0, BootTimeSec: 10},
+		{Replicas: 5, MSCPerSecond: 480},
+	}}}
+
+	_, issues := CheckProfiles(profiles, REPAIR_NONE)
+	kinds := map[string]int{}
+	for _, issue := range issues {
+		kinds[issue.Kind]++
+		if issue.Action != ACTION_REPORTED {
+			t.Errorf("Expected issues only reported, got %s", issue.Action)
+		}
+	}
+	if kinds[ISSUE_ZERO_CAPACITY] != 1 || kinds[ISSUE_MISSING_BOOT_TIME] != 1 || kinds[ISSUE_NON_MONOTONIC] == 0 {
+		t.Errorf("Unexpected issues %v", issues)
+	}
+
+	repaired, _ := CheckProfiles(profiles, REPAIR_SMOOTH)
+	settings := repaired[0].MSCSettings
+	if len(settings) != 4 {
+		t.Fatalf("Expected the setting without capacity excluded, got %v", settings)
+	}
+	for i := 1; i < len(settings); i++ {
+		if settings[i].MSCPerSecond < settings[i-1].MSCPerSecond {
+			t.Errorf("Expected non-decreasing capacities, got %v", settings)
+		}
+	}
+	if settings[3].BootTimeSec != 10 {
+		t.Errorf("Expected the median boot time 10, got %f", settings[3].BootTimeSec)
+	}
+
+	excluded, _ := CheckProfiles(profiles, REPAIR_EXCLUDE)
+	if len(excluded[0].MSCSettings) != 2 {
+		t.Errorf("Expected 2 settings kept, got %v", excluded[0].MSCSettings)
+	}
+}
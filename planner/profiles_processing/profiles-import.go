@@ -0,0 +1,302 @@
+package profiles_processing
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Cloud-Pie/SPDT/types"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//Formats of the files with performance profiles
+const (
+	FORMAT_CSV = "csv"
+	FORMAT_JSON = "json"
+	FORMAT_K6 = "k6"
+	FORMAT_VEGETA = "vegeta"
+)
+
+//Columns of the csv files with performance profiles
+const (
+	COLUMN_CPU_CORES = "cpu_cores"
+	COLUMN_MEM_GB = "mem_gb"
+	COLUMN_REPLICAS = "replicas"
+	COLUMN_MSC = "msc_per_second"
+	COLUMN_BOOT_TIME = "pod_boot_time_sec"
+	COLUMN_SD_BOOT_TIME = "sd_pod_boot_time_sec"
+)
+
+//Configuration of the service under test, used for the load tools outputs that do not include it
+type LoadTestSetting struct {
+	Limit       types.Limit
+	Replicas    int
+	BootTimeSec float64
+}
+
+//Summary exported by k6 with --summary-export
+type k6Summary struct {
+	Metrics map[string]map[string]float64 `json:"metrics"`
+}
+
+//Report of vegeta with -type=json
+type vegetaReport struct {
+	Requests   int     `json:"requests"`
+	Rate       float64 `json:"rate"`
+	Throughput float64 `json:"throughput"`
+	Success    float64 `json:"success"`
+}
+
+/* Read the performance profiles from a csv file with one row per limit and number of replicas.
+	The header is required and should include the columns cpu_cores, mem_gb, replicas and msc_per_second.
+	The columns pod_boot_time_sec and sd_pod_boot_time_sec are optional
+	in:
+		@reader io.Reader
+	out:
+		@[]types.PerformanceProfile	- Profiles grouped by limits
+		@error
+*/
+func ReadCSVProfiles(reader io.Reader) ([]types.PerformanceProfile, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.TrimLeadingSpace = true
+	rows, err := csvReader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) < 2 {
+		return nil, errors.New("csv file without profiles")
+	}
+
+	columns := map[string]int{}
+	for i, name := range rows[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{COLUMN_CPU_CORES, COLUMN_MEM_GB, COLUMN_REPLICAS, COLUMN_MSC} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("column %s is required", name)
+		}
+	}
+
+	profiles := []types.PerformanceProfile{}
+	for i, row := range rows[1:] {
+		values := map[string]float64{}
+		for name, column := range columns {
+			if column >= len(row) || strings.TrimSpace(row[column]) == "" {
+				continue
+			}
+			value, err := strconv.ParseFloat(strings.TrimSpace(row[column]), 64)
+			if err != nil {
+				return nil, fmt.Errorf("row %d: invalid value for %s", i+2, name)
+			}
+			values[name] = value
+		}
+		limit := types.Limit{CPUCores: values[COLUMN_CPU_CORES], MemoryGB: values[COLUMN_MEM_GB]}
+		setting := types.MSCSimpleSetting{
+			Replicas:            int(values[COLUMN_REPLICAS]),
+			MSCPerSecond:        values[COLUMN_MSC],
+			BootTimeSec:         values[COLUMN_BOOT_TIME],
+			StandDevBootTimeSec: values[COLUMN_SD_BOOT_TIME],
+		}
+		if err := validateSetting(limit, setting); err != nil {
+			return nil, fmt.Errorf("row %d: %s", i+2, err.Error())
+		}
+		profiles = append(profiles, types.PerformanceProfile{Limit: limit, MSCSettings: []types.MSCSimpleSetting{setting}})
+	}
+	return MergeProfiles(nil, profiles), nil
+}
+
+/* Read the performance profiles stored in a json file, as written by the export
+	in:
+		@reader io.Reader
+	out:
+		@[]types.PerformanceProfile
+		@error
+*/
+func ReadJSONProfiles(reader io.Reader) ([]types.PerformanceProfile, error) {
+	var profiles []types.PerformanceProfile
+	if err := json.NewDecoder(reader).Decode(&profiles); err != nil {
+		return nil, err
+	}
+	for _, p := range profiles {
+		for _, s := range p.MSCSettings {
+			if err := validateSetting(p.Limit, s); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return MergeProfiles(nil, profiles), nil
+}
+
+/* Read the result of a k6 load test exported with --summary-export.
+	The capacity is the rate of successful requests
+	in:
+		@reader io.Reader
+		@setting LoadTestSetting	- Limits and replicas of the service under test
+	out:
+		@types.PerformanceProfile
+		@error
+*/
+func ReadK6Summary(reader io.Reader, setting LoadTestSetting) (types.PerformanceProfile, error) {
+	var summary k6Summary
+	if err := json.NewDecoder(reader).Decode(&summary); err != nil {
+		return types.PerformanceProfile{}, err
+	}
+	requests, ok := summary.Metrics["http_reqs"]
+	if !ok {
+		return types.PerformanceProfile{}, errors.New("metric http_reqs not found")
+	}
+	failedRate := summary.Metrics["http_req_failed"]["value"]
+	return newProfile(setting, requests["rate"]*(1-failedRate))
+}
+
+/* Read the result of a vegeta load test reported with -type=json.
+	The capacity is the throughput of successful requests
+	in:
+		@reader io.Reader
+		@setting LoadTestSetting	- Limits and replicas of the service under test
+	out:
+		@types.PerformanceProfile
+		@error
+*/
+func ReadVegetaReport(reader io.Reader, setting LoadTestSetting) (types.PerformanceProfile, error) {
+	var report vegetaReport
+	if err := json.NewDecoder(reader).Decode(&report); err != nil {
+		return types.PerformanceProfile{}, err
+	}
+	if report.Requests == 0 {
+		return types.PerformanceProfile{}, errors.New("report without requests")
+	}
+	return newProfile(setting, report.Throughput)
+}
+
+/* Merge imported performance profiles into the stored ones.
+	Profiles with the same limits are joined and imported settings replace the stored settings with the same
+	number of replicas. If several imported settings have the same number of replicas, the highest capacity is kept
+	in:
+		@stored []types.PerformanceProfile
+		@imported []types.PerformanceProfile
+	out:
+		@[]types.PerformanceProfile	- Profiles sorted by limits, with settings sorted by replicas.
+										New limits have an empty id
+*/
+func MergeProfiles(stored []types.PerformanceProfile, imported []types.PerformanceProfile) []types.PerformanceProfile {
+	type limitKey struct{ cores, memory float64 }
+	merged := map[limitKey]*types.PerformanceProfile{}
+	settings := map[limitKey]map[int]types.MSCSimpleSetting{}
+	importedReplicas := map[limitKey]map[int]bool{}
+	keys := []limitKey{}
+
+	add := func(profiles []types.PerformanceProfile, replace bool) {
+		for _, p := range profiles {
+			key := limitKey{p.Limit.CPUCores, p.Limit.MemoryGB}
+			if _, ok := merged[key]; !ok {
+				profile := types.PerformanceProfile{Limit: p.Limit}
+				merged[key] = &profile
+				settings[key] = map[int]types.MSCSimpleSetting{}
+				keys = append(keys, key)
+			}
+			//Only the stored profiles keep their id, imported ids belong to other databases
+			if !replace && merged[key].ID == "" {
+				merged[key].ID = p.ID
+			}
+			if p.Source != "" && (replace || merged[key].Source == "") {
+				merged[key].Source = p.Source
+				merged[key].FetchedAt = p.FetchedAt
+			}
+			if replace && importedReplicas[key] == nil {
+				importedReplicas[key] = map[int]bool{}
+			}
+			for _, s := range p.MSCSettings {
+				current, exists := settings[key][s.Replicas]
+				firstImported := replace && !importedReplicas[key][s.Replicas]
+				if !exists || firstImported || s.MSCPerSecond > current.MSCPerSecond {
+					settings[key][s.Replicas] = s
+				}
+				if replace {
+					importedReplicas[key][s.Replicas] = true
+				}
+			}
+		}
+	}
+	add(stored, false)
+	add(imported, true)
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].cores != keys[j].cores {
+			return keys[i].cores < keys[j].cores
+		}
+		return keys[i].memory < keys[j].memory
+	})
+	profiles := []types.PerformanceProfile{}
+	for _, key := range keys {
+		profile := merged[key]
+		profile.MSCSettings = []types.MSCSimpleSetting{}
+		for _, s := range settings[key] {
+			profile.MSCSettings = append(profile.MSCSettings, s)
+		}
+		sort.Slice(profile.MSCSettings, func(i, j int) bool {
+			return profile.MSCSettings[i].Replicas < profile.MSCSettings[j].Replicas
+		})
+		profiles = append(profiles, *profile)
+	}
+	return profiles
+}
+
+/* Write the performance profiles in a csv file with the columns read by ReadCSVProfiles
+	in:
+		@writer io.Writer
+		@profiles []types.PerformanceProfile
+	out:
+		@error
+*/
+func WriteCSVProfiles(writer io.Writer, profiles []types.PerformanceProfile) error {
+	csvWriter := csv.NewWriter(writer)
+	csvWriter.Write([]string{COLUMN_CPU_CORES, COLUMN_MEM_GB, COLUMN_REPLICAS, COLUMN_MSC, COLUMN_BOOT_TIME, COLUMN_SD_BOOT_TIME})
+	for _, p := range profiles {
+		for _, s := range p.MSCSettings {
+			csvWriter.Write([]string{
+				strconv.FormatFloat(p.Limit.CPUCores, 'f', -1, 64),
+				strconv.FormatFloat(p.Limit.MemoryGB, 'f', -1, 64),
+				strconv.Itoa(s.Replicas),
+				strconv.FormatFloat(s.MSCPerSecond, 'f', -1, 64),
+				strconv.FormatFloat(s.BootTimeSec, 'f', -1, 64),
+				strconv.FormatFloat(s.StandDevBootTimeSec, 'f', -1, 64),
+			})
+		}
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+//Build the profile of a single load test
+func newProfile(setting LoadTestSetting, msc float64) (types.PerformanceProfile, error) {
+	mscSetting := types.MSCSimpleSetting{
+		Replicas:     setting.Replicas,
+		MSCPerSecond: msc,
+		BootTimeSec:  setting.BootTimeSec,
+	}
+	if err := validateSetting(setting.Limit, mscSetting); err != nil {
+		return types.PerformanceProfile{}, err
+	}
+	return types.PerformanceProfile{Limit: setting.Limit, MSCSettings: []types.MSCSimpleSetting{mscSetting}}, nil
+}
+
+//Check that a setting can be used for the derivation
+func validateSetting(limit types.Limit, setting types.MSCSimpleSetting) error {
+	if limit.CPUCores <= 0 || limit.MemoryGB <= 0 {
+		return errors.New("cpu cores and memory should be greater than 0")
+	}
+	if setting.Replicas <= 0 {
+		return errors.New("replicas should be greater than 0")
+	}
+	if setting.MSCPerSecond <= 0 {
+		return errors.New("msc per second should be greater than 0")
+	}
+	if setting.BootTimeSec < 0 || setting.StandDevBootTimeSec < 0 {
+		return errors.New("boot time should not be negative")
+	}
+	return nil
+}
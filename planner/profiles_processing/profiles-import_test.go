@@ -0,0 +1,62 @@
+package profiles_processing
+
+import (
+	"github.com/Cloud-Pie/SPDT/types"
+	"strings"
+	"testing"
+)
+
+func TestReadCSVProfiles(t *testing.T) {
+	data := `cpu_cores,mem_gb,replicas,msc_per_second,pod_boot_time_sec
+0.5,1,2,180,12
+0.5,1,1,95,10
+0.5,1,1,90,11
+1,2,1,200,
+`
+	profiles, err := ReadCSVProfiles(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	if len(profiles) != 2 {
+		t.Fatalf("Expected 2 profiles, got %d", len(profiles))
+	}
+	settings := profiles[0].MSCSettings
+	if len(settings) != 2 || settings[0].Replicas != 1 || settings[0].MSCPerSecond != 95 {
+		t.Errorf("Expected the highest capacity for 1 replica sorted first, got %v", settings)
+	}
+
+	if _, err := ReadCSVProfiles(strings.NewReader("cpu_cores,mem_gb,replicas\n1,2,1\n")); err == nil {
+		t.Errorf("Expected error for missing msc_per_second column")
+	}
+}
+
+func TestMergeProfiles(t *testing.T) {
+	limit := types.Limit{CPUCores: 1, MemoryGB: 2}
+	stored := []types.PerformanceProfile{{ID: "stored", Limit: limit, MSCSettings: []types.MSCSimpleSetting{
+		{Replicas: 1, MSCPerSecond: 300}, {Replicas: 2, MSCPerSecond: 400}}}}
+	imported := []types.PerformanceProfile{{Limit: limit, MSCSettings: []types.MSCSimpleSetting{
+		{Replicas: 1, MSCPerSecond: 200}}}}
+
+	merged := MergeProfiles(stored, imported)
+	if len(merged) != 1 || merged[0].ID != "stored" {
+		t.Fatalf("Expected the stored profile to be updated, got %v", merged)
+	}
+	if merged[0].MSCSettings[0].MSCPerSecond != 200 || merged[0].MSCSettings[1].MSCPerSecond != 400 {
+		t.Errorf("Expected the imported setting to replace the stored one, got %v", merged[0].MSCSettings)
+	}
+}
+
+func TestReadLoadToolsOutputs(t *testing.T) {
+	setting := LoadTestSetting{Limit: types.Limit{CPUCores: 1, MemoryGB: 2}, Replicas: 3}
+	k6 := `{"metrics": {"http_reqs": {"count": 6000, "rate": 100}, "http_req_failed": {"value": 0.1}}}`
+	profile, err := ReadK6Summary(strings.NewReader(k6), setting)
+	if err != nil || profile.MSCSettings[0].MSCPerSecond != 90 || profile.MSCSettings[0].Replicas != 3 {
+		t.Errorf("Expected 90 requests per second for 3 replicas, got %v %v", profile, err)
+	}
+
+	vegeta := `{"requests": 3000, "rate": 50, "throughput": 48.5, "success": 0.97}`
+	profile, err = ReadVegetaReport(strings.NewReader(vegeta), setting)
+	if err != nil || profile.MSCSettings[0].MSCPerSecond != 48.5 {
+		t.Errorf("Expected 48.5 requests per second, got %v %v", profile, err)
+	}
+}
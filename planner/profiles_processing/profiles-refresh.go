@@ -0,0 +1,179 @@
+package profiles_processing
+
+import (
+	"github.com/Cloud-Pie/SPDT/types"
+	"sort"
+	"time"
+)
+
+//Differences between the stored set of profiles and a new set
+type ProfilesComparison struct {
+	Added     int
+	Removed   int
+	Changed   int
+	Unchanged int
+}
+
+/* Check if a profile should be fetched again.
+	Profiles imported or learned locally are never stale, profiles without fetch time always are
+	in:
+		@source string
+		@fetchedAt time.Time
+		@maxAge time.Duration
+		@now time.Time
+	out:
+		@bool
+*/
+func IsStale(source string, fetchedAt time.Time, maxAge time.Duration, now time.Time) bool {
+	if source != "" && source != types.PROFILE_SOURCE_PROFILER {
+		return false
+	}
+	return fetchedAt.IsZero() || now.Sub(fetchedAt) > maxAge
+}
+
+/* Compare the stored performance profiles with a new set, matching them by limits
+	in:
+		@stored []types.PerformanceProfile
+		@fetched []types.PerformanceProfile
+	out:
+		@ProfilesComparison
+*/
+func CompareProfiles(stored []types.PerformanceProfile, fetched []types.PerformanceProfile) ProfilesComparison {
+	var comparison ProfilesComparison
+	storedByLimit := map[types.Limit]types.PerformanceProfile{}
+	for _, p := range stored {
+		storedByLimit[limitKey(p.Limit)] = p
+	}
+	for _, p := range fetched {
+		storedProfile, ok := storedByLimit[limitKey(p.Limit)]
+		if !ok {
+			comparison.Added++
+		} else if sameSettings(storedProfile.MSCSettings, p.MSCSettings) {
+			comparison.Unchanged++
+		} else {
+			comparison.Changed++
+		}
+		delete(storedByLimit, limitKey(p.Limit))
+	}
+	comparison.Removed = len(storedByLimit)
+	return comparison
+}
+
+/* Compare the stored booting profiles with a new set, matching them by vm type
+	in:
+		@stored []types.InstancesBootShutdownTime
+		@fetched []types.InstancesBootShutdownTime
+	out:
+		@ProfilesComparison
+*/
+func CompareBootingProfiles(stored []types.InstancesBootShutdownTime, fetched []types.InstancesBootShutdownTime) ProfilesComparison {
+	var comparison ProfilesComparison
+	storedByType := map[string]types.InstancesBootShutdownTime{}
+	for _, p := range stored {
+		storedByType[p.VMType] = p
+	}
+	for _, p := range fetched {
+		storedProfile, ok := storedByType[p.VMType]
+		if !ok {
+			comparison.Added++
+		} else if sameBootShutdownTimes(storedProfile.InstancesValues, p.InstancesValues) {
+			comparison.Unchanged++
+		} else {
+			comparison.Changed++
+		}
+		delete(storedByType, p.VMType)
+	}
+	comparison.Removed = len(storedByType)
+	return comparison
+}
+
+/* Join the fetched performance profiles with the stored profiles imported locally.
+	An imported profile is kept instead of the fetched profile with the same limits
+	in:
+		@stored []types.PerformanceProfile
+		@fetched []types.PerformanceProfile
+	out:
+		@[]types.PerformanceProfile
+*/
+func KeepLocalProfiles(stored []types.PerformanceProfile, fetched []types.PerformanceProfile) []types.PerformanceProfile {
+	local := map[types.Limit]types.PerformanceProfile{}
+	for _, p := range stored {
+		if p.Source != "" && p.Source != types.PROFILE_SOURCE_PROFILER {
+			local[limitKey(p.Limit)] = p
+		}
+	}
+	profiles := []types.PerformanceProfile{}
+	for _, p := range fetched {
+		if _, ok := local[limitKey(p.Limit)]; !ok {
+			profiles = append(profiles, p)
+		}
+	}
+	for _, p := range stored {
+		if _, ok := local[limitKey(p.Limit)]; ok {
+			profiles = append(profiles, p)
+		}
+	}
+	return profiles
+}
+
+/* Join the fetched booting profiles with the stored profiles imported or learned locally.
+	A local profile is kept instead of the fetched profile of the same vm type
+	in:
+		@stored []types.InstancesBootShutdownTime
+		@fetched []types.InstancesBootShutdownTime
+	out:
+		@[]types.InstancesBootShutdownTime
+*/
+func KeepLocalBootingProfiles(stored []types.InstancesBootShutdownTime, fetched []types.InstancesBootShutdownTime) []types.InstancesBootShutdownTime {
+	local := map[string]bool{}
+	profiles := []types.InstancesBootShutdownTime{}
+	for _, p := range stored {
+		if p.Source != "" && p.Source != types.PROFILE_SOURCE_PROFILER {
+			local[p.VMType] = true
+			profiles = append(profiles, p)
+		}
+	}
+	for _, p := range fetched {
+		if !local[p.VMType] {
+			profiles = append(profiles, p)
+		}
+	}
+	return profiles
+}
+
+//Limits used to match profiles, only cores and memory identify a profile
+func limitKey(limit types.Limit) types.Limit {
+	return types.Limit{CPUCores: limit.CPUCores, MemoryGB: limit.MemoryGB}
+}
+
+func sameSettings(a []types.MSCSimpleSetting, b []types.MSCSimpleSetting) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	sortedA := append([]types.MSCSimpleSetting{}, a...)
+	sortedB := append([]types.MSCSimpleSetting{}, b...)
+	sort.Slice(sortedA, func(i, j int) bool { return sortedA[i].Replicas < sortedA[j].Replicas })
+	sort.Slice(sortedB, func(i, j int) bool { return sortedB[i].Replicas < sortedB[j].Replicas })
+	for i := range sortedA {
+		if sortedA[i] != sortedB[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func sameBootShutdownTimes(a []types.BootShutDownTime, b []types.BootShutDownTime) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	sortedA := append([]types.BootShutDownTime{}, a...)
+	sortedB := append([]types.BootShutDownTime{}, b...)
+	sort.Slice(sortedA, func(i, j int) bool { return sortedA[i].NumInstances < sortedA[j].NumInstances })
+	sort.Slice(sortedB, func(i, j int) bool { return sortedB[i].NumInstances < sortedB[j].NumInstances })
+	for i := range sortedA {
+		if sortedA[i] != sortedB[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,42 @@
+package profiles_processing
+
+import (
+	"github.com/Cloud-Pie/SPDT/types"
+	"testing"
+	"time"
+)
+
+func TestRefreshKeepsLocalProfiles(t *testing.T) {
+	now := time.Now()
+	if !IsStale(types.PROFILE_SOURCE_PROFILER, now.Add(-48*time.Hour), 24*time.Hour, now) {
+		t.Errorf("Expected a profile fetched 48h ago to be stale")
+	}
+	if IsStale(types.PROFILE_SOURCE_IMPORT, time.Time{}, 24*time.Hour, now) {
+		t.Errorf("Expected imported profiles to never be stale")
+	}
+
+	stored := []types.PerformanceProfile{
+		{Limit: types.Limit{CPUCores: 1, MemoryGB: 2}, Source: types.PROFILE_SOURCE_IMPORT,
+			MSCSettings: []types.MSCSimpleSetting{{Replicas: 1, MSCPerSecond: 120}}},
+		{Limit: types.Limit{CPUCores: 2, MemoryGB: 4}, Source: types.PROFILE_SOURCE_PROFILER,
+			MSCSettings: []types.MSCSimpleSetting{{Replicas: 1, MSCPerSecond: 200}}},
+	}
+	fetched := []types.PerformanceProfile{
+		{Limit: types.Limit{CPUCores: 1, MemoryGB: 2}, MSCSettings: []types.MSCSimpleSetting{{Replicas: 1, MSCPerSecond: 100}}},
+		{Limit: types.Limit{CPUCores: 2, MemoryGB: 4}, MSCSettings: []types.MSCSimpleSetting{{Replicas: 1, MSCPerSecond: 210}}},
+		{Limit: types.Limit{CPUCores: 4, MemoryGB: 8}, MSCSettings: []types.MSCSimpleSetting{{Replicas: 1, MSCPerSecond: 400}}},
+	}
+	profiles := KeepLocalProfiles(stored, fetched)
+	if len(profiles) != 3 {
+		t.Fatalf("Expected 3 profiles, got %d", len(profiles))
+	}
+	for _, p := range profiles {
+		if p.Limit.CPUCores == 1 && p.MSCSettings[0].MSCPerSecond != 120 {
+			t.Errorf("Expected the imported profile to be kept, got %v", p)
+		}
+	}
+	comparison := CompareProfiles(stored, profiles)
+	if comparison.Added != 1 || comparison.Changed != 1 || comparison.Unchanged != 1 || comparison.Removed != 0 {
+		t.Errorf("Unexpected comparison %+v", comparison)
+	}
+}
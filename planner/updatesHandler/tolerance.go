@@ -0,0 +1,129 @@
+package updatesHandler
+
+import (
+	"fmt"
+	"github.com/Cloud-Pie/SPDT/monitoring"
+	"github.com/Cloud-Pie/SPDT/types"
+	"github.com/Cloud-Pie/SPDT/util"
+	"time"
+)
+
+//Result of validating a forecast update against the capacity of a policy
+type ValidationDecision struct {
+	Violated           bool
+	Direction          string    //monitoring.VIOLATION_OVER_CAPACITY or monitoring.VIOLATION_UNDER_CAPACITY
+	ScalingActionIndex int       //Scaling action where the violation starts, -1 if not violated
+	TimeStart          time.Time //Timestamp of the first value of the violation
+	Points             int       //Number of consecutive values out of the band
+	DurationSec        float64   //Time between the first and the last value out of the band
+	Reason             string
+}
+
+//Sequence of consecutive forecasted values out of the band in the same direction
+type violationRun struct {
+	direction   string
+	actionIndex int
+	timeStart   time.Time
+	timeEnd     time.Time
+	points      int
+}
+
+/* Validate the forecasted values against the capacity band of each scaling action.
+	A value above the capacity plus the scale-up margin, or below the capacity minus one replica and the scale-down margin,
+	is out of the band. The policy is only violated when enough consecutive values are out of the band for long enough
+	in:
+		@forecast types.Forecast
+		@policy types.Policy
+		@sysConfiguration util.SystemConfiguration
+	out:
+		@ValidationDecision
+*/
+func ValidateForecast(forecast types.Forecast, policy types.Policy, sysConfiguration util.SystemConfiguration) ValidationDecision {
+	predictions := forecast.ForecastedValues
+	mainService := sysConfiguration.MainServiceName
+	tolerance := sysConfiguration.ValidationTolerance
+	rules := map[string]util.ToleranceRule{
+		monitoring.VIOLATION_OVER_CAPACITY:  tolerance.ScaleUp,
+		monitoring.VIOLATION_UNDER_CAPACITY: tolerance.ScaleDown,
+	}
+
+	var run *violationRun
+	var longestRun violationRun
+	index := 0
+	nPredictedValues := len(predictions)
+	for i, c := range policy.ScalingActions {
+		upperBoundCapacity := c.Metrics.RequestsCapacity
+		lowerBoundCapacity := upperBoundCapacity - (upperBoundCapacity / float64(c.DesiredState.Services[mainService].Scale))
+		upperBoundCapacity += upperBoundCapacity * tolerance.ScaleUp.MarginPercentage / 100.0
+		lowerBoundCapacity -= lowerBoundCapacity * tolerance.ScaleDown.MarginPercentage / 100.0
+
+		for index < nPredictedValues && c.TimeEnd.After(predictions[index].TimeStamp) {
+			value := predictions[index]
+			index = index + 1
+			direction := ""
+			if value.Requests > upperBoundCapacity {
+				direction = monitoring.VIOLATION_OVER_CAPACITY
+			} else if value.Requests < lowerBoundCapacity {
+				direction = monitoring.VIOLATION_UNDER_CAPACITY
+			}
+			if direction == "" {
+				run = nil
+				continue
+			}
+			if run == nil || run.direction != direction {
+				run = &violationRun{direction: direction, actionIndex: i, timeStart: value.TimeStamp}
+			}
+			run.points++
+			run.timeEnd = value.TimeStamp
+			if run.points > longestRun.points {
+				longestRun = *run
+			}
+			if isToleranceExceeded(rules[direction], *run) {
+				monitoring.ThresholdViolation(mainService, direction)
+				return ValidationDecision{
+					Violated:           true,
+					Direction:          direction,
+					ScalingActionIndex: run.actionIndex,
+					TimeStart:          run.timeStart,
+					Points:             run.points,
+					DurationSec:        run.duration(),
+					Reason: fmt.Sprintf("%d consecutive values %s from %s during %.0fs",
+						run.points, direction, run.timeStart.Format(time.RFC3339), run.duration()),
+				}
+			}
+		}
+	}
+
+	decision := ValidationDecision{ScalingActionIndex: -1, Reason: "All forecasted values are within the tolerance bands"}
+	if longestRun.points > 0 {
+		rule := rules[longestRun.direction]
+		decision.Reason = fmt.Sprintf("Values %s from %s tolerated: %d consecutive values during %.0fs, required %d values during %ds",
+			longestRun.direction, longestRun.timeStart.Format(time.RFC3339), longestRun.points, longestRun.duration(),
+			minConsecutivePoints(rule), minDurationSec(rule))
+	}
+	return decision
+}
+
+//Time in seconds between the first and the last value of the run
+func (r violationRun) duration() float64 {
+	return r.timeEnd.Sub(r.timeStart).Seconds()
+}
+
+//Check if a run of values out of the band is long enough to violate the policy
+func isToleranceExceeded(rule util.ToleranceRule, run violationRun) bool {
+	return run.points >= minConsecutivePoints(rule) && run.duration() >= float64(minDurationSec(rule))
+}
+
+func minConsecutivePoints(rule util.ToleranceRule) int {
+	if rule.MinConsecutivePoints < 1 {
+		return 1
+	}
+	return rule.MinConsecutivePoints
+}
+
+func minDurationSec(rule util.ToleranceRule) int64 {
+	if rule.MinDuration == "" {
+		return 0
+	}
+	return util.ParseIntervalToSeconds(rule.MinDuration)
+}
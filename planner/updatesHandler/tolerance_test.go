@@ -0,0 +1,65 @@
+package updatesHandler
+
+import (
+	"github.com/Cloud-Pie/SPDT/types"
+	"github.com/Cloud-Pie/SPDT/util"
+	"testing"
+	"time"
+)
+
+func tolerancePolicy(start time.Time) types.Policy {
+	state := types.State{Services: types.Service{"app": types.ServiceInfo{Scale: 4}}}
+	return types.Policy{ScalingActions: []types.ScalingAction{
+		{TimeStart: start, TimeEnd: start.Add(3 * time.Hour), DesiredState: state,
+			Metrics: types.ConfigMetrics{RequestsCapacity: 100}},
+		{TimeStart: start.Add(3 * time.Hour), TimeEnd: start.Add(6 * time.Hour), DesiredState: state,
+			Metrics: types.ConfigMetrics{RequestsCapacity: 100}},
+	}}
+}
+
+func toleranceForecast(start time.Time, values ...float64) types.Forecast {
+	forecast := types.Forecast{}
+	for i, v := range values {
+		forecast.ForecastedValues = append(forecast.ForecastedValues,
+			types.ForecastedValue{TimeStamp: start.Add(time.Duration(i) * time.Hour), Requests: v})
+	}
+	return forecast
+}
+
+func TestValidateForecast(t *testing.T) {
+	start := time.Date(2018, 11, 1, 0, 0, 0, 0, time.UTC)
+	policy := tolerancePolicy(start)
+
+	cases := []struct {
+		name      string
+		tolerance util.ValidationTolerance
+		values    []float64
+		violated  bool
+		index     int
+		timeStart time.Time
+	}{
+		{"single spike without tolerance", util.ValidationTolerance{},
+			[]float64{90, 110, 90, 90, 90, 90}, true, 0, start.Add(time.Hour)},
+		{"spike within margin", util.ValidationTolerance{ScaleUp: util.ToleranceRule{MarginPercentage: 20}},
+			[]float64{90, 110, 90, 90, 90, 90}, false, -1, time.Time{}},
+		{"single spike with consecutive points", util.ValidationTolerance{ScaleUp: util.ToleranceRule{MinConsecutivePoints: 2}},
+			[]float64{90, 110, 90, 90, 90, 90}, false, -1, time.Time{}},
+		{"run across scaling actions", util.ValidationTolerance{ScaleUp: util.ToleranceRule{MinConsecutivePoints: 2, MinDuration: "1h"}},
+			[]float64{90, 90, 110, 120, 90, 90}, true, 0, start.Add(2 * time.Hour)},
+		{"short scale down run", util.ValidationTolerance{ScaleDown: util.ToleranceRule{MinDuration: "2h"}},
+			[]float64{90, 90, 90, 10, 10, 90}, false, -1, time.Time{}},
+		{"long scale down run", util.ValidationTolerance{ScaleDown: util.ToleranceRule{MinDuration: "2h"}},
+			[]float64{90, 90, 90, 10, 10, 10}, true, 1, start.Add(3 * time.Hour)},
+	}
+	for _, c := range cases {
+		sysConfiguration := util.SystemConfiguration{MainServiceName: "app", ValidationTolerance: c.tolerance}
+		decision := ValidateForecast(toleranceForecast(start, c.values...), policy, sysConfiguration)
+		if decision.Violated != c.violated || decision.ScalingActionIndex != c.index || !decision.TimeStart.Equal(c.timeStart) {
+			t.Error(
+				"For: ", c.name,
+				"expected: ", c.violated, c.index, c.timeStart,
+				"got: ", decision.Violated, decision.ScalingActionIndex, decision.TimeStart,
+			)
+		}
+	}
+}
@@ -27,6 +27,11 @@ func InvalidateOldPolicies(systemConfiguration util.SystemConfiguration, timeSta
 				invalidated = false
 				log.Fatalf("Error, policies could not be removed from db: %s",  err.Error())
 			}
+			//The spend of a removed policy is no longer scheduled
+			err = storage.GetBudgetLedgerDAO(systemConfiguration.MainServiceName).RemovePolicy(p.ID.Hex())
+			if err != nil {
+				log.Error("The budget ledger of policy %s could not be removed. Error %s\n", p.ID.Hex(), err)
+			}
 		}
 		invalidated = true
 	} else {
@@ -51,22 +56,25 @@ func InvalidateScalingStates(sysConfiguration util.SystemConfiguration, timeInva
 
 
 func ValidateMSCThresholds(forecast types.Forecast,  policy types.Policy, sysConfiguration util.SystemConfiguration) bool{
-	shouldScale := false
-	predictions := forecast.ForecastedValues
-	mainService := sysConfiguration.MainServiceName
-	index := 0
-	nPredictedValues := len(predictions)
-	for _,c := range policy.ScalingActions {
-		upperBoundCapacity := c.Metrics.RequestsCapacity
-		lowerBoundCapacity := upperBoundCapacity - (upperBoundCapacity / float64(c.DesiredState.Services[mainService].Scale))
+	violatedIndex,_ := FirstThresholdViolation(forecast, policy, sysConfiguration)
+	return violatedIndex >= 0
+}
 
-		for  index < nPredictedValues && c.TimeEnd.After(predictions[index].TimeStamp) {
-			shouldScale = predictions[index].Requests > upperBoundCapacity || predictions[index].Requests < lowerBoundCapacity
-			index=index+1
-			if shouldScale {
-				return shouldScale
-			}
-		}
+/* Find the first scaling action whose capacity band does not cover the forecasted load
+	in:
+		@forecast types.Forecast
+		@policy types.Policy
+		@sysConfiguration util.SystemConfiguration
+	out:
+		@int	- Index of the first violated scaling action, -1 if the policy is still valid
+		@time.Time	- Timestamp of the first forecasted value outside the band
+*/
+func FirstThresholdViolation(forecast types.Forecast,  policy types.Policy, sysConfiguration util.SystemConfiguration) (int, time.Time) {
+	decision := ValidateForecast(forecast, policy, sysConfiguration)
+	if decision.Violated {
+		log.Info("Policy %s violated. %s", policy.ID.Hex(), decision.Reason)
+	} else {
+		log.Info("Policy %s still valid. %s", policy.ID.Hex(), decision.Reason)
 	}
-	return  shouldScale
-}
\ No newline at end of file
+	return decision.ScalingActionIndex, decision.TimeStart
+}
@@ -1,6 +1,7 @@
 package forecast
 
 import (
+	"github.com/Cloud-Pie/SPDT/monitoring"
 	"github.com/Cloud-Pie/SPDT/types"
 	"net/http"
 	"io/ioutil"
@@ -12,6 +13,8 @@ import (
 	"errors"
 )
 
+var httpClient = monitoring.NewInstrumentedClient(monitoring.COMPONENT_FORECAST)
+
 type RequestSubscription struct {
 	IDPrediction      string                `json:"predictions_id"`
 	URL				  string				`json:"url"`
@@ -29,10 +32,9 @@ func GetForecast(endpoint string, startTime time.Time, endTime time.Time) (types
 		return forecast,err
 	}
 	req.URL.RawQuery = q.Encode()
-	client := &http.Client{}
 	req.Header.Set("Content-Type", "application/json; charset=utf-8")
 	req.Header.Add("Accept", "application/json")
-	resp, err := client.Do(req)
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		return forecast,err
 	}
@@ -55,13 +57,13 @@ func GetForecast(endpoint string, startTime time.Time, endTime time.Time) (types
 
 func PostMaxRequestCapacities(loadCapacitiesPerState types.RequestCapacitySupply, endpoint string) error {
 	jsonValue, _ := json.Marshal(loadCapacitiesPerState)
-	_, err := http.Post(endpoint, "application/json", bytes.NewBuffer(jsonValue))
+	_, err := httpClient.Post(endpoint, "application/json", bytes.NewBuffer(jsonValue))
 	return err
 }
 
 func SubscribeNotifications(urlNotification string, idPrediction string, endpoint string) error {
 	requestBody := RequestSubscription{IDPrediction: idPrediction, URL:urlNotification}
 	jsonValue, _ := json.Marshal(requestBody)
-	_, err := http.Post(endpoint, "application/json", bytes.NewBuffer(jsonValue))
+	_, err := httpClient.Post(endpoint, "application/json", bytes.NewBuffer(jsonValue))
 	return err
 }
\ No newline at end of file
@@ -1,6 +1,7 @@
 package performance_profiles
 
 import (
+	"github.com/Cloud-Pie/SPDT/monitoring"
 	"net/http"
 	"encoding/json"
 	"io/ioutil"
@@ -10,10 +11,12 @@ import (
 	"strconv"
 )
 
+var httpClient = monitoring.NewInstrumentedClient(monitoring.COMPONENT_PERFORMANCE_PROFILES)
+
 func GetPerformanceProfiles(endpoint string) (types.ServiceProfile, error){
 
 	performanceProfile := types.ServiceProfile{}
-	response, err := http.Get(endpoint)
+	response, err := httpClient.Get(endpoint)
 	if err != nil {
 		return performanceProfile,err
 	}
@@ -39,7 +42,7 @@ func GetServicePerformanceProfiles(endpoint string, appName string, appType stri
 	parameters["mainservicename"] = mainServiceName
 	endpoint = util.ParseURL(endpoint, parameters)
 
-	response, err := http.Get(endpoint)
+	response, err := httpClient.Get(endpoint)
 	if err != nil {
 		return servicePerformanceProfile,err
 	}
@@ -69,7 +72,7 @@ func GetPredictedReplicas(endpoint string, appName string, appType string, mainS
 
 	endpoint = util.ParseURL(endpoint, parameters)
 
-	response, err := http.Get(endpoint)
+	response, err := httpClient.Get(endpoint)
 	if err != nil {
 		return mscSetting,err
 	}
@@ -98,7 +101,7 @@ func GetPredictedMSCByReplicas(endpoint string, appName string, appType string,
 
 	endpoint = util.ParseURL(endpoint, parameters)
 
-	response, err := http.Get(endpoint)
+	response, err := httpClient.Get(endpoint)
 	if err != nil {
 		return mscSetting,err
 	}
@@ -116,7 +119,7 @@ func GetPredictedMSCByReplicas(endpoint string, appName string, appType string,
 
 func GetVMsProfiles(endpoint string) ([]types.VmProfile, error){
 	vmList := []types.VmProfile{}
-	response, err := http.Get(endpoint)
+	response, err := httpClient.Get(endpoint)
 	if err != nil {
 		return vmList,err
 	}
@@ -147,8 +150,7 @@ func GetAllBootShutDownProfilesByType(endpoint string, vmType string, region str
 
 	req.URL.RawQuery = q.Encode()
 
-	client := &http.Client{}
-	response, err := client.Do(req)
+	response, err := httpClient.Do(req)
 	if err != nil {
 		return instanceValues,err
 	}
@@ -182,8 +184,7 @@ func GetBootShutDownProfileByType(endpoint string, vmType string, numberInstance
 
 	req.URL.RawQuery = q.Encode()
 
-	client := &http.Client{}
-	response, err := client.Do(req)
+	response, err := httpClient.Do(req)
 	if err != nil {
 		return instanceValues,err
 	}
@@ -1,21 +1,25 @@
 package scheduler
 
 import (
+	"github.com/Cloud-Pie/SPDT/monitoring"
 	"github.com/Cloud-Pie/SPDT/types"
 	"encoding/json"
-	"net/http"
 	"bytes"
 	"io/ioutil"
 	"time"
 	"github.com/Cloud-Pie/SPDT/util"
 )
 
+var httpClient = monitoring.NewInstrumentedClient(monitoring.COMPONENT_SCHEDULER)
+
 type StateToSchedule struct {
 	LaunchTime time.Time 						`json:"ISODate"`
 	Services   map[string]ServiceToSchedule     `json:"Services"`
 	Name       string    						`json:"Name"`
 	VMs        types.VMScale   					`json:"VMs"`
+	SpotVMs    types.VMScale   					`json:"SpotVMs,omitempty"`	//Spot VMs by name of the VM type
 	ExpectedStart time.Time 					`json:"ExpectedTime"`
+	CallbackURL string							`json:"CallbackURL,omitempty"`
 }
 
 type ServiceToSchedule struct {
@@ -32,7 +36,7 @@ type InfrastructureState struct {
 
 func CreateState(stateToSchedule StateToSchedule, endpoint string) error {
 	jsonValue, _ := json.Marshal(stateToSchedule)
-	_, err := http.Post(endpoint, "application/json", bytes.NewBuffer(jsonValue))
+	_, err := httpClient.Post(endpoint, "application/json", bytes.NewBuffer(jsonValue))
 	return err
 }
 
@@ -40,7 +44,7 @@ func CreateState(stateToSchedule StateToSchedule, endpoint string) error {
 func InfraCurrentState(endpoint string) (StateToSchedule, error) {
 	currentState := StateToSchedule{}
 	infrastructureState := InfrastructureState{}
-	response, err := http.Get(endpoint)
+	response, err := httpClient.Get(endpoint)
 	if err != nil {
 		return currentState, err
 	}
@@ -62,7 +66,7 @@ func InvalidateStates(timestamp time.Time,endpoint string) (error) {
 	parameters := make(map[string]string)
 	parameters["timestamp"] = timestamp.Format(util.UTC_TIME_LAYOUT)
 	endpoint = util.ParseURL(endpoint,parameters )
-	response, err := http.Get(endpoint)
+	response, err := httpClient.Get(endpoint)
 	if err != nil {
 		return  err
 	}
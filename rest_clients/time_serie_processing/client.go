@@ -1,13 +1,15 @@
 package forecast
 
 import (
+	"github.com/Cloud-Pie/SPDT/monitoring"
 	"github.com/Cloud-Pie/SPDT/types"
-	"net/http"
 	"io/ioutil"
 	"encoding/json"
 	"bytes"
 )
 
+var httpClient = monitoring.NewInstrumentedClient(monitoring.COMPONENT_TIME_SERIE)
+
 type Serie struct {
 	Serie	[]float64	 `json:"serie"`
 	Threshold int 	`json:"threshold"`
@@ -26,7 +28,7 @@ func ProcessData(values []float64, endpoint string) ([]types.PoI, error){
 	if err != nil {
 		return poiList,err
 	}
-	response, err := http.Post(endpoint, "application/json", bytes.NewBuffer(jsonValue))
+	response, err := httpClient.Post(endpoint, "application/json", bytes.NewBuffer(jsonValue))
 
 	responsePoI := ResponsePoI{}
 	if err != nil {
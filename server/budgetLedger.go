@@ -0,0 +1,53 @@
+package server
+
+import (
+	"github.com/Cloud-Pie/SPDT/planner/derivation"
+	db "github.com/Cloud-Pie/SPDT/storage"
+	"github.com/Cloud-Pie/SPDT/types"
+	"github.com/Cloud-Pie/SPDT/util"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+/* Record the spend of a scheduled policy in the budget ledger.
+	The entries replace the previous entries of the policy and of the time it covers
+	in:
+		@sysConfiguration util.SystemConfiguration
+		@policy types.Policy	- Policy with all its scheduled scaling actions
+*/
+func recordBudgetLedger(sysConfiguration util.SystemConfiguration, policy types.Policy) {
+	entries := derivation.LedgerEntries(policy, time.Now())
+	if len(entries) == 0 {
+		return
+	}
+	err := db.GetBudgetLedgerDAO(sysConfiguration.MainServiceName).ReplacePolicy(policy.ID.Hex(),
+		entries[0].TimeStart, entries[len(entries)-1].TimeEnd, entries)
+	if err != nil {
+		log.Error("The budget ledger of policy %s could not be updated. Error %s\n", policy.ID.Hex(), err)
+	}
+}
+
+// This handler shows the committed and forecast spend of a month, its burn rate and projected spend
+// The request responds to:  /api/:service/budget?month=YYYY-MM
+// Without month the current month is shown
+func getMonthlySpend(c *gin.Context) {
+	serviceName := c.Param("service")
+	now := time.Now()
+	month := c.DefaultQuery("month", now.UTC().Format(derivation.LEDGER_MONTH_FORMAT))
+	if _, err := time.Parse(derivation.LEDGER_MONTH_FORMAT, month); err != nil {
+		c.JSON(http.StatusBadRequest, "Invalid month parameter, use the format YYYY-MM")
+		return
+	}
+	entries, err := db.GetBudgetLedgerDAO(serviceName).FindByMonths(month, month)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+	spend, err := derivation.MonthlySpend(entries, month, sysConfiguration.PricingModel.Budget, now)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, spend)
+}
@@ -0,0 +1,237 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"github.com/Cloud-Pie/SPDT/types"
+	"github.com/Cloud-Pie/SPDT/util"
+	"gopkg.in/mgo.v2/bson"
+	"sort"
+	"sync"
+	"time"
+)
+
+//Status of a derivation job
+const (
+	JOB_PENDING   = "pending"
+	JOB_RUNNING   = "running"
+	JOB_COMPLETED = "completed"
+	JOB_FAILED    = "failed"
+	JOB_CANCELLED = "cancelled"
+)
+
+//Number of finished jobs kept in memory
+const maxFinishedJobs = 100
+
+//Settings of the system configuration that can be replaced for a single derivation
+type DerivationOverrides struct {
+	PreferredAlgorithm string  `json:"preferred_algorithm"`
+	MonthlyBudget      float64 `json:"monthly_budget"`
+	BillingUnit        string  `json:"billing_unit"`
+	ScalingMethod      string  `json:"vm_scaling_method"`
+	PreferredMetric    string  `json:"preferred_metric"`
+	Granularity        string  `json:"granularity"`
+}
+
+//Body of a request to start a derivation
+type DerivationRequest struct {
+	StartTime time.Time           `json:"start_time" binding:"required"`
+	EndTime   time.Time           `json:"end_time" binding:"required"`
+	Overrides DerivationOverrides `json:"overrides"`
+}
+
+//Derivation started through the REST API
+type DerivationJob struct {
+	ID                string              `json:"id"`
+	Service           string              `json:"service"`
+	Status            string              `json:"status"`
+	Stage             string              `json:"stage"`
+	Progress          float64             `json:"progress"`
+	TimeWindowStart   time.Time           `json:"window_time_start"`
+	TimeWindowEnd     time.Time           `json:"window_time_end"`
+	Overrides         DerivationOverrides `json:"overrides"`
+	CreatedAt         time.Time           `json:"created_at"`
+	StartedAt         *time.Time          `json:"started_at,omitempty"`
+	FinishedAt        *time.Time          `json:"finished_at,omitempty"`
+	Error             string              `json:"error,omitempty"`
+	CandidatePolicies []types.Policy      `json:"candidate_policies"`
+	SelectedPolicy    *types.Policy       `json:"selected_policy,omitempty"`
+	cancel            context.CancelFunc
+}
+
+//In-memory registry of the derivation jobs
+type jobRegistry struct {
+	sync.Mutex
+	jobs map[string]*DerivationJob
+}
+
+var derivationJobs = &jobRegistry{jobs: make(map[string]*DerivationJob)}
+
+//Check that the requested horizon is valid
+func (r DerivationRequest) validate() error {
+	if r.StartTime.IsZero() || r.EndTime.IsZero() {
+		return errors.New("start_time and end_time are required")
+	}
+	if !r.EndTime.After(r.StartTime) {
+		return errors.New("end_time should be after start_time")
+	}
+	return nil
+}
+
+/* Apply the overrides of a request to a copy of the system configuration
+	in:
+		@sysConfiguration util.SystemConfiguration
+		@service string
+	out:
+		@util.SystemConfiguration
+*/
+func (o DerivationOverrides) apply(sysConfiguration util.SystemConfiguration, service string) util.SystemConfiguration {
+	sysConfiguration.MainServiceName = service
+	if o.PreferredAlgorithm != "" {
+		sysConfiguration.PreferredAlgorithm = o.PreferredAlgorithm
+	}
+	if o.MonthlyBudget > 0 {
+		sysConfiguration.PricingModel.Budget = o.MonthlyBudget
+	}
+	if o.BillingUnit != "" {
+		sysConfiguration.PricingModel.BillingUnit = o.BillingUnit
+	}
+	if o.ScalingMethod != "" {
+		sysConfiguration.PolicySettings.ScalingMethod = o.ScalingMethod
+	}
+	if o.PreferredMetric != "" {
+		sysConfiguration.PolicySettings.PreferredMetric = o.PreferredMetric
+	}
+	if o.Granularity != "" {
+		sysConfiguration.ForecastComponent.Granularity = o.Granularity
+	}
+	return sysConfiguration
+}
+
+/* Register a new derivation job and start it in background
+	in:
+		@service string	- Service to scale
+		@request DerivationRequest
+		@sysConfiguration util.SystemConfiguration	- Base configuration
+	out:
+		@DerivationJob	- Snapshot of the created job
+*/
+func (r *jobRegistry) start(service string, request DerivationRequest, sysConfiguration util.SystemConfiguration) DerivationJob {
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &DerivationJob{
+		ID:                bson.NewObjectId().Hex(),
+		Service:           service,
+		Status:            JOB_PENDING,
+		TimeWindowStart:   request.StartTime,
+		TimeWindowEnd:     request.EndTime,
+		Overrides:         request.Overrides,
+		CreatedAt:         time.Now(),
+		CandidatePolicies: []types.Policy{},
+		cancel:            cancel,
+	}
+	r.Lock()
+	r.jobs[job.ID] = job
+	r.evictFinished()
+	snapshot := *job
+	r.Unlock()
+
+	jobConfiguration := request.Overrides.apply(sysConfiguration, service)
+	go r.run(ctx, job, jobConfiguration)
+	return snapshot
+}
+
+//Execute the derivation of a job and keep its results
+func (r *jobRegistry) run(ctx context.Context, job *DerivationJob, sysConfiguration util.SystemConfiguration) {
+	r.Lock()
+	if job.Status == JOB_CANCELLED {
+		r.Unlock()
+		return
+	}
+	startedAt := time.Now()
+	job.Status = JOB_RUNNING
+	job.StartedAt = &startedAt
+	r.Unlock()
+
+	report := func(stage string, progress float64) {
+		r.Lock()
+		job.Stage = stage
+		job.Progress = progress
+		r.Unlock()
+	}
+	selectedPolicy, candidatePolicies, err := runPolicyDerivation(ctx, job.TimeWindowStart, job.TimeWindowEnd,
+		sysConfiguration, report)
+
+	r.Lock()
+	defer r.Unlock()
+	finishedAt := time.Now()
+	job.FinishedAt = &finishedAt
+	if candidatePolicies != nil {
+		job.CandidatePolicies = candidatePolicies
+	}
+	if selectedPolicy.ID != "" {
+		job.SelectedPolicy = &selectedPolicy
+	}
+	switch {
+	case ctx.Err() != nil:
+		job.Status = JOB_CANCELLED
+	case err != nil:
+		job.Status = JOB_FAILED
+		job.Error = err.Error()
+		log.Error("Derivation job %s failed. Details: %s", job.ID, err)
+	default:
+		job.Status = JOB_COMPLETED
+	}
+}
+
+//Retrieve a snapshot of the job with the given id
+func (r *jobRegistry) get(id string) (DerivationJob, bool) {
+	r.Lock()
+	defer r.Unlock()
+	job, ok := r.jobs[id]
+	if !ok {
+		return DerivationJob{}, false
+	}
+	return *job, true
+}
+
+/* Cancel a pending or running job.
+	A running derivation stops at the next stage, it does not schedule any state
+	out:
+		@DerivationJob	- Snapshot of the job
+		@error	- In case the job is not found or already finished
+*/
+func (r *jobRegistry) cancelJob(id string) (DerivationJob, error) {
+	r.Lock()
+	defer r.Unlock()
+	job, ok := r.jobs[id]
+	if !ok {
+		return DerivationJob{}, errors.New("Derivation job not found")
+	}
+	if job.Status != JOB_PENDING && job.Status != JOB_RUNNING {
+		return *job, errors.New("Derivation job already " + job.Status)
+	}
+	job.cancel()
+	if job.Status == JOB_PENDING {
+		job.Status = JOB_CANCELLED
+	}
+	return *job, nil
+}
+
+//Remove the oldest finished jobs once the limit is reached. It must be called holding the lock
+func (r *jobRegistry) evictFinished() {
+	var finished []*DerivationJob
+	for _, job := range r.jobs {
+		if job.FinishedAt != nil || job.Status == JOB_CANCELLED {
+			finished = append(finished, job)
+		}
+	}
+	if len(finished) <= maxFinishedJobs {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].CreatedAt.Before(finished[j].CreatedAt)
+	})
+	for _, job := range finished[:len(finished)-maxFinishedJobs] {
+		delete(r.jobs, job.ID)
+	}
+}
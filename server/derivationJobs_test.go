@@ -0,0 +1,131 @@
+package server
+
+import (
+	"context"
+	"github.com/Cloud-Pie/SPDT/util"
+	"strconv"
+	"testing"
+	"time"
+)
+
+//Register a job with the given status without starting its derivation
+func addJob(r *jobRegistry, id string, status string, createdAt time.Time) (*DerivationJob, context.Context) {
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &DerivationJob{ID: id, Status: status, CreatedAt: createdAt, cancel: cancel}
+	if status == JOB_COMPLETED || status == JOB_FAILED {
+		job.FinishedAt = &createdAt
+	}
+	r.jobs[id] = job
+	return job, ctx
+}
+
+func TestCancelJob(t *testing.T) {
+	var tests = []struct {
+		status    string
+		expected  string
+		cancelled bool
+		fails     bool
+	}{
+		{JOB_PENDING, JOB_CANCELLED, true, false},
+		//A running job is cancelled once its derivation stops
+		{JOB_RUNNING, JOB_RUNNING, true, false},
+		{JOB_COMPLETED, JOB_COMPLETED, false, true},
+		{JOB_FAILED, JOB_FAILED, false, true},
+		{JOB_CANCELLED, JOB_CANCELLED, false, true},
+	}
+	for _, test := range tests {
+		registry := &jobRegistry{jobs: make(map[string]*DerivationJob)}
+		_, ctx := addJob(registry, "job", test.status, time.Now())
+		job, err := registry.cancelJob("job")
+		if job.Status != test.expected || (ctx.Err() != nil) != test.cancelled || (err != nil) != test.fails {
+			t.Error(
+				"For: ", test.status,
+				"expected: ", test.expected, test.cancelled, test.fails,
+				"got: ", job.Status, ctx.Err(), err,
+			)
+		}
+	}
+
+	registry := &jobRegistry{jobs: make(map[string]*DerivationJob)}
+	if _, err := registry.cancelJob("unknown"); err == nil {
+		t.Error(
+			"For: ", "unknown job",
+			"expected: ", "error",
+			"got: ", nil,
+		)
+	}
+}
+
+func TestRunCancelledJob(t *testing.T) {
+	registry := &jobRegistry{jobs: make(map[string]*DerivationJob)}
+	job, ctx := addJob(registry, "job", JOB_PENDING, time.Now())
+	registry.cancelJob(job.ID)
+
+	//A job cancelled before it runs never starts its derivation
+	registry.run(ctx, job, util.SystemConfiguration{})
+	snapshot, ok := registry.get(job.ID)
+	if !ok || snapshot.Status != JOB_CANCELLED || snapshot.StartedAt != nil {
+		t.Error(
+			"For: ", "job cancelled while pending",
+			"expected: ", JOB_CANCELLED,
+			"got: ", snapshot.Status, snapshot.StartedAt,
+		)
+	}
+}
+
+func TestEvictFinishedJobs(t *testing.T) {
+	registry := &jobRegistry{jobs: make(map[string]*DerivationJob)}
+	start := time.Date(2018, 11, 1, 0, 0, 0, 0, time.UTC)
+	nFinished := maxFinishedJobs + 5
+	for i := 0; i < nFinished; i++ {
+		status := JOB_COMPLETED
+		if i%2 == 0 {
+			status = JOB_CANCELLED
+		}
+		addJob(registry, "finished-"+strconv.Itoa(i), status, start.Add(time.Duration(i)*time.Minute))
+	}
+	addJob(registry, "running", JOB_RUNNING, start)
+	addJob(registry, "pending", JOB_PENDING, start)
+
+	registry.evictFinished()
+	if len(registry.jobs) != maxFinishedJobs+2 {
+		t.Error(
+			"For: ", nFinished, "finished jobs",
+			"expected: ", maxFinishedJobs+2,
+			"got: ", len(registry.jobs),
+		)
+	}
+	//The oldest finished jobs are removed, unfinished jobs are kept
+	for _, id := range []string{"finished-0", "finished-4"} {
+		if _, ok := registry.get(id); ok {
+			t.Error(
+				"For: ", id,
+				"expected: ", "evicted",
+				"got: ", "kept",
+			)
+		}
+	}
+	for _, id := range []string{"finished-5", "running", "pending"} {
+		if _, ok := registry.get(id); !ok {
+			t.Error(
+				"For: ", id,
+				"expected: ", "kept",
+				"got: ", "evicted",
+			)
+		}
+	}
+}
+
+func TestGetJobSnapshot(t *testing.T) {
+	registry := &jobRegistry{jobs: make(map[string]*DerivationJob)}
+	job, _ := addJob(registry, "job", JOB_RUNNING, time.Now())
+	snapshot, _ := registry.get("job")
+	snapshot.Status = JOB_FAILED
+	if job.Status != JOB_RUNNING {
+		t.Error(
+			"For: ", "snapshot modified",
+			"expected: ", JOB_RUNNING,
+			"got: ", job.Status,
+		)
+	}
+}
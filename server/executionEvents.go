@@ -0,0 +1,72 @@
+package server
+
+import (
+	"github.com/Cloud-Pie/SPDT/planner/derivation"
+	"github.com/Cloud-Pie/SPDT/planner/execution"
+	db "github.com/Cloud-Pie/SPDT/storage"
+	"github.com/Cloud-Pie/SPDT/types"
+	"github.com/gin-gonic/gin"
+	"gopkg.in/mgo.v2/bson"
+	"net/http"
+	"time"
+)
+
+// This handler receives the execution events of the states sent to the scheduler
+// The request body identifies the state by its name and expected start:
+// {"name": "<state hash>", "expected_time": "2018-11-01T07:00:00Z", "event": "finished", "timestamp": "2018-11-01T07:01:10Z"}
+func executionEvent(c *gin.Context) {
+	serviceName := c.Param("service")
+	event := types.ExecutionEvent{}
+	if err := c.ShouldBindJSON(&event); err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	policyDAO := db.GetPolicyDAO(serviceName)
+	policy, err := policyDAO.FindSelectedByScalingAction(event.Name, event.ExpectedStart)
+	if err != nil {
+		c.JSON(http.StatusNotFound, "No scaling action found for state "+event.Name)
+		return
+	}
+	scalingAction := execution.FindScheduledAction(&policy, event)
+	if scalingAction == nil {
+		c.JSON(http.StatusNotFound, "No scaling action found for state "+event.Name)
+		return
+	}
+	if err := execution.ApplyExecutionEvent(scalingAction, event); err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+	if event.Event == types.EXECUTION_FAILED {
+		log.Error("State %s of policy %s failed. Details: %s", event.Name, policy.ID.Hex(), event.Error)
+	}
+	//Only the matched action is written, so concurrent events of other actions are not overwritten
+	err = policyDAO.UpdateScalingActionExecution(policy.ID, event.Name, event.ExpectedStart,
+		scalingAction.Execution)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+	if event.Event == types.EXECUTION_FINISHED {
+		go derivation.LearnTransitionTimes(*scalingAction, sysConfiguration)
+	}
+	c.JSON(http.StatusOK, scalingAction.Execution)
+}
+
+// This handler compares the planned and the actual timing of the scaling actions of the policy :id
+func policyTiming(c *gin.Context) {
+	id := c.Param("id")
+	serviceName := c.Param("service")
+	if !bson.IsObjectIdHex(id) {
+		c.JSON(http.StatusBadRequest, "Invalid id "+id)
+		return
+	}
+	policy, err := db.GetPolicyDAO(serviceName).FindByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, "Policy "+id+" not found")
+		return
+	}
+	c.JSON(http.StatusOK, execution.ComputePolicyTiming(policy))
+}
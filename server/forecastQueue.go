@@ -0,0 +1,142 @@
+package server
+
+import (
+	"github.com/Cloud-Pie/SPDT/storage"
+	"github.com/Cloud-Pie/SPDT/types"
+	"github.com/Cloud-Pie/SPDT/util"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"sync"
+	"time"
+)
+
+//Time that an idle worker waits before looking again for pending updates
+const forecastQueuePollInterval = 10 * time.Second
+
+//Workers that process the pushed forecast updates stored in the queue
+type forecastUpdateQueue struct {
+	sync.Mutex
+	busy   map[bson.ObjectId]types.ForecastUpdate //Updates in process
+	notify chan struct{}
+}
+
+var forecastUpdates = &forecastUpdateQueue{
+	busy:   make(map[bson.ObjectId]types.ForecastUpdate),
+	notify: make(chan struct{}, 1),
+}
+
+/* Store a pushed forecast and wake up an idle worker
+	in:
+		@serviceName string
+		@forecast types.Forecast
+	out:
+		@types.ForecastUpdate	- Pending update, it can include previous updates for the same window
+		@error
+*/
+func (q *forecastUpdateQueue) enqueue(serviceName string, forecast types.Forecast) (types.ForecastUpdate, error) {
+	update, err := storage.GetForecastUpdateDAO().Enqueue(serviceName, forecast)
+	if err != nil {
+		return update, err
+	}
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+	return update, nil
+}
+
+/* Set back to pending a failed update and wake up an idle worker
+	in:
+		@id bson.ObjectId
+	out:
+		@types.ForecastUpdate
+		@error
+*/
+func (q *forecastUpdateQueue) requeue(id bson.ObjectId) (types.ForecastUpdate, error) {
+	update, err := storage.GetForecastUpdateDAO().Requeue(id)
+	if err != nil {
+		return update, err
+	}
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+	return update, nil
+}
+
+/* Start the workers. Updates that were in process when the service stopped are processed again
+	in:
+		@sysConfiguration util.SystemConfiguration	- Configuration with which the updates are processed
+*/
+func (q *forecastUpdateQueue) start(sysConfiguration util.SystemConfiguration) {
+	nWorkers := sysConfiguration.ForecastUpdateWorkers
+	if nWorkers < 1 {
+		nWorkers = 1
+	}
+	err := storage.GetForecastUpdateDAO().ResetProcessing()
+	if err != nil {
+		log.Error("Forecast updates in process could not be restored. Details: %s", err)
+	}
+	for i := 0; i < nWorkers; i++ {
+		go q.work(sysConfiguration)
+	}
+}
+
+func (q *forecastUpdateQueue) work(sysConfiguration util.SystemConfiguration) {
+	for {
+		update, found := q.claim()
+		if !found {
+			select {
+			case <-q.notify:
+			case <-time.After(forecastQueuePollInterval):
+			}
+			continue
+		}
+		log.Info("Start processing forecast update %s for window %s - %s", update.ID.Hex(),
+			update.TimeWindowStart, update.TimeWindowEnd)
+		err := processForecastUpdate(update.Forecast, update.ServiceName, sysConfiguration)
+		q.finish(update, err)
+	}
+}
+
+//Take the oldest pending update whose window is not being processed by another worker
+func (q *forecastUpdateQueue) claim() (types.ForecastUpdate, bool) {
+	q.Lock()
+	defer q.Unlock()
+	busy := []types.ForecastUpdate{}
+	for _, u := range q.busy {
+		busy = append(busy, u)
+	}
+	update, err := storage.GetForecastUpdateDAO().ClaimNext(busy)
+	if err != nil {
+		if err != mgo.ErrNotFound {
+			log.Error("Forecast updates could not be retrieved. Details: %s", err)
+		}
+		return update, false
+	}
+	q.busy[update.ID] = update
+	return update, true
+}
+
+//Remove a processed update from the queue, failed updates are kept with the error until they are re-queued
+func (q *forecastUpdateQueue) finish(update types.ForecastUpdate, err error) {
+	q.Lock()
+	delete(q.busy, update.ID)
+	q.Unlock()
+
+	forecastUpdateDAO := storage.GetForecastUpdateDAO()
+	if err != nil {
+		log.Error("Forecast update %s failed. Details: %s", update.ID.Hex(), err)
+		err = forecastUpdateDAO.MarkFailed(update.ID, err.Error())
+	} else {
+		log.Info("Finish processing forecast update %s", update.ID.Hex())
+		err = forecastUpdateDAO.DeleteById(update.ID)
+	}
+	if err != nil {
+		log.Error("The forecast update %s could not be removed from the queue. Details: %s", update.ID.Hex(), err)
+	}
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"errors"
+	"github.com/Cloud-Pie/SPDT/planner/derivation"
+	db "github.com/Cloud-Pie/SPDT/storage"
+	"github.com/Cloud-Pie/SPDT/types"
+	"github.com/gin-gonic/gin"
+	"gopkg.in/mgo.v2/bson"
+	"net/http"
+)
+
+// This handler compares two policies aligning their scaling actions by time
+// The request responds to:  /api/:service/diff?a=<id>&b=<id>
+// The ids can belong to policies or to derivation jobs, in which case the selected policy of the job is used
+func policiesDiff(c *gin.Context) {
+	serviceName := c.Param("service")
+	idA := c.Query("a")
+	idB := c.Query("b")
+	if idA == "" || idB == "" {
+		c.JSON(http.StatusBadRequest, "Missing parameters [a,b]")
+		return
+	}
+	policyA, err := resolvePolicy(serviceName, idA)
+	if err != nil {
+		c.JSON(http.StatusNotFound, err.Error())
+		return
+	}
+	policyB, err := resolvePolicy(serviceName, idB)
+	if err != nil {
+		c.JSON(http.StatusNotFound, err.Error())
+		return
+	}
+	vmProfiles, _, err := ReadVMProfiles(sysConfiguration)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+	diff := derivation.DiffPolicies(policyA, policyB, serviceName, derivation.VMListToMap(vmProfiles))
+	c.JSON(http.StatusOK, diff)
+}
+
+//Find a stored policy, or the policy selected by a derivation job
+func resolvePolicy(serviceName string, id string) (types.Policy, error) {
+	if job, ok := derivationJobs.get(id); ok && job.Service == serviceName {
+		if job.SelectedPolicy == nil {
+			return types.Policy{}, errors.New("Derivation job " + id + " has no selected policy")
+		}
+		return *job.SelectedPolicy, nil
+	}
+	if !bson.IsObjectIdHex(id) {
+		return types.Policy{}, errors.New("Invalid id " + id)
+	}
+	policy, err := db.GetPolicyDAO(serviceName).FindByID(id)
+	if err != nil {
+		return policy, errors.New("Policy " + id + " not found")
+	}
+	return policy, nil
+}
@@ -0,0 +1,143 @@
+package server
+
+import (
+	"github.com/Cloud-Pie/SPDT/planner/profiles_processing"
+	"github.com/Cloud-Pie/SPDT/storage"
+	"github.com/Cloud-Pie/SPDT/types"
+	"github.com/Cloud-Pie/SPDT/util"
+	"time"
+)
+
+//Defaults used when the intervals of profile-refresh are missing or not positive
+const (
+	defaultProfilesCheckInterval = time.Hour
+	defaultProfilesMaxAge        = 7 * 24 * time.Hour
+)
+
+//Check periodically the age of the stored profiles and fetch them again when they are stale
+func refreshProfiles(sysConfiguration util.SystemConfiguration) {
+	settings := sysConfiguration.ProfileRefresh
+	interval := defaultProfilesCheckInterval
+	if settings.CheckInterval != "" {
+		interval = positiveInterval("check-interval", settings.CheckInterval, defaultProfilesCheckInterval)
+	}
+	for {
+		time.Sleep(interval)
+		vmProfiles, _, err := ReadVMProfiles(sysConfiguration)
+		if err != nil {
+			log.Error("Profiles could not be refreshed. Details: %s", err)
+			continue
+		}
+		err = RefreshProfiles(sysConfiguration, vmProfiles, false)
+		if err != nil {
+			log.Error("Profiles could not be refreshed. Details: %s", err)
+		}
+	}
+}
+
+/* Fetch again the performance and booting profiles older than the max age and swap them with the stored ones.
+	The stored profiles are kept if the request fails or returns no profiles, and the profiles imported or
+	learned locally are never replaced
+	in:
+		@sysConfiguration util.SystemConfiguration
+		@vmProfiles []types.VmProfile
+		@force bool	- Refresh the profiles regardless of their age
+	out:
+		@error
+*/
+func RefreshProfiles(sysConfiguration util.SystemConfiguration, vmProfiles []types.VmProfile, force bool) error {
+	maxAge := profilesMaxAge(sysConfiguration.ProfileRefresh, force)
+	now := time.Now()
+	err := refreshPerformanceProfiles(sysConfiguration, maxAge, now)
+	errBooting := refreshVMBootingProfiles(sysConfiguration, vmProfiles, maxAge, now)
+	if err != nil {
+		return err
+	}
+	return errBooting
+}
+
+//Age after which the fetched profiles are stale, all of them are stale when the refresh is forced
+func profilesMaxAge(settings util.ProfileRefresh, force bool) time.Duration {
+	if force {
+		return 0
+	}
+	if settings.MaxAge == "" {
+		return defaultProfilesMaxAge
+	}
+	return positiveInterval("max-age", settings.MaxAge, defaultProfilesMaxAge)
+}
+
+//Parse an interval of profile-refresh, a non-positive one would refresh in a busy loop and is replaced by the default
+func positiveInterval(name string, value string, defaultInterval time.Duration) time.Duration {
+	interval := time.Duration(util.ParseIntervalToSeconds(value)) * time.Second
+	if interval <= 0 {
+		log.Warning("Invalid profile-refresh %s %s, using %s", name, value, defaultInterval)
+		return defaultInterval
+	}
+	return interval
+}
+
+func refreshPerformanceProfiles(sysConfiguration util.SystemConfiguration, maxAge time.Duration, now time.Time) error {
+	serviceProfileDAO := storage.GetPerformanceProfileDAO(sysConfiguration.MainServiceName)
+	stored, _ := serviceProfileDAO.FindAll()
+	stale := len(stored) == 0
+	for _, p := range stored {
+		if profiles_processing.IsStale(p.Source, p.FetchedAt, maxAge, now) {
+			stale = true
+			break
+		}
+	}
+	if !stale {
+		return nil
+	}
+
+	fetched, err := fetchPerformanceProfiles(sysConfiguration)
+	if err != nil {
+		return err
+	}
+	if len(fetched) == 0 {
+		log.Warning("No performance profiles received, the stored profiles are kept")
+		return nil
+	}
+	profiles := profiles_processing.KeepLocalProfiles(stored, fetched)
+	comparison := profiles_processing.CompareProfiles(stored, profiles)
+	log.Info("Performance profiles refreshed: %d added, %d removed, %d changed, %d unchanged",
+		comparison.Added, comparison.Removed, comparison.Changed, comparison.Unchanged)
+	return serviceProfileDAO.ReplaceAll(profiles)
+}
+
+func refreshVMBootingProfiles(sysConfiguration util.SystemConfiguration, vmProfiles []types.VmProfile, maxAge time.Duration, now time.Time) error {
+	vmBootingProfileDAO := storage.GetVMBootingProfileDAO()
+	stored, _ := vmBootingProfileDAO.FindAll()
+	stale := len(stored) == 0
+	for _, p := range stored {
+		if profiles_processing.IsStale(p.Source, p.FetchedAt, maxAge, now) {
+			stale = true
+			break
+		}
+	}
+	if !stale {
+		return nil
+	}
+
+	fetched, err := fetchVMBootingProfiles(sysConfiguration, vmProfiles)
+	if len(fetched) == 0 {
+		log.Warning("No VM booting profiles received, the stored profiles are kept")
+		return err
+	}
+	//Types that could not be requested keep their stored profile
+	fetchedTypes := map[string]bool{}
+	for _, p := range fetched {
+		fetchedTypes[p.VMType] = true
+	}
+	for _, p := range stored {
+		if !fetchedTypes[p.VMType] {
+			fetched = append(fetched, p)
+		}
+	}
+	profiles := profiles_processing.KeepLocalBootingProfiles(stored, fetched)
+	comparison := profiles_processing.CompareBootingProfiles(stored, profiles)
+	log.Info("VM booting profiles refreshed: %d added, %d removed, %d changed, %d unchanged",
+		comparison.Added, comparison.Removed, comparison.Changed, comparison.Unchanged)
+	return vmBootingProfileDAO.ReplaceAll(profiles)
+}
@@ -0,0 +1,31 @@
+package server
+
+import (
+	"github.com/Cloud-Pie/SPDT/util"
+	"testing"
+	"time"
+)
+
+func TestProfilesMaxAge(t *testing.T) {
+	var tests = []struct {
+		settings util.ProfileRefresh
+		force    bool
+		maxAge   time.Duration
+	}{
+		{util.ProfileRefresh{}, false, defaultProfilesMaxAge},
+		{util.ProfileRefresh{MaxAge: "2D"}, false, 48 * time.Hour},
+		{util.ProfileRefresh{MaxAge: "0h"}, false, defaultProfilesMaxAge},
+		{util.ProfileRefresh{}, true, 0},
+		{util.ProfileRefresh{MaxAge: "2D"}, true, 0},
+	}
+	for _, test := range tests {
+		maxAge := profilesMaxAge(test.settings, test.force)
+		if maxAge != test.maxAge {
+			t.Error(
+				"For: ", test.settings, test.force,
+				"expected: ", test.maxAge,
+				"got: ", maxAge,
+			)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	Fservice "github.com/Cloud-Pie/SPDT/rest_clients/forecast"
 	"github.com/Cloud-Pie/SPDT/storage"
 	"github.com/Cloud-Pie/SPDT/util"
@@ -9,56 +10,130 @@ import (
 	"github.com/Cloud-Pie/SPDT/types"
 	"fmt"
 	"github.com/Cloud-Pie/SPDT/planner/updatesHandler"
+	"github.com/Cloud-Pie/SPDT/planner/derivation"
+	"github.com/Cloud-Pie/SPDT/planner/forecast_processing"
 )
 
 var requestsCapacityPerState types.RequestCapacitySupply
 
+//Stages reported while a scaling policy is derived
+const (
+	STAGE_PERFORMANCE_PROFILES = "fetching-performance-profiles"
+	STAGE_FORECAST             = "fetching-forecast"
+	STAGE_VM_PROFILES          = "fetching-vm-profiles"
+	STAGE_VALIDATION           = "validating-stored-policy"
+	STAGE_DERIVATION           = "deriving-policies"
+	STAGE_SCHEDULING           = "scheduling"
+	STAGE_FINISHED             = "finished"
+)
+
+//Function called every time the derivation reaches a new stage, progress goes from 0 to 1
+type progressReporter func(stage string, progress float64)
+
 func StartPolicyDerivation(timeStart time.Time, timeEnd time.Time, sysConfiguration util.SystemConfiguration) (types.Policy, error) {
+	selectedPolicy,_,err := runPolicyDerivation(context.Background(), timeStart, timeEnd, sysConfiguration,
+		func(stage string, progress float64) {})
+	return selectedPolicy, err
+}
+
+/* Fetch the inputs, derive and schedule the scaling policy for a time window.
+	The context is checked between stages, a cancelled derivation stops before scheduling any state
+	in:
+		@ctx context.Context
+		@timeStart time.Time
+		@timeEnd time.Time
+		@sysConfiguration util.SystemConfiguration
+		@report progressReporter
+	out:
+		@types.Policy	- Selected policy
+		@[]types.Policy	- Candidate policies, empty if the stored policy is still valid
+		@error
+*/
+func runPolicyDerivation(ctx context.Context, timeStart time.Time, timeEnd time.Time, sysConfiguration util.SystemConfiguration,
+	report progressReporter) (types.Policy, []types.Policy, error) {
 	var selectedPolicy types.Policy
+	var candidatePolicies []types.Policy
 	mainService := sysConfiguration.MainServiceName
 
 	//Request Performance Profiles
+	report(STAGE_PERFORMANCE_PROFILES, 0.1)
 	error := FetchApplicationProfile(sysConfiguration)
 	if error != nil {
-		return types.Policy{},error
+		return types.Policy{}, candidatePolicies, error
 	}
 	//Request Forecasting
+	if err := ctx.Err(); err != nil {
+		return types.Policy{}, candidatePolicies, err
+	}
+	report(STAGE_FORECAST, 0.2)
 	forecast,err := fetchForecast(sysConfiguration, timeStart, timeEnd)
 	if err != nil {
-		return types.Policy{},err
+		return types.Policy{}, candidatePolicies, err
 	}
 
 	//Get VM Profiles
-	vmProfiles,err := ReadVMProfiles()
+	if err := ctx.Err(); err != nil {
+		return types.Policy{}, candidatePolicies, err
+	}
+	report(STAGE_VM_PROFILES, 0.3)
+	vmProfiles,catalogVersion,err := ReadVMProfiles(sysConfiguration)
 	if err != nil {
-		return types.Policy{},err
+		return types.Policy{}, candidatePolicies, err
 	}
 	//Get VM booting Profiles
 	err = FetchVMBootingProfiles(sysConfiguration, vmProfiles)
 	if err != nil {
-		return types.Policy{},err
+		return types.Policy{}, candidatePolicies, err
 	}
 
 	updateForecastInDB(forecast, sysConfiguration)
 
+	if err := ctx.Err(); err != nil {
+		return types.Policy{}, candidatePolicies, err
+	}
+	report(STAGE_VALIDATION, 0.4)
 	policyDAO := storage.GetPolicyDAO(mainService)
 	storedPolicy, err := policyDAO.FindSelectedByTimeWindow(timeStart, timeEnd)
-	if err != nil {
-		selectedPolicy,err = setNewPolicy(forecast, sysConfiguration, vmProfiles)
-		ScheduleScaling(sysConfiguration, selectedPolicy)
-	}else {
-		shouldUpdate := updatesHandler.ValidateMSCThresholds(forecast,storedPolicy, sysConfiguration)
-		if shouldUpdate {
+	shouldDerive := err != nil
+	invalidateStored := false
+	if !shouldDerive {
+		violatedIndex,_ := updatesHandler.FirstThresholdViolation(forecast,storedPolicy, sysConfiguration)
+		if violatedIndex < 0 {
+			selectedPolicy = storedPolicy
+		} else if derivation.CommittedScalingActions(storedPolicy, violatedIndex, time.Now()) > 0 {
+			//Keep the committed scaling actions and derive only the rest of the window
+			report(STAGE_DERIVATION, 0.5)
+			selectedPolicy,err = replanPolicy(ctx, forecast, storedPolicy, violatedIndex, sysConfiguration, vmProfiles, catalogVersion)
+			report(STAGE_FINISHED, 1.0)
+			return selectedPolicy, candidatePolicies, err
+		} else {
+			shouldDerive = true
+			invalidateStored = true
+		}
+	}
+	err = nil
+
+	if shouldDerive {
+		report(STAGE_DERIVATION, 0.5)
+		selectedPolicy,candidatePolicies,err = derivePolicies(forecast, sysConfiguration, vmProfiles, catalogVersion)
+		if err != nil {
+			return types.Policy{}, candidatePolicies, err
+		}
+		//A job cancelled during the derivation leaves the stored policies unchanged
+		if err := ctx.Err(); err != nil {
+			return selectedPolicy, candidatePolicies, err
+		}
+		if invalidateStored {
 			updatesHandler.InvalidateOldPolicies(sysConfiguration, timeStart, timeEnd )
-			selectedPolicy,err = setNewPolicy(forecast, sysConfiguration, vmProfiles)
-			ScheduleScaling(sysConfiguration, selectedPolicy)
-			if err != nil {
-				return types.Policy{},err
-			}
 		}
+		err = storePolicies(candidatePolicies, sysConfiguration)
+		report(STAGE_SCHEDULING, 0.9)
+		ScheduleScaling(sysConfiguration, selectedPolicy)
+		recordBudgetLedger(sysConfiguration, selectedPolicy)
 	}
+	report(STAGE_FINISHED, 1.0)
 
-	return selectedPolicy, err
+	return selectedPolicy, candidatePolicies, err
 }
 
 func fetchForecast(sysConfiguration util.SystemConfiguration, timeStart time.Time, timeEnd time.Time) (types.Forecast,  error) {
@@ -74,6 +149,7 @@ func fetchForecast(sysConfiguration util.SystemConfiguration, timeStart time.Tim
 	} else {
 		log.Info("Finish request Forecasting")
 	}
+	forecast.ContentHash = forecast_processing.ContentHash(forecast)
 
 	//Retrieve data access to the database for forecasting
 	forecastDAO := storage.GetForecastDAO(mainService)
@@ -1,49 +1,57 @@
 package server
 
 import (
+	"context"
 	"github.com/Cloud-Pie/SPDT/types"
 	"github.com/Cloud-Pie/SPDT/planner/updatesHandler"
 	"github.com/Cloud-Pie/SPDT/storage"
 	"github.com/Cloud-Pie/SPDT/util"
-	"fmt"
 	"gopkg.in/mgo.v2/bson"
+	"github.com/Cloud-Pie/SPDT/monitoring"
 )
 
-func updatePolicyDerivation(forecastChannel chan types.Forecast) {
-	for forecast := range forecastChannel {
-		timeStart := forecast.TimeWindowStart
-		timeEnd := forecast.TimeWindowEnd
-		mainService := sysConfiguration.MainServiceName
+/* Validate a pushed forecast against the selected policy and re-plan it if the thresholds are violated
+	in:
+		@forecast types.Forecast
+		@serviceName string	- Service of the forecast update
+		@sysConfiguration util.SystemConfiguration	- Configuration of the running service
+	out:
+		@error
+*/
+func processForecastUpdate(forecast types.Forecast, serviceName string, sysConfiguration util.SystemConfiguration) error {
+	timeStart := forecast.TimeWindowStart
+	timeEnd := forecast.TimeWindowEnd
+	sysConfiguration.MainServiceName = serviceName
+	mainService := serviceName
 
-		//Read Configuration File
-		sysConfiguration,_ := util.ReadConfigFile(util.CONFIG_FILE)
-		if err := recover(); err != nil {
-			fmt.Println(err)
-		}
-		//Request Performance Profiles
-		FetchApplicationProfile(sysConfiguration)
-		//Get VM Profiles
-		vmProfiles,err := ReadVMProfiles()
-		if err != nil {
-			fmt.Println(err)
-		}
-		//Get VM booting Profiles
-		err = FetchVMBootingProfiles(sysConfiguration, vmProfiles)
+	//Request Performance Profiles
+	FetchApplicationProfile(sysConfiguration)
+	//Get VM Profiles
+	vmProfiles,catalogVersion,err := ReadVMProfiles(sysConfiguration)
+	if err != nil {
+		return err
+	}
+	//Get VM booting Profiles
+	err = FetchVMBootingProfiles(sysConfiguration, vmProfiles)
+	if err != nil {
+		log.Error("VM booting profiles could not be fetched. Details: %s", err)
+	}
+	updateForecastInDB(forecast, sysConfiguration)
+	policyDAO := storage.GetPolicyDAO(mainService)
+	storedPolicy, err := policyDAO.FindSelectedByTimeWindow(timeStart, timeEnd)
+	violatedIndex,_ := updatesHandler.FirstThresholdViolation(forecast,storedPolicy, sysConfiguration)
+	if violatedIndex >= 0 {
+		monitoring.ForecastUpdate(mainService, monitoring.FORECAST_UPDATE_INVALIDATED)
+		_,err := replanPolicy(context.Background(), forecast, storedPolicy, violatedIndex, sysConfiguration, vmProfiles, catalogVersion)
 		if err != nil {
-			fmt.Println(err)
-		}
-		updateForecastInDB(forecast, sysConfiguration)
-		policyDAO := storage.GetPolicyDAO(mainService)
-		storedPolicy, err := policyDAO.FindSelectedByTimeWindow(timeStart, timeEnd)
-		shouldUpdate := updatesHandler.ValidateMSCThresholds(forecast,storedPolicy, sysConfiguration)
-		if shouldUpdate {
-			updatesHandler.InvalidateOldPolicies(sysConfiguration, timeStart, timeEnd )
-			selectedPolicy,_ := setNewPolicy(forecast, sysConfiguration, vmProfiles)
-			ScheduleScaling(sysConfiguration, selectedPolicy)
-		} else {
-			log.Info("Forecast updated. Scaling policy is still valid")
+			monitoring.ForecastUpdate(mainService, monitoring.FORECAST_UPDATE_FAILED)
+			return err
 		}
+	} else {
+		monitoring.ForecastUpdate(mainService, monitoring.FORECAST_UPDATE_VALID)
+		log.Info("Forecast updated. Scaling policy is still valid")
 	}
+	return nil
 }
 
 func updateForecastInDB(forecast types.Forecast, sysConfiguration util.SystemConfiguration) error {
@@ -72,4 +80,28 @@ func updateForecastInDB(forecast types.Forecast, sysConfiguration util.SystemCon
 		forecastDAO.Update(id, forecast)
 	}
 	return err
-}
\ No newline at end of file
+}
+
+/* Check if a pushed forecast was already received, either pending in the queue or already processed.
+	Two notifications are the same if they have the same prediction id and content hash
+	in:
+		@serviceName string
+		@forecast types.Forecast
+	out:
+		@bool
+*/
+func isRepeatedForecast(serviceName string, forecast types.Forecast) bool {
+	pendingUpdates,err := storage.GetForecastUpdateDAO().FindPending(serviceName)
+	if err == nil {
+		for _,u := range pendingUpdates {
+			if u.Forecast.IDPrediction == forecast.IDPrediction && u.Forecast.ContentHash == forecast.ContentHash {
+				return true
+			}
+		}
+	}
+	storedForecast,err := storage.GetForecastDAO(serviceName).FindOneByTimeWindow(forecast.TimeWindowStart, forecast.TimeWindowEnd)
+	if err != nil {
+		return false
+	}
+	return storedForecast.IDPrediction == forecast.IDPrediction && storedForecast.ContentHash == forecast.ContentHash
+}
@@ -0,0 +1,143 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"github.com/Cloud-Pie/SPDT/monitoring"
+	"github.com/Cloud-Pie/SPDT/planner/execution"
+	"github.com/Cloud-Pie/SPDT/storage"
+	"github.com/Cloud-Pie/SPDT/types"
+	"github.com/Cloud-Pie/SPDT/util"
+	"gopkg.in/mgo.v2/bson"
+	"strings"
+	"time"
+)
+
+//Compare periodically the infrastructure with the state expected by the selected policy
+func reconcileDrift(sysConfiguration util.SystemConfiguration) {
+	settings := sysConfiguration.DriftReconciliation
+	interval := 5 * time.Minute
+	if settings.Interval != "" {
+		interval = time.Duration(util.ParseIntervalToSeconds(settings.Interval)) * time.Second
+	}
+	//Scaling actions already handled with their end time, the reconciler reacts only once to each of them
+	handled := make(map[string]time.Time)
+	for {
+		time.Sleep(interval)
+		err := checkDrift(sysConfiguration, time.Now(), handled)
+		if err != nil {
+			log.Error("Drift could not be checked. Details: %s", err)
+		}
+	}
+}
+
+/* Compare the actual state with the desired state of the active scaling action.
+	The drift is stored and, depending on the configuration, the state is scheduled again,
+	the policy is derived again from the actual state or only an alert is logged
+	in:
+		@sysConfiguration util.SystemConfiguration
+		@now time.Time
+		@handled map[string]time.Time	- Scaling actions to which the reconciler already reacted, with their end time
+	out:
+		@error
+*/
+func checkDrift(sysConfiguration util.SystemConfiguration, now time.Time, handled map[string]time.Time) error {
+	pruneHandled(handled, now)
+	mainService := sysConfiguration.MainServiceName
+	settings := sysConfiguration.DriftReconciliation
+	policyDAO := storage.GetPolicyDAO(mainService)
+	policy, err := policyDAO.FindSelectedByTime(now)
+	if err != nil {
+		log.Info("No selected policy active at %s", now)
+		return nil
+	}
+	activeAction := execution.ActiveScalingAction(policy.ScalingActions, now)
+	if activeAction == nil {
+		return nil
+	}
+	gracePeriod := time.Duration(0)
+	if settings.GracePeriod != "" {
+		gracePeriod = time.Duration(util.ParseIntervalToSeconds(settings.GracePeriod)) * time.Second
+	}
+	if now.Before(activeAction.TimeStart.Add(gracePeriod)) {
+		return nil
+	}
+
+	actualState, err := execution.RetrieveCurrentState(sysConfiguration.SchedulerComponent.Endpoint + util.ENDPOINT_CURRENT_STATE)
+	if err != nil {
+		return err
+	}
+	event := execution.CompareStates(*activeAction, actualState, mainService)
+	if len(event.Kinds) == 0 {
+		return nil
+	}
+
+	actionKey := policy.ID.Hex() + activeAction.TimeStart.String()
+	event.ID = bson.NewObjectId()
+	event.ServiceName = mainService
+	event.PolicyID = policy.ID.Hex()
+	event.ActionTimeStart = activeAction.TimeStart
+	event.DetectedAt = now
+	event.Action = util.DRIFT_ACTION_ALERT
+	if _, ok := handled[actionKey]; !ok && settings.Action != "" {
+		event.Action = settings.Action
+	}
+	log.Warning("Drift detected for policy %s in the state starting at %s: %s", event.PolicyID,
+		activeAction.TimeStart, strings.Join(event.Kinds, ", "))
+	for _, kind := range event.Kinds {
+		monitoring.DriftDetected(mainService, kind)
+	}
+	err = storage.GetDriftEventDAO(mainService).Insert(event)
+	if err != nil {
+		log.Error("The drift event could not be stored. Details: %s", err)
+	}
+
+	switch event.Action {
+	case util.DRIFT_ACTION_RESCHEDULE:
+		handled[actionKey] = activeAction.TimeEnd
+		log.Info("Schedule again the state %s", activeAction.DesiredState.Hash)
+		rescheduled := *activeAction
+		rescheduled.InitialState = actualState
+		rescheduled.TimeStartTransition = now
+		rescheduled.TimeStart = now
+		ScheduleScaling(sysConfiguration, types.Policy{ScalingActions: []types.ScalingAction{rescheduled}})
+	case util.DRIFT_ACTION_REDERIVE:
+		handled[actionKey] = activeAction.TimeEnd
+		return rederiveFromActualState(sysConfiguration, policy, actualState, now)
+	}
+	return nil
+}
+
+//Forget the handled scaling actions that already ended, they cannot be active again
+func pruneHandled(handled map[string]time.Time, now time.Time) {
+	for key, timeEnd := range handled {
+		if !timeEnd.After(now) {
+			delete(handled, key)
+		}
+	}
+}
+
+//Derive again the rest of the policy window starting from the actual state of the infrastructure
+func rederiveFromActualState(sysConfiguration util.SystemConfiguration, policy types.Policy, actualState types.State,
+	now time.Time) error {
+	forecastDAO := storage.GetForecastDAO(sysConfiguration.MainServiceName)
+	forecast, err := forecastDAO.FindOneByTimeWindow(policy.TimeWindowStart, policy.TimeWindowEnd)
+	if err != nil {
+		return errors.New("No forecast found for the window of policy " + policy.ID.Hex())
+	}
+	vmProfiles, catalogVersion, err := ReadVMProfiles(sysConfiguration)
+	if err != nil {
+		return err
+	}
+	//The scaling actions that already finished are kept
+	committed := 0
+	for committed < len(policy.ScalingActions) && !policy.ScalingActions[committed].TimeEnd.After(now) {
+		committed++
+	}
+	log.Info("Start derivation of policy %s from the actual state", policy.ID.Hex())
+	_, err = rederivePolicy(context.Background(), forecast, policy, committed, actualState, now, now, sysConfiguration, vmProfiles, catalogVersion)
+	if err == nil {
+		log.Info("Finish derivation of policy %s from the actual state", policy.ID.Hex())
+	}
+	return err
+}
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPruneHandled(t *testing.T) {
+	now := time.Date(2018, 11, 1, 10, 0, 0, 0, time.UTC)
+	handled := map[string]time.Time{
+		"ended":   now.Add(-time.Hour),
+		"ending":  now,
+		"active":  now.Add(time.Minute),
+		"pending": now.Add(2 * time.Hour),
+	}
+	pruneHandled(handled, now)
+
+	var tests = []struct {
+		key  string
+		kept bool
+	}{
+		{"ended", false},
+		{"ending", false},
+		{"active", true},
+		{"pending", true},
+	}
+	for _, test := range tests {
+		if _, ok := handled[test.key]; ok != test.kept {
+			t.Error(
+				"For: ", test.key,
+				"expected: ", test.kept,
+				"got: ", ok,
+			)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"github.com/Cloud-Pie/SPDT/planner/derivation"
+	"github.com/Cloud-Pie/SPDT/planner/updatesHandler"
+	"github.com/Cloud-Pie/SPDT/storage"
+	"github.com/Cloud-Pie/SPDT/types"
+	"github.com/Cloud-Pie/SPDT/util"
+	"time"
+)
+
+/* Update a policy whose thresholds are violated by a new forecast.
+	The scaling actions before the first violation are kept and only the rest of the window is derived again,
+	starting from the last committed state. If nothing can be kept, the whole window is derived again.
+	Once ctx is cancelled nothing is stored nor scheduled
+	in:
+		@ctx context.Context
+		@forecast types.Forecast
+		@storedPolicy types.Policy	- Selected policy for the window of the forecast
+		@violatedIndex int	- Index of the first violated scaling action
+		@sysConfiguration util.SystemConfiguration
+		@vmProfiles []types.VmProfile
+		@catalogVersion int	- Version of the VM catalog from which vmProfiles were read
+	out:
+		@types.Policy	- New selected policy
+		@error
+*/
+func replanPolicy(ctx context.Context, forecast types.Forecast, storedPolicy types.Policy, violatedIndex int,
+	sysConfiguration util.SystemConfiguration, vmProfiles []types.VmProfile, catalogVersion int) (types.Policy, error) {
+	now := time.Now()
+	committed := derivation.CommittedScalingActions(storedPolicy, violatedIndex, now)
+	if committed == 0 {
+		selectedPolicy, candidatePolicies, err := derivePolicies(forecast, sysConfiguration, vmProfiles, catalogVersion)
+		if err != nil {
+			return selectedPolicy, err
+		}
+		if err := ctx.Err(); err != nil {
+			return selectedPolicy, err
+		}
+		updatesHandler.InvalidateOldPolicies(sysConfiguration, forecast.TimeWindowStart, forecast.TimeWindowEnd)
+		err = storePolicies(candidatePolicies, sysConfiguration)
+		ScheduleScaling(sysConfiguration, selectedPolicy)
+		recordBudgetLedger(sysConfiguration, selectedPolicy)
+		return selectedPolicy, err
+	}
+	timeStart := derivation.ReplanningStart(storedPolicy, violatedIndex, committed, now)
+	timeInvalidation := now
+	if committed > violatedIndex {
+		log.Warning("The transition of the violated scaling action of policy %s already started, it is kept until %s",
+			storedPolicy.ID.Hex(), timeStart)
+	} else {
+		timeInvalidation = storedPolicy.ScalingActions[committed].TimeStartTransition
+	}
+	log.Info("Start re-planning of policy %s from %s", storedPolicy.ID.Hex(), timeStart)
+	lastCommittedState := storedPolicy.ScalingActions[committed-1].DesiredState
+	selectedPolicy, err := rederivePolicy(ctx, forecast, storedPolicy, committed, lastCommittedState, timeStart,
+		timeInvalidation, sysConfiguration, vmProfiles, catalogVersion)
+	if err != nil {
+		return selectedPolicy, err
+	}
+	log.Info("Finish re-planning of policy %s", storedPolicy.ID.Hex())
+	return selectedPolicy, nil
+}
+
+/* Derive again a policy from a given state and time, keeping its first scaling actions.
+	The stored policy is updated and the new scaling actions are sent to the scheduler, unless ctx is cancelled
+	in:
+		@ctx context.Context
+		@forecast types.Forecast
+		@storedPolicy types.Policy
+		@committed int	- Number of scaling actions kept
+		@initialState types.State	- State from which the new scaling actions start
+		@timeStart time.Time	- Time from which the policy is derived again
+		@timeInvalidation time.Time	- Scheduled states after this time are invalidated
+		@sysConfiguration util.SystemConfiguration
+		@vmProfiles []types.VmProfile
+		@catalogVersion int	- Version of the VM catalog from which vmProfiles were read
+	out:
+		@types.Policy	- Updated policy
+		@error
+*/
+func rederivePolicy(ctx context.Context, forecast types.Forecast, storedPolicy types.Policy, committed int, initialState types.State,
+	timeStart time.Time, timeInvalidation time.Time, sysConfiguration util.SystemConfiguration,
+	vmProfiles []types.VmProfile, catalogVersion int) (types.Policy, error) {
+	selectedPolicy, err := deriveSuffix(forecast, storedPolicy, committed, initialState, timeStart, sysConfiguration,
+		vmProfiles, catalogVersion)
+	if err != nil {
+		return storedPolicy, err
+	}
+	if err := ctx.Err(); err != nil {
+		return storedPolicy, err
+	}
+
+	policyDAO := storage.GetPolicyDAO(sysConfiguration.MainServiceName)
+	err = policyDAO.UpdateById(selectedPolicy.ID, selectedPolicy)
+	if err != nil {
+		log.Error("The policy with ID = %s could not be updated. Error %s\n", selectedPolicy.ID, err)
+	}
+
+	//Only the states after the committed prefix are sent again to the scheduler.
+	//The new actions may need to start their transition before the replaced ones
+	if len(selectedPolicy.ScalingActions) > committed &&
+		selectedPolicy.ScalingActions[committed].TimeStartTransition.Before(timeInvalidation) {
+		timeInvalidation = selectedPolicy.ScalingActions[committed].TimeStartTransition
+	}
+	err = updatesHandler.InvalidateScalingStates(sysConfiguration, timeInvalidation)
+	if err != nil {
+		return selectedPolicy, err
+	}
+	suffixPolicy := selectedPolicy
+	suffixPolicy.ScalingActions = selectedPolicy.ScalingActions[committed:]
+	ScheduleScaling(sysConfiguration, suffixPolicy)
+	recordBudgetLedger(sysConfiguration, selectedPolicy)
+	return selectedPolicy, nil
+}
+
+//Derive the scaling actions after the committed prefix and join them with the stored policy
+func deriveSuffix(forecast types.Forecast, storedPolicy types.Policy, committed int, initialState types.State,
+	timeStart time.Time, sysConfiguration util.SystemConfiguration, vmProfiles []types.VmProfile,
+	catalogVersion int) (types.Policy, error) {
+	suffixForecast := derivation.ForecastFrom(forecast, timeStart)
+	if len(suffixForecast.ForecastedValues) == 0 {
+		return storedPolicy, errors.New("No forecasted values after " + timeStart.String())
+	}
+
+	derivationLock.Lock()
+	defer derivationLock.Unlock()
+
+	candidatePolicies, err := derivation.PoliciesFromState(vmProfiles, sysConfiguration, suffixForecast, initialState)
+	if err != nil {
+		return storedPolicy, err
+	}
+	suffix, err := derivation.SelectPolicy(&candidatePolicies, sysConfiguration, vmProfiles, suffixForecast)
+	if err != nil {
+		return storedPolicy, err
+	}
+	selectedPolicy := derivation.ReplaceScalingActions(storedPolicy, committed, suffix)
+	selectedPolicy.VMCatalogVersion = catalogVersion
+
+	//Metrics are computed again for the whole window
+	metrics, _ := derivation.ComputePolicyMetrics(&selectedPolicy.ScalingActions, forecast.ForecastedValues,
+		sysConfiguration, derivation.VMListToMap(vmProfiles))
+	metrics.StartTimeDerivation = storedPolicy.Metrics.StartTimeDerivation
+	metrics.FinishTimeDerivation = suffix.Metrics.FinishTimeDerivation
+	metrics.DerivationDuration = util.RoundN(storedPolicy.Metrics.DerivationDuration + suffix.Metrics.DerivationDuration, 2.0)
+	metrics.BillingBoundarySavings = suffix.Metrics.BillingBoundarySavings
+	selectedPolicy.Metrics = metrics
+	selectedPolicy.Metrics.Baselines = derivation.ComputeBaselines(selectedPolicy, forecast.ForecastedValues,
+		sysConfiguration, derivation.VMListToMap(vmProfiles))
+	return selectedPolicy, nil
+}
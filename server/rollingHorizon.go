@@ -0,0 +1,210 @@
+package server
+
+import (
+	"errors"
+	"github.com/Cloud-Pie/SPDT/planner/derivation"
+	"github.com/Cloud-Pie/SPDT/storage"
+	"github.com/Cloud-Pie/SPDT/types"
+	"github.com/Cloud-Pie/SPDT/util"
+	"gopkg.in/mgo.v2/bson"
+	"time"
+)
+
+//Policy built by stitching the committed segments of the rolling horizon planning.
+//A stored policy spans at most the planning horizon, then a new one continues from its last state
+type rollingSchedule struct {
+	policy   types.Policy
+	forecast []types.ForecastedValue //Forecasted values of the committed segments
+}
+
+//Plan a long horizon periodically, committing and scheduling only the near-term segment
+func rollingHorizonDerivation(sysConfiguration util.SystemConfiguration) {
+	settings := sysConfiguration.RollingHorizon
+	if settings.PlanningHorizon == "" || settings.CommitHorizon == "" || settings.ReplanningInterval == "" {
+		log.Error("Rolling horizon requires planning-horizon, commit-horizon and replanning-interval")
+		periodicPolicyDerivation(sysConfiguration)
+		return
+	}
+	replanningInterval := time.Duration(util.ParseIntervalToSeconds(settings.ReplanningInterval)) * time.Second
+
+	timeStart := sysConfiguration.ScalingHorizon.StartTime
+	if now := time.Now(); now.After(timeStart) {
+		timeStart = now.Truncate(time.Minute)
+	}
+	schedule := loadRollingSchedule(sysConfiguration, timeStart)
+	for {
+		err := schedule.planNextSegment(sysConfiguration, time.Now())
+		if err != nil {
+			log.Error("An error has occurred and the next segment has been not planned. Details: %s", err)
+		}
+		time.Sleep(replanningInterval)
+	}
+}
+
+/* Continue the schedule of the last stored rolling horizon policy.
+	The forecast of its segments is not stored, then a new policy starts from its last state
+	in:
+		@sysConfiguration util.SystemConfiguration
+		@timeStart time.Time	- Start of the schedule when no policy is stored
+	out:
+		@rollingSchedule
+*/
+func loadRollingSchedule(sysConfiguration util.SystemConfiguration, timeStart time.Time) rollingSchedule {
+	policyDAO := storage.GetPolicyDAO(sysConfiguration.MainServiceName)
+	storedPolicy, err := policyDAO.FindLastRolling()
+	if err != nil || len(storedPolicy.ScalingActions) == 0 {
+		return newRollingSchedule(timeStart, nil)
+	}
+	if storedPolicy.TimeWindowEnd.After(timeStart) {
+		timeStart = storedPolicy.TimeWindowEnd
+	}
+	log.Info("Continue the schedule of policy %s from %s", storedPolicy.ID.Hex(), timeStart)
+	return newRollingSchedule(timeStart, &storedPolicy.ScalingActions[len(storedPolicy.ScalingActions)-1])
+}
+
+/* Start a new rolling horizon policy
+	in:
+		@timeStart time.Time
+		@lastAction *types.ScalingAction	- Last action of the previous policy, nil if there is none
+	out:
+		@rollingSchedule
+*/
+func newRollingSchedule(timeStart time.Time, lastAction *types.ScalingAction) rollingSchedule {
+	policy := types.Policy{
+		ID:              bson.NewObjectId(),
+		Status:          types.SELECTED,
+		Parameters:      map[string]string{types.ROLLING_HORIZON: "true"},
+		ScalingActions:  []types.ScalingAction{},
+		TimeWindowStart: timeStart,
+		TimeWindowEnd:   timeStart,
+	}
+	if lastAction != nil {
+		//The last state is already scheduled, it is kept only to stitch the next segments
+		current := *lastAction
+		current.InitialState = current.DesiredState
+		current.TimeStartTransition = timeStart
+		current.TimeStart = timeStart
+		current.TimeEnd = timeStart
+		policy.ScalingActions = append(policy.ScalingActions, current)
+	}
+	return rollingSchedule{policy: policy}
+}
+
+/* Derive the planning horizon starting at the end of the committed schedule,
+	then commit and schedule the scaling actions until the commit horizon
+	in:
+		@sysConfiguration util.SystemConfiguration
+		@now time.Time
+	out:
+		@error
+*/
+func (s *rollingSchedule) planNextSegment(sysConfiguration util.SystemConfiguration, now time.Time) error {
+	settings := sysConfiguration.RollingHorizon
+	planningHorizon := time.Duration(util.ParseIntervalToSeconds(settings.PlanningHorizon)) * time.Second
+	commitHorizon := time.Duration(util.ParseIntervalToSeconds(settings.CommitHorizon)) * time.Second
+
+	//The committed history is not kept in memory nor stored again
+	if n := len(s.policy.ScalingActions); n > 0 && s.policy.TimeWindowEnd.Sub(s.policy.TimeWindowStart) >= planningHorizon {
+		*s = newRollingSchedule(s.policy.TimeWindowEnd, &s.policy.ScalingActions[n-1])
+	}
+
+	planStart := s.policy.TimeWindowEnd
+	planEnd := planStart.Add(planningHorizon)
+	commitEnd := now.Add(commitHorizon)
+	if !commitEnd.After(planStart) {
+		log.Info("Schedule already committed until %s", planStart)
+		return nil
+	}
+	if commitEnd.After(planEnd) {
+		commitEnd = planEnd
+	}
+
+	err := FetchApplicationProfile(sysConfiguration)
+	if err != nil {
+		return err
+	}
+	forecast, err := fetchForecast(sysConfiguration, planStart, planEnd)
+	if err != nil {
+		return err
+	}
+	vmProfiles, catalogVersion, err := ReadVMProfiles(sysConfiguration)
+	if err != nil {
+		return err
+	}
+	err = FetchVMBootingProfiles(sysConfiguration, vmProfiles)
+	if err != nil {
+		return err
+	}
+
+	log.Info("Start planning segment from %s to %s, committing until %s", planStart, planEnd, commitEnd)
+	segment, err := s.deriveSegment(forecast, sysConfiguration, vmProfiles, catalogVersion)
+	if err != nil {
+		return err
+	}
+	committedActions := derivation.CommitSegment(segment.ScalingActions, commitEnd)
+	if len(committedActions) == 0 {
+		return errors.New("No scaling actions derived before " + commitEnd.String())
+	}
+	nScheduled := len(s.policy.ScalingActions)
+	s.policy.ScalingActions = derivation.StitchScalingActions(s.policy.ScalingActions, committedActions)
+	s.policy.TimeWindowEnd = commitEnd
+	s.policy.Algorithm = segment.Algorithm
+	s.policy.VMCatalogVersion = segment.VMCatalogVersion
+	for _, v := range forecast.ForecastedValues {
+		if v.TimeStamp.Before(commitEnd) {
+			s.forecast = append(s.forecast, v)
+		}
+	}
+
+	metrics, vmTypes := derivation.ComputePolicyMetrics(&s.policy.ScalingActions, s.forecast, sysConfiguration,
+		derivation.VMListToMap(vmProfiles))
+	metrics.StartTimeDerivation = s.policy.Metrics.StartTimeDerivation
+	if metrics.StartTimeDerivation.IsZero() {
+		metrics.StartTimeDerivation = segment.Metrics.StartTimeDerivation
+	}
+	metrics.FinishTimeDerivation = segment.Metrics.FinishTimeDerivation
+	metrics.DerivationDuration = util.RoundN(s.policy.Metrics.DerivationDuration+segment.Metrics.DerivationDuration, 2.0)
+	metrics.BillingBoundarySavings = util.RoundN(s.policy.Metrics.BillingBoundarySavings+segment.Metrics.BillingBoundarySavings, 2.0)
+	s.policy.Metrics = metrics
+	s.policy.Metrics.Baselines = derivation.ComputeBaselines(s.policy, s.forecast, sysConfiguration,
+		derivation.VMListToMap(vmProfiles))
+	s.policy.Parameters[types.VMTYPES] = derivation.MapKeysToString(vmTypes)
+
+	policyDAO := storage.GetPolicyDAO(sysConfiguration.MainServiceName)
+	err = policyDAO.UpsertById(s.policy.ID, s.policy)
+	if err != nil {
+		log.Error("The policy with ID = %s could not be stored. Error %s\n", s.policy.ID, err)
+	}
+
+	//Only the new scaling actions are sent to the scheduler
+	newActions := types.Policy{ScalingActions: s.policy.ScalingActions[nScheduled:]}
+	if len(newActions.ScalingActions) > 0 {
+		ScheduleScaling(sysConfiguration, newActions)
+		recordBudgetLedger(sysConfiguration, s.policy)
+	}
+	log.Info("Finish planning segment, schedule committed until %s", commitEnd)
+	return nil
+}
+
+//Derive and select the policy for the planning horizon, starting from the last committed state
+func (s *rollingSchedule) deriveSegment(forecast types.Forecast, sysConfiguration util.SystemConfiguration,
+	vmProfiles []types.VmProfile, catalogVersion int) (types.Policy, error) {
+	var candidatePolicies []types.Policy
+	var err error
+
+	derivationLock.Lock()
+	defer derivationLock.Unlock()
+
+	if n := len(s.policy.ScalingActions); n > 0 {
+		lastState := s.policy.ScalingActions[n-1].DesiredState
+		candidatePolicies, err = derivation.PoliciesFromState(vmProfiles, sysConfiguration, forecast, lastState)
+	} else {
+		candidatePolicies, err = derivation.Policies(vmProfiles, sysConfiguration, forecast)
+	}
+	if err != nil {
+		return types.Policy{}, err
+	}
+	segment, err := derivation.SelectPolicy(&candidatePolicies, sysConfiguration, vmProfiles, forecast)
+	segment.VMCatalogVersion = catalogVersion
+	return segment, err
+}
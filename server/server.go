@@ -7,13 +7,16 @@ import (
 	"github.com/Cloud-Pie/SPDT/util"
 	"github.com/Cloud-Pie/SPDT/types"
 	"time"
+	"github.com/Cloud-Pie/SPDT/monitoring"
+	"strings"
+	"strconv"
+	"github.com/Cloud-Pie/SPDT/planner/forecast_processing"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
 )
 
-var forecastChannel chan types.Forecast
-
 //Set up server routes
-func SetUpServer( fc chan types.Forecast ) *gin.Engine {
-	forecastChannel = fc
+func SetUpServer() *gin.Engine {
 	router := gin.Default()
 	//router.Static("/assets", "./ui/assets")
 	router.Static("/ui", "./ui")
@@ -21,12 +24,32 @@ func SetUpServer( fc chan types.Forecast ) *gin.Engine {
 	router.POST("/api/policies", serverCall)
 	router.GET("/ui", homeUI)
 	router.POST("/api/forecast", updateForecast)
+	router.POST("/api/forecast/updates/:id/retry", retryForecastUpdate)
 	router.GET("/api/:service/policies/:id", policyByID)
 	router.GET("/api/:service/policies", getPolicies)
 	router.DELETE("/api/:service/policies/:id", deletePolicyByID)
 	router.DELETE("/api/:service/policies", deletePolicyWindow)
 	router.PUT("/api/:service/policies/:id", invalidatePolicyByID)
 	router.GET("/api/:service/forecast", getForecast)
+	router.GET("/api/:service/forecast/updates", getForecastUpdates)
+	router.GET("/api/:service/drift", getDriftEvents)
+	router.GET("/api/:service/budget", getMonthlySpend)
+	//Not under /policies, the router does not allow /api/:service/policies/diff next to /api/:service/policies/:id
+	router.GET("/api/:service/diff", policiesDiff)
+	//The service follows the static segment, the router does not allow POST /api/:service next to POST /api/policies
+	router.POST("/api/executions/:service", executionEvent)
+	router.GET("/api/:service/policies/:id/timing", policyTiming)
+	//Derivation jobs are grouped apart, the router does not allow :service next to POST /api/policies
+	router.POST("/derivations/:service", startDerivation)
+	router.GET("/derivations/:service/:id", derivationByID)
+	router.DELETE("/derivations/:service/:id", cancelDerivation)
+	router.GET("/vms", getVMCatalog)
+	router.GET("/vms/versions", getVMCatalogVersions)
+	router.POST("/vms", postVMCatalogEntry)
+	router.POST("/vms/reload", reloadVMCatalog)
+	router.PUT("/vms/:csp/:region/:type", putVMCatalogEntry)
+	router.DELETE("/vms/:csp/:region/:type", deleteVMCatalogEntry)
+	router.GET("/metrics", gin.WrapH(monitoring.Handler()))
 
 	return router
 }
@@ -61,33 +84,32 @@ func deletePolicyByID(c *gin.Context) {
 
 // This handler retrieve information of all policies that match the query paramenters
 // The request responds to a policiesEndpoint matching:  /api/policies?start=2018-08-07T20:28:20&end=2018-08-07T20:28:20
+// Other filters: algorithm, status, min_cost, max_cost, created_after, created_before
+// Sort, pagination and projection: sort=cost,-created&limit=20&cursor=<X-Next-Cursor>&fields=id,algorithm,metrics
 func getPolicies(c *gin.Context) {
-	windowTimeStart := c.DefaultQuery("start", "")
-	windowTimeEnd := c.DefaultQuery("end","")
 	serviceName := c.Param("service")
-	policyDAO := db.GetPolicyDAO(serviceName)
-
-	policies := []types.Policy{}
-	if windowTimeStart == "" && windowTimeEnd == "" {
-		policies,_ = policyDAO.FindAll()
-
-	} else if windowTimeStart == "" && windowTimeEnd != "" {
-		time, _ := time.Parse(util.UTC_TIME_LAYOUT, windowTimeEnd)
-		policies,_ = policyDAO.FindByEndTime(time)
-
-	} else if windowTimeStart != "" && windowTimeEnd == "" {
-		time, _ := time.Parse(util.UTC_TIME_LAYOUT, windowTimeStart)
-		policies,_ = policyDAO.FindByStartTime(time)
-
-	} else if windowTimeStart != "" && windowTimeEnd != "" {
-		startTime, _ := time.Parse(util.UTC_TIME_LAYOUT, windowTimeStart)
-		endTime, _ := time.Parse(util.UTC_TIME_LAYOUT, windowTimeEnd)
-		policies,_ = policyDAO.FindAllByTimeWindow(startTime,endTime)
+	params := make(map[string]string)
+	for k,v := range c.Request.URL.Query() {
+		params[k] = strings.Join(v, ",")
+	}
+	query,err := db.ParsePolicyQuery(params)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
 
+	policyDAO := db.GetPolicyDAO(serviceName)
+	policies,nextCursor,err := policyDAO.FindByQuery(query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
 	}
 	if len(policies) == 0 {
 		policies = make([]types.Policy,0)
 	}
+	if nextCursor != "" {
+		c.Header("X-Next-Cursor", nextCursor)
+	}
 	c.JSON(http.StatusOK, policies)
 }
 
@@ -127,26 +149,141 @@ func invalidatePolicyByID(c *gin.Context) {
 	c.JSON(http.StatusOK,"Policy removed")
 }
 
+// Start a derivation job for the main service of the configuration file
 func serverCall(c *gin.Context) {
-	/*sysConfiguration,_ := util.ReadConfigFile("config.yml")
+	submitDerivation(c, sysConfiguration.MainServiceName)
+}
+
+// Start an asynchronous derivation for the service :service
+// The request body contains the horizon and optional overrides of the configuration:
+// {"start_time": "2018-11-01T07:00:00Z", "end_time": "2018-11-03T06:00:00Z", "overrides": {"preferred_algorithm": "naive"}}
+func startDerivation(c *gin.Context) {
+	submitDerivation(c, c.Param("service"))
+}
+
+func submitDerivation(c *gin.Context, serviceName string) {
+	request := DerivationRequest{}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := request.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+	job := derivationJobs.start(serviceName, request, sysConfiguration)
+	c.JSON(http.StatusAccepted, job)
+}
 
-	selectedPolicy, predictionID, err := StartPolicyDerivation(timeStart,timeEnd,sysConfiguration)
+// Retrieve progress and results of the derivation job with the correspondent :id
+func derivationByID(c *gin.Context) {
+	job, ok := derivationJobs.get(c.Param("id"))
+	if !ok || job.Service != c.Param("service") {
+		c.JSON(http.StatusNotFound, "Derivation job not found")
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
 
+// Cancel the derivation job with the correspondent :id
+func cancelDerivation(c *gin.Context) {
+	job, ok := derivationJobs.get(c.Param("id"))
+	if !ok || job.Service != c.Param("service") {
+		c.JSON(http.StatusNotFound, "Derivation job not found")
+		return
+	}
+	job, err := derivationJobs.cancelJob(job.ID)
 	if err != nil {
-		c.JSON(http.StatusOK, err.Error())
-	}else {
-		ScheduleScaling(sysConfiguration,selectedPolicy)
-		subscribeForecastingUpdates(sysConfiguration, predictionID)
-		c.JSON(http.StatusOK, testJSON)
-	}*/
+		c.JSON(http.StatusConflict, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, job)
 }
 
 //Listener to receive forecasting updates
 func updateForecast(c *gin.Context) {
 	forecast := &types.Forecast{}
-	c.Bind(forecast)
-	forecastChannel <- *forecast
-	c.JSON(http.StatusOK,testJSON)
+	if err := c.ShouldBindJSON(forecast); err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+	serviceName := forecast.ServiceName
+	if serviceName == "" {
+		serviceName = sysConfiguration.MainServiceName
+	}
+	monitoring.ForecastUpdate(serviceName, monitoring.FORECAST_UPDATE_RECEIVED)
+	if err := forecast_processing.ValidateForecast(*forecast); err != nil {
+		monitoring.ForecastUpdate(serviceName, monitoring.FORECAST_UPDATE_REJECTED)
+		c.JSON(http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	forecast.ContentHash = forecast_processing.ContentHash(*forecast)
+	if isRepeatedForecast(serviceName, *forecast) {
+		monitoring.ForecastUpdate(serviceName, monitoring.FORECAST_UPDATE_DUPLICATED)
+		c.JSON(http.StatusOK, "Forecast already received")
+		return
+	}
+	update,err := forecastUpdates.enqueue(serviceName, *forecast)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	c.JSON(http.StatusAccepted, update)
+}
+
+// This handler lists the pushed forecast updates of :service that are pending, in process or failed
+func getForecastUpdates(c *gin.Context) {
+	serviceName := c.Param("service")
+	updates,err := db.GetForecastUpdateDAO().FindByServiceName(serviceName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, updates)
+}
+
+// This handler sets back to pending the failed forecast update with the correspondent :id
+func retryForecastUpdate(c *gin.Context) {
+	id := c.Param("id")
+	if !bson.IsObjectIdHex(id) {
+		c.JSON(http.StatusBadRequest, "Invalid forecast update id")
+		return
+	}
+	update,err := forecastUpdates.requeue(bson.ObjectIdHex(id))
+	if err == mgo.ErrNotFound {
+		c.JSON(http.StatusNotFound, "No failed forecast update with id " + id)
+		return
+	} else if err != nil {
+		c.JSON(http.StatusConflict, err.Error())
+		return
+	}
+	c.JSON(http.StatusAccepted, update)
+}
+
+// This handler lists the drift events detected for :service, most recent first
+// The request responds to:  /api/:service/drift?since=2018-11-01T07:00:00Z&limit=100
+func getDriftEvents(c *gin.Context) {
+	serviceName := c.Param("service")
+	since := time.Time{}
+	if value := c.Query("since"); value != "" {
+		var err error
+		since,err = time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, "Invalid since parameter, use the format YYYY-MM-DDTHH:mm:ssZ")
+			return
+		}
+	}
+	limit,err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, "Invalid limit parameter")
+		return
+	}
+	events,err := db.GetDriftEventDAO(serviceName).FindSince(since, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, events)
 }
 
 //This handler return the home page of the user interface
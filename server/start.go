@@ -1,6 +1,7 @@
 package server
 
 import (
+	"github.com/Cloud-Pie/SPDT/monitoring"
 	Pservice "github.com/Cloud-Pie/SPDT/rest_clients/performance_profiles"
 	Sservice "github.com/Cloud-Pie/SPDT/rest_clients/scheduler"
 	"github.com/Cloud-Pie/SPDT/util"
@@ -13,24 +14,24 @@ import (
 	"path/filepath"
 	"io"
 	"gopkg.in/mgo.v2/bson"
-	"io/ioutil"
-	"encoding/json"
-	"sort"
 	"github.com/Cloud-Pie/SPDT/planner/derivation"
 	"github.com/Cloud-Pie/SPDT/planner/execution"
+	"sync"
 )
 
 var (
-	FlagsVar         = util.ParseFlags()
+	FlagsVar         util.FlagVars
  	log              = logging.MustGetLogger("spdt")
-	policies        []types.Policy
 	testJSON        []Sservice.StateToSchedule
 	sysConfiguration	util.SystemConfiguration
+	derivationLock	sync.Mutex
 )
 
 // Main function to start the scaling policy derivation
 func Start(port string, configFile string) {
 
+	FlagsVar = util.ParseFlags()
+
 	//Print Tool Name
 	styleEntry()
 
@@ -43,12 +44,22 @@ func Start(port string, configFile string) {
 	if err != nil {
 		log.Error("%s", err)
 	}
+	monitoring.SetServices(sysConfiguration.MainServiceName)
 
-	out := make(chan types.Forecast)
-	server := SetUpServer(out)
-	go updatePolicyDerivation(out)
+	server := SetUpServer()
+	forecastUpdates.start(sysConfiguration)
 	go removeTemporalData(sysConfiguration)
-	go periodicPolicyDerivation(sysConfiguration)
+	if sysConfiguration.DriftReconciliation.Enabled {
+		go reconcileDrift(sysConfiguration)
+	}
+	if sysConfiguration.ProfileRefresh.Enabled {
+		go refreshProfiles(sysConfiguration)
+	}
+	if sysConfiguration.RollingHorizon.Enabled {
+		go rollingHorizonDerivation(sysConfiguration)
+	} else {
+		go periodicPolicyDerivation(sysConfiguration)
+	}
 
 	server.Run(":" + port)
 
@@ -130,136 +141,210 @@ func setLogger() {
 	return sysConfiguration
 }*/
 
-//Fetch the profiles of the available Virtual Machines to generate the scaling policies
-func ReadVMProfiles()([]types.VmProfile, error) {
-	var err error
-	var vmProfiles	[]types.VmProfile
-	data, err := ioutil.ReadFile("./vm_profiles.json")
-	if err != nil {
-		log.Error(err.Error())
-		return vmProfiles,err
-	}
-	err = json.Unmarshal(data, &vmProfiles)
-
-	if err != nil {
-		log.Error(err.Error())
-		return vmProfiles, err
-	}
-	sort.Slice(vmProfiles, func(i, j int) bool {
-		return vmProfiles[i].Pricing.Price <=  vmProfiles[j].Pricing.Price
-	})
-
-	return vmProfiles,err
-}
-
 //Fetch the booting and shutdown time of vms
 func FetchVMBootingProfiles(sysConfiguration util.SystemConfiguration, vmProfiles []types.VmProfile) error{
 	var err error
-	var vmBootingProfile types.InstancesBootShutdownTime
 	vmBootingProfileDAO := storage.GetVMBootingProfileDAO()
 	storedVMBootingProfiles,_ := vmBootingProfileDAO.FindAll()
 	if len(storedVMBootingProfiles) == 0 {
-		log.Info("Start request VM booting Profiles")
-		endpoint := sysConfiguration.PerformanceProfilesComponent.Endpoint + util.ENDPOINT_ALL_VM_TIMES
-		csp := sysConfiguration.CSP
-		region := sysConfiguration.Region
-		for _, vm := range vmProfiles {
-			vmBootingProfile, err = Pservice.GetAllBootShutDownProfilesByType(endpoint, vm.Type, region, csp)
-			if err != nil {
-				log.Error("Error in request VM Booting Profile for type %s. %s",vm.Type, err.Error())
-			}
-			vmBootingProfile.VMType = vm.Type
+		var vmBootingProfiles []types.InstancesBootShutdownTime
+		vmBootingProfiles, err = fetchVMBootingProfiles(sysConfiguration, vmProfiles)
+		for _, vmBootingProfile := range vmBootingProfiles {
 			vmBootingProfileDAO.Insert(vmBootingProfile)
 		}
-		log.Info("Finish request VM booting Profiles")
 	}
 	return err
 }
 
+/* Request the booting and shutdown times of the vm types to the performance profiles component
+	in:
+		@sysConfiguration util.SystemConfiguration
+		@vmProfiles []types.VmProfile
+	out:
+		@[]types.InstancesBootShutdownTime	- Profiles of the types successfully requested
+		@error	- Last error found
+*/
+func fetchVMBootingProfiles(sysConfiguration util.SystemConfiguration, vmProfiles []types.VmProfile) ([]types.InstancesBootShutdownTime, error) {
+	var err error
+	vmBootingProfiles := []types.InstancesBootShutdownTime{}
+	log.Info("Start request VM booting Profiles")
+	endpoint := sysConfiguration.PerformanceProfilesComponent.Endpoint + util.ENDPOINT_ALL_VM_TIMES
+	csp := sysConfiguration.CSP
+	region := sysConfiguration.Region
+	for _, vm := range vmProfiles {
+		vmBootingProfile, errType := Pservice.GetAllBootShutDownProfilesByType(endpoint, vm.Type, region, csp)
+		if errType != nil {
+			log.Error("Error in request VM Booting Profile for type %s. %s",vm.Type, errType.Error())
+			err = errType
+			continue
+		}
+		vmBootingProfile.VMType = vm.Type
+		vmBootingProfile.FetchedAt = time.Now()
+		vmBootingProfile.Source = types.PROFILE_SOURCE_PROFILER
+		vmBootingProfiles = append(vmBootingProfiles, vmBootingProfile)
+	}
+	log.Info("Finish request VM booting Profiles")
+	return vmBootingProfiles, err
+}
+
 //Fetch the performance profile of the microservice that should be scaled
 func FetchApplicationProfile(sysConfiguration util.SystemConfiguration) error {
 	var err error
-	var servicePerformanceProfile types.ServicePerformanceProfile
 	serviceProfileDAO := storage.GetPerformanceProfileDAO(sysConfiguration.MainServiceName)
 	storedPerformanceProfiles,_ := serviceProfileDAO.FindAll()
 	if len(storedPerformanceProfiles) == 0 {
-
-		log.Info("Start request Performance Profiles")
-		endpoint := sysConfiguration.PerformanceProfilesComponent.Endpoint + util.ENDPOINT_SERVICE_PROFILES
-		servicePerformanceProfile, err = Pservice.GetServicePerformanceProfiles(endpoint,sysConfiguration.AppName,
-																sysConfiguration.AppType, sysConfiguration.MainServiceName)
-
-		if err != nil {
-			log.Error("Error in request Performance Profiles: %s",err.Error())
-		} else {
-			log.Info("Finish request Performance Profiles")
+		var performanceProfiles []types.PerformanceProfile
+		performanceProfiles, err = fetchPerformanceProfiles(sysConfiguration)
+		for _, performanceProfile := range performanceProfiles {
+			errInsert := serviceProfileDAO.Insert(performanceProfile)
+			if errInsert != nil {
+				log.Error("Error Storing Performance Profiles: %s",errInsert.Error())
+				err = errInsert
+			}
 		}
+	}
+	return err
+}
 
-		//Selects and stores received information about Performance Profiles
-		for _,p := range servicePerformanceProfile.Profiles {
-			mscSettings := []types.MSCSimpleSetting{}
-			for _,msc := range p.MSCs {
-				setting := types.MSCSimpleSetting{
-					BootTimeSec: util.MillisecondsToSeconds(msc.BootTimeMs),
-					MSCPerSecond: msc.MSCPerSecond.RegBruteForce,
-					Replicas: msc.Replicas,
-					StandDevBootTimeSec: util.MillisecondsToSeconds(msc.StandDevBootTimeMS),
-				}
-				mscSettings = append(mscSettings, setting)
-			}
-			performanceProfile := types.PerformanceProfile {
-				ID: bson.NewObjectId(),Limit: p.Limits, MSCSettings: mscSettings,
-			}
-			err = serviceProfileDAO.Insert(performanceProfile)
-			if err != nil {
-				log.Error("Error Storing Performance Profiles: %s",err.Error())
+/* Request the performance profiles of the main service to the performance profiles component
+	in:
+		@sysConfiguration util.SystemConfiguration
+	out:
+		@[]types.PerformanceProfile
+		@error
+*/
+func fetchPerformanceProfiles(sysConfiguration util.SystemConfiguration) ([]types.PerformanceProfile, error) {
+	performanceProfiles := []types.PerformanceProfile{}
+	log.Info("Start request Performance Profiles")
+	endpoint := sysConfiguration.PerformanceProfilesComponent.Endpoint + util.ENDPOINT_SERVICE_PROFILES
+	servicePerformanceProfile, err := Pservice.GetServicePerformanceProfiles(endpoint,sysConfiguration.AppName,
+															sysConfiguration.AppType, sysConfiguration.MainServiceName)
+	if err != nil {
+		log.Error("Error in request Performance Profiles: %s",err.Error())
+		return performanceProfiles, err
+	}
+	log.Info("Finish request Performance Profiles")
+
+	//Selects the received information about Performance Profiles
+	fetchedAt := time.Now()
+	for _,p := range servicePerformanceProfile.Profiles {
+		mscSettings := []types.MSCSimpleSetting{}
+		for _,msc := range p.MSCs {
+			setting := types.MSCSimpleSetting{
+				BootTimeSec: util.MillisecondsToSeconds(msc.BootTimeMs),
+				MSCPerSecond: msc.MSCPerSecond.RegBruteForce,
+				Replicas: msc.Replicas,
+				StandDevBootTimeSec: util.MillisecondsToSeconds(msc.StandDevBootTimeMS),
 			}
+			mscSettings = append(mscSettings, setting)
 		}
+		performanceProfile := types.PerformanceProfile {
+			ID: bson.NewObjectId(),Limit: p.Limits, MSCSettings: mscSettings,
+			FetchedAt: fetchedAt, Source: types.PROFILE_SOURCE_PROFILER,
+		}
+		performanceProfiles = append(performanceProfiles, performanceProfile)
 	}
-	return err
+	return performanceProfiles, nil
 }
 
 //Start Derivation of a new scaling policy for the specified scaling horizon and correspondent forecast
-func setNewPolicy(forecast types.Forecast,sysConfiguration util.SystemConfiguration, vmProfiles [] types.VmProfile) (types.Policy, error){
-	//timeStart := forecast.TimeWindowStart
-	//timeEnd := forecast.TimeWindowEnd
+func setNewPolicy(forecast types.Forecast,sysConfiguration util.SystemConfiguration, vmProfiles [] types.VmProfile,
+	catalogVersion int) (types.Policy, error){
+	selectedPolicy, _, err := deriveNewPolicies(forecast, sysConfiguration, vmProfiles, catalogVersion)
+	return selectedPolicy, err
+}
+
+/* Derive, evaluate and store the candidate policies for a forecast
+	in:
+		@forecast types.Forecast
+		@sysConfiguration util.SystemConfiguration
+		@vmProfiles []types.VmProfile
+		@catalogVersion int	- Version of the VM catalog from which vmProfiles were read
+	out:
+		@types.Policy	- Selected policy
+		@[]types.Policy	- All candidate policies
+		@error
+*/
+func deriveNewPolicies(forecast types.Forecast,sysConfiguration util.SystemConfiguration, vmProfiles [] types.VmProfile,
+	catalogVersion int) (types.Policy, []types.Policy, error){
+	selectedPolicy, candidatePolicies, err := derivePolicies(forecast, sysConfiguration, vmProfiles, catalogVersion)
+	if err == nil {
+		err = storePolicies(candidatePolicies, sysConfiguration)
+	}
+	return selectedPolicy, candidatePolicies, err
+}
 
-	//Get VM Profiles
+/* Derive and evaluate the candidate policies for a forecast without storing them
+	The derivation package keeps the configuration in package state, then derivations are serialized
+	in:
+		@forecast types.Forecast
+		@sysConfiguration util.SystemConfiguration
+		@vmProfiles []types.VmProfile
+		@catalogVersion int	- Version of the VM catalog from which vmProfiles were read
+	out:
+		@types.Policy	- Selected policy
+		@[]types.Policy	- All candidate policies
+		@error
+*/
+func derivePolicies(forecast types.Forecast,sysConfiguration util.SystemConfiguration, vmProfiles [] types.VmProfile,
+	catalogVersion int) (types.Policy, []types.Policy, error){
 	var err error
 	var selectedPolicy types.Policy
+	var candidatePolicies []types.Policy
 
+	derivationLock.Lock()
+	defer derivationLock.Unlock()
 
 	//Derive Strategies
 	log.Info("Start policies derivation")
-	policies,err = derivation.Policies(vmProfiles, sysConfiguration, forecast)
+	candidatePolicies,err = derivation.Policies(vmProfiles, sysConfiguration, forecast)
 	if err != nil {
-		return selectedPolicy, err
+		return selectedPolicy, candidatePolicies, err
 	}
 	log.Info("Finish policies derivation")
 
 	log.Info("Start policies evaluation")
-	//var err error
-	selectedPolicy,err = derivation.SelectPolicy(&policies, sysConfiguration, vmProfiles, forecast)
+	selectedPolicy,err = derivation.SelectPolicy(&candidatePolicies, sysConfiguration, vmProfiles, forecast)
 	if err != nil {
 		log.Error("Error evaluation policies: %s", err.Error())
 	}else {
 		log.Info("Finish policies evaluation")
 
-		policyDAO := storage.GetPolicyDAO(sysConfiguration.MainServiceName)
-		for _,p := range policies {
-			err = policyDAO.Insert(p)
-			if err != nil {
-				log.Error("The policy with ID = %s could not be stored. Error %s\n", p.ID, err)
+		selectedPolicy.VMCatalogVersion = catalogVersion
+		derivation.ComputeCandidatesBaselines(candidatePolicies, forecast.ForecastedValues, sysConfiguration,
+			derivation.VMListToMap(vmProfiles))
+		for i := range candidatePolicies {
+			candidatePolicies[i].VMCatalogVersion = catalogVersion
+			if candidatePolicies[i].ID == selectedPolicy.ID {
+				selectedPolicy.Metrics.Baselines = candidatePolicies[i].Metrics.Baselines
+			}
+		}
+	}
+	return  selectedPolicy, candidatePolicies, err
+}
+
+//Store the candidate policies of a derivation. It returns the first error, the remaining policies are still stored
+func storePolicies(candidatePolicies []types.Policy, sysConfiguration util.SystemConfiguration) error {
+	var firstErr error
+	policyDAO := storage.GetPolicyDAO(sysConfiguration.MainServiceName)
+	for _,p := range candidatePolicies {
+		err := policyDAO.Insert(p)
+		if err != nil {
+			log.Error("The policy with ID = %s could not be stored. Error %s\n", p.ID, err)
+			if firstErr == nil {
+				firstErr = err
 			}
 		}
 	}
-	return  selectedPolicy, err
+	return firstErr
 }
 
 func ScheduleScaling(sysConfiguration util.SystemConfiguration, selectedPolicy types.Policy) {
 	log.Info("Start request Scheduler")
 	schedulerURL := sysConfiguration.SchedulerComponent.Endpoint + util.ENDPOINT_STATES
-	tset,err := execution.TriggerScheduler(selectedPolicy, schedulerURL)
+	callbackURL := util.ParseURL(sysConfiguration.Host + util.ENDPOINT_EXECUTION_EVENTS,
+		map[string]string{"service": sysConfiguration.MainServiceName})
+	tset,err := execution.TriggerScheduler(selectedPolicy, schedulerURL, callbackURL)
 	testJSON = tset
 	if err != nil {
 		log.Error("The scheduler request failed with error %s\n", err)
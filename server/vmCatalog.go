@@ -0,0 +1,256 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Cloud-Pie/SPDT/planner/catalog_processing"
+	"github.com/Cloud-Pie/SPDT/storage"
+	"github.com/Cloud-Pie/SPDT/types"
+	"github.com/Cloud-Pie/SPDT/util"
+	"github.com/gin-gonic/gin"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+//Sources of the catalog versions
+const (
+	VM_CATALOG_SOURCE_API = "api"
+	VM_CATALOG_SOURCE_CLI = "cli"
+)
+
+/* Read the VM types available in the provider and region of the configuration from the latest version of the VM catalog.
+	The catalog is loaded from the VM catalog file the first time. Disallowed types are excluded and prices are normalized per hour
+	in:
+		@sysConfiguration util.SystemConfiguration
+	out:
+		@[]types.VmProfile	- VM types sorted by price
+		@int	- Version of the catalog from which the VM types were read, recorded in the derived policies
+		@error
+*/
+func ReadVMProfiles(sysConfiguration util.SystemConfiguration)([]types.VmProfile, int, error) {
+	var vmProfiles	[]types.VmProfile
+	catalogVersion, err := loadVMCatalog(sysConfiguration)
+	if err != nil {
+		log.Error(err.Error())
+		return vmProfiles, 0, err
+	}
+	catalog := catalog_processing.UsableCatalog(catalogVersion.Entries)
+	vmProfiles, err = catalog_processing.SelectVMProfiles(catalog, sysConfiguration.CSP, sysConfiguration.Region)
+	if err != nil {
+		log.Error(err.Error())
+	}
+	return vmProfiles, catalogVersion.Version, err
+}
+
+//Retrieve the latest version of the catalog, the configured file is stored as first version if there is none
+func loadVMCatalog(sysConfiguration util.SystemConfiguration) (types.VMCatalogVersion, error) {
+	catalogVersion, err := storage.GetVMCatalogDAO().FindLatest()
+	if err == mgo.ErrNotFound {
+		catalogVersion, _, err = ReloadVMCatalog(sysConfiguration, "")
+	}
+	return catalogVersion, err
+}
+
+/* Reload the catalog from a file and store it as a new version.
+	The allowed and disallowed flags of the current version are kept. No version is created if nothing changed
+	in:
+		@sysConfiguration util.SystemConfiguration
+		@file string	- Path of the file, default the vm-catalog-file of the configuration
+	out:
+		@types.VMCatalogVersion	- Latest version
+		@bool	- If a new version was created
+		@error	- If the file can not be read or has invalid VM types
+*/
+func ReloadVMCatalog(sysConfiguration util.SystemConfiguration, file string) (types.VMCatalogVersion, bool, error) {
+	if file == "" {
+		file = sysConfiguration.VMCatalogFile
+	}
+	if file == "" {
+		file = util.DEFAULT_VM_CATALOG_FILE
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return types.VMCatalogVersion{}, false, err
+	}
+	catalog, err := catalog_processing.ParseCatalog(data, sysConfiguration.CSP, sysConfiguration.Region)
+	if err != nil {
+		return types.VMCatalogVersion{}, false, fmt.Errorf("%s: %s", file, err.Error())
+	}
+	reloaded := catalog_processing.CatalogEntries(catalog)
+	return UpdateVMCatalog(file, func(entries []types.VMCatalogEntry) ([]types.VMCatalogEntry, error) {
+		return catalog_processing.KeepEntryFlags(entries, reloaded), nil
+	})
+}
+
+/* Apply a change to the latest version of the catalog and store the result as a new version.
+	Changes are serialized, so concurrent changes are not lost
+	in:
+		@source string	- File or operation that changes the catalog
+		@change func([]types.VMCatalogEntry) ([]types.VMCatalogEntry, error)	- Receives a copy of the current entries
+	out:
+		@types.VMCatalogVersion	- Latest version
+		@bool	- If a new version was created
+		@error	- If the change fails or the result is not a valid catalog
+*/
+func UpdateVMCatalog(source string, change func([]types.VMCatalogEntry) ([]types.VMCatalogEntry, error)) (types.VMCatalogVersion, bool, error) {
+	derivationLock.Lock()
+	defer derivationLock.Unlock()
+
+	catalogDAO := storage.GetVMCatalogDAO()
+	current, err := catalogDAO.FindLatest()
+	if err != nil && err != mgo.ErrNotFound {
+		return current, false, err
+	}
+	entries, err := change(append([]types.VMCatalogEntry{}, current.Entries...))
+	if err != nil {
+		return current, false, err
+	}
+	if err = catalog_processing.ValidateEntries(entries); err != nil {
+		return current, false, err
+	}
+	if current.Version > 0 && catalog_processing.SameEntries(current.Entries, entries) {
+		return current, false, nil
+	}
+	catalogVersion := types.VMCatalogVersion{
+		ID:        bson.NewObjectId(),
+		Version:   current.Version + 1,
+		CreatedAt: time.Now(),
+		Source:    source,
+		Entries:   entries,
+	}
+	if err = catalogDAO.Insert(catalogVersion); err != nil {
+		return current, false, err
+	}
+	log.Info("VM catalog version %d created from %s with %d VM types", catalogVersion.Version, source, len(entries))
+	return catalogVersion, true, nil
+}
+
+/* Change of the catalog that adds or replaces the entry of a VM type
+	in:
+		@entry types.VMCatalogEntry
+		@create bool	- Fails if the VM type already exists, otherwise fails if it does not exist
+	out:
+		@func([]types.VMCatalogEntry) ([]types.VMCatalogEntry, error)
+*/
+func PutVMCatalogEntry(entry types.VMCatalogEntry, create bool) func([]types.VMCatalogEntry) ([]types.VMCatalogEntry, error) {
+	return func(entries []types.VMCatalogEntry) ([]types.VMCatalogEntry, error) {
+		i := catalog_processing.FindEntry(entries, entry.CSP, entry.Region, entry.VmProfile.Type)
+		switch {
+		case create && i >= 0:
+			return entries, errors.New("VM type " + entry.VmProfile.Type + " already exists")
+		case create:
+			return append(entries, entry), nil
+		case i < 0:
+			return entries, errors.New("VM type " + entry.VmProfile.Type + " not found")
+		}
+		entries[i] = entry
+		return entries, nil
+	}
+}
+
+//Change of the catalog that removes the entry of a VM type
+func DeleteVMCatalogEntry(csp string, region string, vmType string) func([]types.VMCatalogEntry) ([]types.VMCatalogEntry, error) {
+	return func(entries []types.VMCatalogEntry) ([]types.VMCatalogEntry, error) {
+		i := catalog_processing.FindEntry(entries, csp, region, vmType)
+		if i < 0 {
+			return entries, errors.New("VM type " + vmType + " not found")
+		}
+		return append(entries[:i], entries[i+1:]...), nil
+	}
+}
+
+// This handler lists the VM types of the latest catalog version, or of ?version=
+// The types can be filtered with ?csp= and ?region=
+func getVMCatalog(c *gin.Context) {
+	var catalogVersion types.VMCatalogVersion
+	var err error
+	if v := c.Query("version"); v != "" {
+		version, errParse := strconv.Atoi(v)
+		if errParse != nil {
+			c.JSON(http.StatusBadRequest, "Invalid version parameter")
+			return
+		}
+		catalogVersion, err = storage.GetVMCatalogDAO().FindByVersion(version)
+	} else {
+		catalogVersion, err = loadVMCatalog(sysConfiguration)
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, err.Error())
+		return
+	}
+	csp, region := c.Query("csp"), c.Query("region")
+	entries := []types.VMCatalogEntry{}
+	for _, e := range catalogVersion.Entries {
+		if (csp == "" || e.CSP == csp) && (region == "" || e.Region == region) {
+			entries = append(entries, e)
+		}
+	}
+	catalogVersion.Entries = entries
+	c.JSON(http.StatusOK, catalogVersion)
+}
+
+// This handler lists the versions of the catalog, the most recent first
+func getVMCatalogVersions(c *gin.Context) {
+	catalogVersions, err := storage.GetVMCatalogDAO().FindVersions()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, catalogVersions)
+}
+
+// This handler adds a VM type to the catalog, the body is a catalog entry:
+// {"csp": "AWS", "region": "us-east-2", "vm": {"type": "t2.large", ...}, "disallowed": false}
+func postVMCatalogEntry(c *gin.Context) {
+	entry := types.VMCatalogEntry{}
+	if err := c.ShouldBindJSON(&entry); err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+	respondVMCatalogUpdate(c, PutVMCatalogEntry(entry, true))
+}
+
+// This handler replaces the VM type /vms/:csp/:region/:type
+func putVMCatalogEntry(c *gin.Context) {
+	entry := types.VMCatalogEntry{}
+	if err := c.ShouldBindJSON(&entry); err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+	entry.CSP, entry.Region, entry.VmProfile.Type = c.Param("csp"), c.Param("region"), c.Param("type")
+	respondVMCatalogUpdate(c, PutVMCatalogEntry(entry, false))
+}
+
+// This handler removes the VM type /vms/:csp/:region/:type
+func deleteVMCatalogEntry(c *gin.Context) {
+	respondVMCatalogUpdate(c, DeleteVMCatalogEntry(c.Param("csp"), c.Param("region"), c.Param("type")))
+}
+
+// This handler reloads the catalog from the vm-catalog-file of the configuration
+func reloadVMCatalog(c *gin.Context) {
+	catalogVersion, created, err := ReloadVMCatalog(sysConfiguration, "")
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	catalogVersion.Entries = nil
+	if !created {
+		c.JSON(http.StatusOK, catalogVersion)
+		return
+	}
+	c.JSON(http.StatusCreated, catalogVersion)
+}
+
+func respondVMCatalogUpdate(c *gin.Context, change func([]types.VMCatalogEntry) ([]types.VMCatalogEntry, error)) {
+	catalogVersion, _, err := UpdateVMCatalog(VM_CATALOG_SOURCE_API, change)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	catalogVersion.Entries = nil
+	c.JSON(http.StatusOK, catalogVersion)
+}
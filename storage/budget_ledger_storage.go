@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"gopkg.in/mgo.v2"
+	"github.com/Cloud-Pie/SPDT/types"
+	"gopkg.in/mgo.v2/bson"
+	"time"
+	"os"
+)
+
+type BudgetLedgerDAO struct {
+	Server	string
+	Database	string
+	Collection  string
+	db *mgo.Database
+	session *mgo.Session
+}
+
+var BudgetLedgerDB *BudgetLedgerDAO
+
+const DEFAULT_DB_COLLECTION_BUDGET_LEDGER = "BudgetLedger"
+
+//Connect to the database
+func (p *BudgetLedgerDAO) Connect() (*mgo.Database, error) {
+	var err error
+
+	if p.session == nil {
+		p.session,  err = mgo.DialWithInfo(&mgo.DialInfo{
+			Addrs: policyDBHost,
+			Username: os.Getenv("POLICIESDB_USER"),
+			Password: os.Getenv("POLICIESDB_PASS"),
+			Timeout:  60 * time.Second,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	p.session = p.session.Clone()
+	p.db = p.session.DB(p.Database)
+	return p.db,err
+}
+
+/* Replace the ledger entries of a policy and of the scheduled time it replaces.
+	Entries of other policies that overlap the window are trimmed to the time outside of it
+	in:
+		@policyID string
+		@timeStart time.Time	- Start of the time window of the policy
+		@timeEnd time.Time	- End of the time window of the policy
+		@entries []types.BudgetLedgerEntry	- New entries of the policy
+	out:
+		@error
+*/
+func (p *BudgetLedgerDAO) ReplacePolicy(policyID string, timeStart time.Time, timeEnd time.Time,
+	entries []types.BudgetLedgerEntry) error {
+	overlapping := bson.M{"time_start": bson.M{"$lt": timeEnd}, "time_end": bson.M{"$gt": timeStart}}
+	var trimmed []types.BudgetLedgerEntry
+	var overlapped []types.BudgetLedgerEntry
+	err := p.db.C(p.Collection).Find(overlapping).All(&overlapped)
+	if err != nil {
+		return err
+	}
+	for _,e := range overlapped {
+		if e.PolicyID != policyID {
+			trimmed = append(trimmed, trimEntry(e, timeStart, timeEnd)...)
+		}
+	}
+	_,err = p.db.C(p.Collection).RemoveAll(bson.M{"$or": []bson.M{{"policy_id": policyID}, overlapping}})
+	if err != nil {
+		return err
+	}
+	for _,e := range append(trimmed, entries...) {
+		err = p.db.C(p.Collection).Insert(&e)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//Remove the ledger entries of a policy
+func (p *BudgetLedgerDAO) RemovePolicy(policyID string) error {
+	_,err := p.db.C(p.Collection).RemoveAll(bson.M{"policy_id": policyID})
+	return err
+}
+
+/* Keep the parts of a ledger entry outside a time window, with the cost prorated by their duration
+	in:
+		@entry types.BudgetLedgerEntry
+		@timeStart time.Time
+		@timeEnd time.Time
+	out:
+		@[]types.BudgetLedgerEntry	- Empty if the window covers the entry
+*/
+func trimEntry(entry types.BudgetLedgerEntry, timeStart time.Time, timeEnd time.Time) []types.BudgetLedgerEntry {
+	var parts []types.BudgetLedgerEntry
+	duration := entry.TimeEnd.Sub(entry.TimeStart).Seconds()
+	if duration <= 0 {
+		return parts
+	}
+	part := func(from time.Time, to time.Time) types.BudgetLedgerEntry {
+		trimmed := entry
+		trimmed.TimeStart = from
+		trimmed.TimeEnd = to
+		trimmed.Cost = entry.Cost * to.Sub(from).Seconds() / duration
+		return trimmed
+	}
+	if entry.TimeStart.Before(timeStart) {
+		parts = append(parts, part(entry.TimeStart, timeStart))
+	}
+	if entry.TimeEnd.After(timeEnd) {
+		after := part(timeEnd, entry.TimeEnd)
+		if len(parts) > 0 {
+			after.ID = bson.NewObjectId()
+		}
+		parts = append(parts, after)
+	}
+	return parts
+}
+
+//Retrieve the ledger entries of the months between two months, both included
+func (p *BudgetLedgerDAO) FindByMonths(from string, to string) ([]types.BudgetLedgerEntry, error) {
+	entries := []types.BudgetLedgerEntry{}
+	err := p.db.C(p.Collection).
+		Find(bson.M{"month": bson.M{"$gte":from, "$lte":to}}).Sort("time_start").All(&entries)
+	return entries,err
+}
+
+func GetBudgetLedgerDAO(serviceName string) *BudgetLedgerDAO{
+	if BudgetLedgerDB == nil || BudgetLedgerDB.Collection != DEFAULT_DB_COLLECTION_BUDGET_LEDGER + "_" + serviceName {
+		BudgetLedgerDB = &BudgetLedgerDAO {
+			Database:DEFAULT_DB_POLICIES,
+			Collection:DEFAULT_DB_COLLECTION_BUDGET_LEDGER + "_" + serviceName,
+		}
+		_,err := BudgetLedgerDB.Connect()
+		if err != nil {
+			log.Error(err.Error())
+		}
+	}
+	return BudgetLedgerDB
+}
@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"github.com/Cloud-Pie/SPDT/types"
+	"testing"
+	"time"
+)
+
+func TestTrimEntry(t *testing.T) {
+	start := time.Date(2018, 11, 1, 0, 0, 0, 0, time.UTC)
+	hour := func(h int) time.Time { return start.Add(time.Duration(h) * time.Hour) }
+	entry := types.BudgetLedgerEntry{PolicyID: "old", Month: "2018-11", TimeStart: hour(2), TimeEnd: hour(6), Cost: 8}
+
+	var tests = []struct {
+		name      string
+		timeStart time.Time
+		timeEnd   time.Time
+		parts     [][2]time.Time
+		costs     []float64
+	}{
+		{"Window covers the entry", hour(0), hour(8), nil, nil},
+		{"Window overlaps the end", hour(4), hour(8), [][2]time.Time{{hour(2), hour(4)}}, []float64{4}},
+		{"Window overlaps the start", hour(0), hour(3), [][2]time.Time{{hour(3), hour(6)}}, []float64{6}},
+		{"Window inside the entry", hour(3), hour(4), [][2]time.Time{{hour(2), hour(3)}, {hour(4), hour(6)}},
+			[]float64{2, 4}},
+	}
+	for _, test := range tests {
+		parts := trimEntry(entry, test.timeStart, test.timeEnd)
+		if len(parts) != len(test.parts) {
+			t.Error(
+				"For: ", test.name,
+				"expected: ", test.parts,
+				"got: ", parts,
+			)
+			continue
+		}
+		for i, p := range parts {
+			if !p.TimeStart.Equal(test.parts[i][0]) || !p.TimeEnd.Equal(test.parts[i][1]) || p.Cost != test.costs[i] ||
+				p.PolicyID != entry.PolicyID || p.Month != entry.Month {
+				t.Error(
+					"For: ", test.name,
+					"expected: ", test.parts[i], test.costs[i],
+					"got: ", p.TimeStart, p.TimeEnd, p.Cost,
+				)
+			}
+		}
+	}
+
+	//A split entry is stored as two documents
+	parts := trimEntry(entry, hour(3), hour(4))
+	if parts[0].ID == parts[1].ID {
+		t.Error(
+			"For: ", "split entry",
+			"expected: ", "different ids",
+			"got: ", parts[0].ID, parts[1].ID,
+		)
+	}
+}
@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"gopkg.in/mgo.v2"
+	"github.com/Cloud-Pie/SPDT/types"
+	"gopkg.in/mgo.v2/bson"
+	"time"
+	"os"
+)
+
+type DriftEventDAO struct {
+	Server	string
+	Database	string
+	Collection  string
+	db *mgo.Database
+	session *mgo.Session
+}
+
+var DriftEventDB *DriftEventDAO
+
+const DEFAULT_DB_COLLECTION_DRIFT = "DriftEvents"
+
+//Connect to the database
+func (p *DriftEventDAO) Connect() (*mgo.Database, error) {
+	var err error
+
+	if p.session == nil {
+		p.session,  err = mgo.DialWithInfo(&mgo.DialInfo{
+			Addrs: policyDBHost,
+			Username: os.Getenv("POLICIESDB_USER"),
+			Password: os.Getenv("POLICIESDB_PASS"),
+			Timeout:  60 * time.Second,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	p.session = p.session.Clone()
+	p.db = p.session.DB(p.Database)
+	return p.db,err
+}
+
+//Insert a new drift event
+func (p *DriftEventDAO) Insert(event types.DriftEvent) error {
+	err := p.db.C(p.Collection).Insert(&event)
+	return err
+}
+
+//Retrieve the most recent drift events detected after a timestamp
+func (p *DriftEventDAO) FindSince(since time.Time, limit int) ([]types.DriftEvent, error) {
+	events := []types.DriftEvent{}
+	err := p.db.C(p.Collection).
+		Find(bson.M{"detected_at": bson.M{"$gte":since}}).Sort("-detected_at").Limit(limit).All(&events)
+	return events,err
+}
+
+func GetDriftEventDAO(serviceName string) *DriftEventDAO{
+	if DriftEventDB == nil || DriftEventDB.Collection != DEFAULT_DB_COLLECTION_DRIFT + "_" + serviceName {
+		DriftEventDB = &DriftEventDAO {
+			Database:DEFAULT_DB_POLICIES,
+			Collection:DEFAULT_DB_COLLECTION_DRIFT + "_" + serviceName,
+		}
+		_,err := DriftEventDB.Connect()
+		if err != nil {
+			log.Error(err.Error())
+		}
+	}
+	return DriftEventDB
+}
@@ -0,0 +1,211 @@
+package storage
+
+import (
+	"gopkg.in/mgo.v2"
+	"github.com/Cloud-Pie/SPDT/types"
+	"gopkg.in/mgo.v2/bson"
+	"time"
+	"os"
+	"errors"
+)
+
+type ForecastUpdateDAO struct {
+	Server	string
+	Database	string
+	Collection  string
+	db *mgo.Database
+	session *mgo.Session
+}
+
+var ForecastUpdateDB *ForecastUpdateDAO
+
+const DEFAULT_DB_COLLECTION_FORECAST_UPDATES = "ForecastUpdates"
+
+//Connect to the database
+func (p *ForecastUpdateDAO) Connect() (*mgo.Database, error) {
+	var err error
+
+	if p.session == nil {
+		p.session,  err = mgo.DialWithInfo(&mgo.DialInfo{
+			Addrs: forecastDBHost,
+			Username: os.Getenv("FORECASTDB_USER"),
+			Password: os.Getenv("FORECASTDB_PASS"),
+			Timeout:  60 * time.Second,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	p.session = p.session.Clone()
+	p.db = p.session.DB(p.Database)
+	//Only pending updates have a window key, then there is at most one pending update per window
+	p.db.C(p.Collection).EnsureIndex(mgo.Index{Key: []string{"pending_window"}, Unique: true, Sparse: true})
+	return p.db,err
+}
+
+//Key of a pending update, unique for the service and time window
+func pendingWindow(serviceName string, timeStart time.Time, timeEnd time.Time) string {
+	return serviceName + "|" + timeStart.UTC().Format(time.RFC3339Nano) + "|" + timeEnd.UTC().Format(time.RFC3339Nano)
+}
+
+/* Store a forecast update. If there is a pending update for the same service and time window,
+	its forecast is replaced by the new one
+	in:
+		@serviceName string
+		@forecast types.Forecast
+	out:
+		@types.ForecastUpdate	- Pending update
+		@error
+*/
+func (p *ForecastUpdateDAO) Enqueue(serviceName string, forecast types.Forecast) (types.ForecastUpdate, error) {
+	var update types.ForecastUpdate
+	now := time.Now()
+	change := mgo.Change{
+		Update: bson.M{
+			"$set": bson.M{"forecast": forecast, "updated_at": now},
+			"$setOnInsert": bson.M{"received_at": now},
+			"$inc": bson.M{"received": 1},
+		},
+		Upsert: true,
+		ReturnNew: true,
+	}
+	query := bson.M{
+		"service_name": serviceName,
+		"start_time": forecast.TimeWindowStart,
+		"end_time": forecast.TimeWindowEnd,
+		"status": types.UPDATE_PENDING,
+		"pending_window": pendingWindow(serviceName, forecast.TimeWindowStart, forecast.TimeWindowEnd),
+	}
+	_,err := p.db.C(p.Collection).Find(query).Apply(change, &update)
+	if mgo.IsDup(err) {
+		//A concurrent request inserted the pending update first
+		_,err = p.db.C(p.Collection).Find(query).Apply(change, &update)
+	}
+	return update, err
+}
+
+/* Mark the oldest pending update as processing
+	in:
+		@busy []types.ForecastUpdate	- Updates in process, pending updates for their windows are skipped
+	out:
+		@types.ForecastUpdate
+		@error	- mgo.ErrNotFound if there are not pending updates
+*/
+func (p *ForecastUpdateDAO) ClaimNext(busy []types.ForecastUpdate) (types.ForecastUpdate, error) {
+	var update types.ForecastUpdate
+	query := bson.M{"status": types.UPDATE_PENDING}
+	if len(busy) > 0 {
+		windows := []bson.M{}
+		for _,u := range busy {
+			windows = append(windows, bson.M{"service_name": u.ServiceName, "start_time": u.TimeWindowStart,
+				"end_time": u.TimeWindowEnd})
+		}
+		query["$nor"] = windows
+	}
+	change := mgo.Change{
+		Update: bson.M{"$set": bson.M{"status": types.UPDATE_PROCESSING, "started_at": time.Now()},
+			"$unset": bson.M{"pending_window": ""}},
+		ReturnNew: true,
+	}
+	_,err := p.db.C(p.Collection).Find(query).Sort("received_at").Apply(change, &update)
+	return update, err
+}
+
+//Remove a processed update
+func (p *ForecastUpdateDAO) DeleteById(id bson.ObjectId) error {
+	err := p.db.C(p.Collection).RemoveId(id)
+	return err
+}
+
+//Keep a failed update with the error
+func (p *ForecastUpdateDAO) MarkFailed(id bson.ObjectId, reason string) error {
+	err := p.db.C(p.Collection).UpdateId(id, bson.M{"$set": bson.M{"status": types.UPDATE_FAILED, "error": reason}})
+	return err
+}
+
+/* Set back to pending the updates that were in process when the service stopped, and add the window key
+	to pending updates stored without it. An update is removed if a newer one for the same window is pending
+*/
+func (p *ForecastUpdateDAO) ResetProcessing() error {
+	var updates []types.ForecastUpdate
+	err := p.db.C(p.Collection).Find(bson.M{"$or": []bson.M{
+		{"status": types.UPDATE_PROCESSING},
+		{"status": types.UPDATE_PENDING, "pending_window": bson.M{"$exists": false}},
+	}}).Sort("-received_at").All(&updates)
+	if err != nil {
+		return err
+	}
+	for _,u := range updates {
+		err = p.setPending(u)
+		if mgo.IsDup(err) {
+			err = p.db.C(p.Collection).RemoveId(u.ID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/* Set back to pending a failed update, which is then processed again
+	in:
+		@id bson.ObjectId
+	out:
+		@types.ForecastUpdate
+		@error	- mgo.ErrNotFound if there is no failed update with the id
+*/
+func (p *ForecastUpdateDAO) Requeue(id bson.ObjectId) (types.ForecastUpdate, error) {
+	var update types.ForecastUpdate
+	err := p.db.C(p.Collection).Find(bson.M{"_id": id, "status": types.UPDATE_FAILED}).One(&update)
+	if err != nil {
+		return update, err
+	}
+	err = p.setPending(update)
+	if mgo.IsDup(err) {
+		return update, errors.New("A newer update for the same time window is already pending")
+	}
+	update.Status = types.UPDATE_PENDING
+	update.Error = ""
+	return update, err
+}
+
+func (p *ForecastUpdateDAO) setPending(update types.ForecastUpdate) error {
+	return p.db.C(p.Collection).UpdateId(update.ID, bson.M{
+		"$set": bson.M{"status": types.UPDATE_PENDING,
+			"pending_window": pendingWindow(update.ServiceName, update.TimeWindowStart, update.TimeWindowEnd)},
+		"$unset": bson.M{"error": ""},
+	})
+}
+
+//Retrieve the updates of a service that are pending or in process
+func (p *ForecastUpdateDAO) FindPending(serviceName string) ([]types.ForecastUpdate, error) {
+	updates := []types.ForecastUpdate{}
+	err := p.db.C(p.Collection).
+		Find(bson.M{"service_name": serviceName,
+			"status": bson.M{"$in": []string{types.UPDATE_PENDING, types.UPDATE_PROCESSING}}}).
+		Sort("received_at").All(&updates)
+	return updates, err
+}
+
+//Retrieve the updates of a service that are pending, in process or failed
+func (p *ForecastUpdateDAO) FindByServiceName(serviceName string) ([]types.ForecastUpdate, error) {
+	updates := []types.ForecastUpdate{}
+	err := p.db.C(p.Collection).
+		Find(bson.M{"service_name": serviceName}).
+		Sort("received_at").All(&updates)
+	return updates, err
+}
+
+func GetForecastUpdateDAO() *ForecastUpdateDAO{
+	if ForecastUpdateDB == nil {
+		ForecastUpdateDB = &ForecastUpdateDAO {
+			Database:DEFAULT_DB_FORECAST,
+			Collection:DEFAULT_DB_COLLECTION_FORECAST_UPDATES,
+		}
+		_,err := ForecastUpdateDB.Connect()
+		if err != nil {
+			log.Error(err.Error())
+		}
+	}
+	return ForecastUpdateDB
+}
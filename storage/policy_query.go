@@ -0,0 +1,418 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Cloud-Pie/SPDT/types"
+	"github.com/Cloud-Pie/SPDT/util"
+	"gopkg.in/mgo.v2/bson"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Parameters accepted to query policies
+const (
+	QUERY_START          = "start"
+	QUERY_END            = "end"
+	QUERY_ALGORITHM      = "algorithm"
+	QUERY_STATUS         = "status"
+	QUERY_MIN_COST       = "min_cost"
+	QUERY_MAX_COST       = "max_cost"
+	QUERY_CREATED_AFTER  = "created_after"
+	QUERY_CREATED_BEFORE = "created_before"
+	QUERY_SORT           = "sort"
+	QUERY_LIMIT          = "limit"
+	QUERY_CURSOR         = "cursor"
+	QUERY_FIELDS         = "fields"
+)
+
+//Max number of policies returned in a page
+const MAX_QUERY_LIMIT = 500
+
+//Fields that can be used to sort the policies, mapped to the stored document keys
+var policySortFields = map[string]string{
+	"created":             "_id",
+	"cost":                "metrics.cost",
+	"over_provision":      "metrics.over_provision",
+	"under_provision":     "metrics.under_provision",
+	"n_scaling_actions":   "metrics.n_scaling_actions",
+	"derivation_duration": "metrics.derivation_duration",
+	"algorithm":           "algorithm",
+	"status":              "status",
+	"window_time_start":   "window_time_start",
+	"window_time_end":     "window_time_end",
+}
+
+//Fields that can be projected, mapped to the stored document keys
+var policyProjectionFields = map[string]string{
+	"id":                "_id",
+	"algorithm":         "algorithm",
+	"metrics":           "metrics",
+	"status":            "status",
+	"parameters":        "parameters",
+	"scaling_actions":   "scaling_actions",
+	"window_time_start": "window_time_start",
+	"window_time_end":   "window_time_end",
+}
+
+//Field used to sort the policies and its direction
+type SortField struct {
+	Name       string
+	Descending bool
+}
+
+//Filters, order, page and projection requested for a query of policies
+type PolicyQuery struct {
+	WindowStart   time.Time
+	WindowEnd     time.Time
+	Algorithms    []string
+	Status        []string
+	MinCost       *float64
+	MaxCost       *float64
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	Sort          []SortField
+	Limit         int
+	Cursor        []interface{}
+	Fields        []string
+}
+
+/* Build a policy query from the request parameters. Empty parameters are ignored
+	in:
+		@params map[string]string	- Parameter name and its value
+	out:
+		@PolicyQuery
+		@error	- Description of the first invalid parameter
+*/
+func ParsePolicyQuery(params map[string]string) (PolicyQuery, error) {
+	query := PolicyQuery{}
+	var err error
+
+	for name, value := range params {
+		if value == "" {
+			continue
+		}
+		switch name {
+		case QUERY_START:
+			query.WindowStart, err = parseQueryTime(name, value)
+		case QUERY_END:
+			query.WindowEnd, err = parseQueryTime(name, value)
+		case QUERY_CREATED_AFTER:
+			query.CreatedAfter, err = parseQueryTime(name, value)
+		case QUERY_CREATED_BEFORE:
+			query.CreatedBefore, err = parseQueryTime(name, value)
+		case QUERY_ALGORITHM:
+			query.Algorithms = splitList(value)
+		case QUERY_STATUS:
+			query.Status = splitList(value)
+			for _, s := range query.Status {
+				if s != types.SELECTED && s != types.DISCARTED && s != types.SCHEDULED {
+					err = fmt.Errorf("Invalid value for %s: %s", name, s)
+				}
+			}
+		case QUERY_MIN_COST:
+			query.MinCost, err = parseQueryCost(name, value)
+		case QUERY_MAX_COST:
+			query.MaxCost, err = parseQueryCost(name, value)
+		case QUERY_LIMIT:
+			query.Limit, err = strconv.Atoi(value)
+			if err != nil || query.Limit < 1 || query.Limit > MAX_QUERY_LIMIT {
+				err = fmt.Errorf("Invalid value for %s: it should be between 1 and %d", name, MAX_QUERY_LIMIT)
+			}
+		case QUERY_SORT:
+			query.Sort, err = parseQuerySort(value)
+		case QUERY_FIELDS:
+			query.Fields = splitList(value)
+			for _, f := range query.Fields {
+				if _, ok := policyProjectionFields[f]; !ok {
+					err = fmt.Errorf("Invalid value for %s: unknown field %s", name, f)
+				}
+			}
+		case QUERY_CURSOR:
+			//Decoded once the sort fields are known
+		default:
+			err = fmt.Errorf("Unknown parameter %s", name)
+		}
+		if err != nil {
+			return query, err
+		}
+	}
+
+	if query.MinCost != nil && query.MaxCost != nil && *query.MinCost > *query.MaxCost {
+		return query, fmt.Errorf("%s should be less than or equal to %s", QUERY_MIN_COST, QUERY_MAX_COST)
+	}
+	if cursor := params[QUERY_CURSOR]; cursor != "" {
+		query.Cursor, err = decodeCursor(cursor, query.sortFields())
+		if err != nil {
+			return query, err
+		}
+	}
+	return query, nil
+}
+
+/* Retrieve one page of the policies that match the query
+	in:
+		@query PolicyQuery
+	out:
+		@[]types.Policy
+		@string	- Cursor to request the next page, empty if there are no more policies
+		@error
+*/
+func (p *PolicyDAO) FindByQuery(query PolicyQuery) ([]types.Policy, string, error) {
+	var policies []types.Policy
+	sortFields := query.sortFields()
+
+	filter := query.filter()
+	if len(query.Cursor) > 0 {
+		filter = bson.M{"$and": []bson.M{filter, cursorFilter(sortFields, query.Cursor)}}
+	}
+
+	var order []string
+	for _, s := range sortFields {
+		key := policySortFields[s.Name]
+		if s.Descending {
+			key = "-" + key
+		}
+		order = append(order, key)
+	}
+
+	q := p.db.C(p.Collection).Find(filter).Sort(order...)
+	if projection := query.projection(sortFields); projection != nil {
+		q = q.Select(projection)
+	}
+	if query.Limit > 0 {
+		q = q.Limit(query.Limit + 1)
+	}
+	err := q.All(&policies)
+	if err != nil {
+		return policies, "", err
+	}
+
+	nextCursor := ""
+	if query.Limit > 0 && len(policies) > query.Limit {
+		policies = policies[:query.Limit]
+		nextCursor = encodeCursor(sortFields, policies[len(policies)-1])
+	}
+	return policies, nextCursor, nil
+}
+
+//Sort fields requested, followed by the creation order to break ties
+func (query PolicyQuery) sortFields() []SortField {
+	fields := []SortField{}
+	for _, s := range query.Sort {
+		if s.Name != "created" {
+			fields = append(fields, s)
+		}
+	}
+	for _, s := range query.Sort {
+		if s.Name == "created" {
+			return append(fields, s)
+		}
+	}
+	return append(fields, SortField{Name: "created"})
+}
+
+//Mongo filter for the query
+func (query PolicyQuery) filter() bson.M {
+	filter := bson.M{}
+	if !query.WindowStart.IsZero() {
+		filter["window_time_start"] = bson.M{"$gte": query.WindowStart}
+	}
+	if !query.WindowEnd.IsZero() {
+		filter["window_time_end"] = bson.M{"$lte": query.WindowEnd}
+	}
+	if len(query.Algorithms) > 0 {
+		filter["algorithm"] = bson.M{"$in": query.Algorithms}
+	}
+	if len(query.Status) > 0 {
+		filter["status"] = bson.M{"$in": query.Status}
+	}
+	cost := bson.M{}
+	if query.MinCost != nil {
+		cost["$gte"] = *query.MinCost
+	}
+	if query.MaxCost != nil {
+		cost["$lte"] = *query.MaxCost
+	}
+	if len(cost) > 0 {
+		filter["metrics.cost"] = cost
+	}
+	//The object id keeps the creation time of the policy
+	created := bson.M{}
+	if !query.CreatedAfter.IsZero() {
+		created["$gte"] = bson.NewObjectIdWithTime(query.CreatedAfter)
+	}
+	if !query.CreatedBefore.IsZero() {
+		created["$lt"] = bson.NewObjectIdWithTime(query.CreatedBefore)
+	}
+	if len(created) > 0 {
+		filter["_id"] = created
+	}
+	return filter
+}
+
+//Projection of the requested fields, it always includes the fields needed to build the cursor
+func (query PolicyQuery) projection(sortFields []SortField) bson.M {
+	if len(query.Fields) == 0 {
+		return nil
+	}
+	projection := bson.M{}
+	for _, f := range query.Fields {
+		projection[policyProjectionFields[f]] = 1
+	}
+	for _, s := range sortFields {
+		projection[policySortFields[s.Name]] = 1
+	}
+	return projection
+}
+
+/* Filter that selects the policies placed after the cursor for the given order
+	(a > x) OR (a == x AND b > y) OR ...
+*/
+func cursorFilter(sortFields []SortField, cursor []interface{}) bson.M {
+	var alternatives []bson.M
+	for i, s := range sortFields {
+		condition := bson.M{}
+		for j := 0; j < i; j++ {
+			condition[policySortFields[sortFields[j].Name]] = cursor[j]
+		}
+		operator := "$gt"
+		if s.Descending {
+			operator = "$lt"
+		}
+		condition[policySortFields[s.Name]] = bson.M{operator: cursor[i]}
+		alternatives = append(alternatives, condition)
+	}
+	return bson.M{"$or": alternatives}
+}
+
+//Value of a sort field for a policy
+func policySortValue(policy types.Policy, field string) interface{} {
+	switch field {
+	case "cost":
+		return policy.Metrics.Cost
+	case "over_provision":
+		return policy.Metrics.OverProvision
+	case "under_provision":
+		return policy.Metrics.UnderProvision
+	case "n_scaling_actions":
+		return policy.Metrics.NumberScalingActions
+	case "derivation_duration":
+		return policy.Metrics.DerivationDuration
+	case "algorithm":
+		return policy.Algorithm
+	case "status":
+		return policy.Status
+	case "window_time_start":
+		return policy.TimeWindowStart
+	case "window_time_end":
+		return policy.TimeWindowEnd
+	}
+	return policy.ID
+}
+
+//Cursor with the sort values of the last policy of a page
+func encodeCursor(sortFields []SortField, policy types.Policy) string {
+	values := []string{}
+	for _, s := range sortFields {
+		switch v := policySortValue(policy, s.Name).(type) {
+		case float64:
+			values = append(values, strconv.FormatFloat(v, 'g', -1, 64))
+		case int:
+			values = append(values, strconv.Itoa(v))
+		case time.Time:
+			values = append(values, v.Format(time.RFC3339Nano))
+		case bson.ObjectId:
+			values = append(values, v.Hex())
+		case string:
+			values = append(values, v)
+		}
+	}
+	data, _ := json.Marshal(values)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+//Decode a cursor into the typed values of the sort fields
+func decodeCursor(cursor string, sortFields []SortField) ([]interface{}, error) {
+	invalid := errors.New("Invalid value for " + QUERY_CURSOR)
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, invalid
+	}
+	var values []string
+	if err = json.Unmarshal(data, &values); err != nil || len(values) != len(sortFields) {
+		return nil, invalid
+	}
+	decoded := make([]interface{}, len(values))
+	for i, s := range sortFields {
+		switch s.Name {
+		case "cost", "over_provision", "under_provision", "derivation_duration":
+			decoded[i], err = strconv.ParseFloat(values[i], 64)
+		case "n_scaling_actions":
+			decoded[i], err = strconv.Atoi(values[i])
+		case "window_time_start", "window_time_end":
+			decoded[i], err = time.Parse(time.RFC3339Nano, values[i])
+		case "created":
+			if !bson.IsObjectIdHex(values[i]) {
+				return nil, invalid
+			}
+			decoded[i] = bson.ObjectIdHex(values[i])
+		default:
+			decoded[i] = values[i]
+		}
+		if err != nil {
+			return nil, invalid
+		}
+	}
+	return decoded, nil
+}
+
+//Parse a list of sort fields, a leading "-" means descending order. E.g. cost,-created
+func parseQuerySort(value string) ([]SortField, error) {
+	var sortFields []SortField
+	seen := make(map[string]bool)
+	for _, f := range splitList(value) {
+		field := SortField{Name: strings.TrimPrefix(f, "-"), Descending: strings.HasPrefix(f, "-")}
+		if _, ok := policySortFields[field.Name]; !ok {
+			return nil, fmt.Errorf("Invalid value for %s: unknown field %s", QUERY_SORT, field.Name)
+		}
+		if seen[field.Name] {
+			return nil, fmt.Errorf("Invalid value for %s: repeated field %s", QUERY_SORT, field.Name)
+		}
+		seen[field.Name] = true
+		sortFields = append(sortFields, field)
+	}
+	return sortFields, nil
+}
+
+func parseQueryTime(name string, value string) (time.Time, error) {
+	t, err := time.Parse(util.UTC_TIME_LAYOUT, value)
+	if err != nil {
+		t, err = time.Parse(time.RFC3339, value)
+	}
+	if err != nil {
+		return t, fmt.Errorf("Invalid value for %s: %s. Expected format %s", name, value, util.UTC_TIME_LAYOUT)
+	}
+	return t, nil
+}
+
+func parseQueryCost(name string, value string) (*float64, error) {
+	cost, err := strconv.ParseFloat(value, 64)
+	if err != nil || cost < 0 {
+		return nil, fmt.Errorf("Invalid value for %s: %s", name, value)
+	}
+	return &cost, nil
+}
+
+func splitList(value string) []string {
+	var list []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"github.com/Cloud-Pie/SPDT/types"
+	"gopkg.in/mgo.v2/bson"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParsePolicyQuery(t *testing.T) {
+	start := time.Date(2018, 11, 1, 10, 0, 0, 0, time.UTC)
+	minCost, maxCost := 1.5, 20.0
+
+	var tests = []struct {
+		params   map[string]string
+		expected PolicyQuery
+	}{
+		{map[string]string{}, PolicyQuery{}},
+		{map[string]string{QUERY_START: "2018-11-01T10:00:00Z", QUERY_END: "", QUERY_LIMIT: "10"},
+			PolicyQuery{WindowStart: start, Limit: 10}},
+		{map[string]string{QUERY_CREATED_AFTER: "2018-11-01T10:00:30Z"},
+			PolicyQuery{CreatedAfter: start.Add(30 * time.Second)}},
+		{map[string]string{QUERY_ALGORITHM: "naive, best-resource-pair,", QUERY_STATUS: "selected,discarted"},
+			PolicyQuery{Algorithms: []string{"naive", "best-resource-pair"},
+				Status: []string{types.SELECTED, types.DISCARTED}}},
+		{map[string]string{QUERY_MIN_COST: "1.5", QUERY_MAX_COST: "20"},
+			PolicyQuery{MinCost: &minCost, MaxCost: &maxCost}},
+		{map[string]string{QUERY_SORT: "cost,-created", QUERY_FIELDS: "id,metrics"},
+			PolicyQuery{Sort: []SortField{{Name: "cost"}, {Name: "created", Descending: true}},
+				Fields: []string{"id", "metrics"}}},
+	}
+	for _, test := range tests {
+		query, err := ParsePolicyQuery(test.params)
+		if err != nil || !reflect.DeepEqual(query, test.expected) {
+			t.Error(
+				"For: ", test.params,
+				"expected: ", test.expected,
+				"got: ", query, err,
+			)
+		}
+	}
+}
+
+func TestParsePolicyQueryInvalid(t *testing.T) {
+	var tests = []map[string]string{
+		{QUERY_START: "yesterday"},
+		{QUERY_STATUS: "selected,running"},
+		{QUERY_MIN_COST: "-1"},
+		{QUERY_MAX_COST: "cheap"},
+		{QUERY_MIN_COST: "10", QUERY_MAX_COST: "5"},
+		{QUERY_LIMIT: "0"},
+		{QUERY_LIMIT: "501"},
+		{QUERY_SORT: "price"},
+		{QUERY_SORT: "cost,-cost"},
+		{QUERY_FIELDS: "id,password"},
+		{QUERY_CURSOR: "not a cursor"},
+		{"page": "2"},
+	}
+	for _, params := range tests {
+		if _, err := ParsePolicyQuery(params); err == nil {
+			t.Error(
+				"For: ", params,
+				"expected: ", "error",
+				"got: ", nil,
+			)
+		}
+	}
+}
+
+func TestPolicyCursor(t *testing.T) {
+	policy := types.Policy{
+		ID:              bson.NewObjectId(),
+		Algorithm:       "naive",
+		Metrics:         types.PolicyMetrics{Cost: 12.25, NumberScalingActions: 3},
+		TimeWindowStart: time.Date(2018, 11, 1, 10, 0, 0, 123, time.UTC),
+	}
+	sortFields := PolicyQuery{Sort: []SortField{{Name: "cost"}, {Name: "n_scaling_actions", Descending: true},
+		{Name: "window_time_start"}, {Name: "algorithm"}}}.sortFields()
+
+	cursor := encodeCursor(sortFields, policy)
+	query, err := ParsePolicyQuery(map[string]string{QUERY_CURSOR: cursor,
+		QUERY_SORT: "cost,-n_scaling_actions,window_time_start,algorithm"})
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	expected := []interface{}{12.25, 3, policy.TimeWindowStart, "naive", policy.ID}
+	if !reflect.DeepEqual(query.Cursor, expected) {
+		t.Error(
+			"For: ", "cursor round-trip",
+			"expected: ", expected,
+			"got: ", query.Cursor,
+		)
+	}
+
+	//A cursor is only valid for the order it was built with
+	if _, err := ParsePolicyQuery(map[string]string{QUERY_CURSOR: cursor, QUERY_SORT: "cost"}); err == nil {
+		t.Error("Expected an error for a cursor of a different order")
+	}
+}
+
+func TestCursorFilter(t *testing.T) {
+	id := bson.NewObjectId()
+	sortFields := []SortField{{Name: "cost"}, {Name: "created", Descending: true}}
+
+	filter := cursorFilter(sortFields, []interface{}{12.25, id})
+	expected := bson.M{"$or": []bson.M{
+		{"metrics.cost": bson.M{"$gt": 12.25}},
+		{"metrics.cost": 12.25, "_id": bson.M{"$lt": id}},
+	}}
+	if !reflect.DeepEqual(filter, expected) {
+		t.Error(
+			"For: ", sortFields,
+			"expected: ", expected,
+			"got: ", filter,
+		)
+	}
+}
@@ -105,6 +105,45 @@ func (p *PolicyDAO) FindSelectedByTimeWindow(startTime time.Time, endTime time.T
 	return policy,err
 }
 
+//Retrieve the selected policy whose time window contains the time t
+func (p *PolicyDAO) FindSelectedByTime(t time.Time) (types.Policy, error) {
+	var policy types.Policy
+	err := p.db.C(p.Collection).
+		Find(bson.M{"window_time_start": bson.M{"$lte":t},
+		"window_time_end": bson.M{"$gt":t},
+		"status": "selected" }).Sort("-_id").One(&policy)
+	return policy,err
+}
+
+//Retrieve the selected policy that contains the scaling action to the state with the given hash and start time
+func (p *PolicyDAO) FindSelectedByScalingAction(stateHash string, timeStart time.Time) (types.Policy, error) {
+	var policy types.Policy
+	err := p.db.C(p.Collection).
+		Find(bson.M{"status": "selected",
+		"scaling_actions": bson.M{"$elemMatch": bson.M{"desired_state.hash": stateHash, "time_start": timeStart}}}).
+		Sort("-_id").One(&policy)
+	return policy,err
+}
+
+//Update only the execution of the scaling action to the state with the given hash and start time in the policy
+func (p *PolicyDAO) UpdateScalingActionExecution(id bson.ObjectId, stateHash string, timeStart time.Time, execution types.ActionExecution) error {
+	err := p.db.C(p.Collection).
+		Update(bson.M{"_id": id,
+		"scaling_actions": bson.M{"$elemMatch": bson.M{"desired_state.hash": stateHash, "time_start": timeStart}}},
+		bson.M{"$set": bson.M{"scaling_actions.$.execution": execution}})
+	return err
+}
+
+//Retrieve the selected policy of the rolling horizon planning with the latest time window
+func (p *PolicyDAO) FindLastRolling() (types.Policy, error) {
+	var policy types.Policy
+	err := p.db.C(p.Collection).
+		Find(bson.M{"status": "selected",
+		"parameters." + types.ROLLING_HORIZON: "true"}).
+		Sort("-window_time_end").One(&policy)
+	return policy,err
+}
+
 //Insert a new Performance Profile
 func (p *PolicyDAO) Insert(policies types.Policy) error {
 	err := p.db.C(p.Collection).Insert(&policies)
@@ -132,6 +171,12 @@ func (p *PolicyDAO) UpdateById(id bson.ObjectId, policy types.Policy) error {
 	return err
 }
 
+//Insert the policy or replace it if it is already stored
+func (p *PolicyDAO) UpsertById(id bson.ObjectId, policy types.Policy) error {
+	_,err := p.db.C(p.Collection).UpsertId(id, policy)
+	return err
+}
+
 func GetPolicyDAO(serviceName string) *PolicyDAO{
 	if PolicyDB == nil {
 		PolicyDB = &PolicyDAO {
@@ -86,6 +86,25 @@ func (p *PerformanceProfileDAO) UpdateById(id bson.ObjectId, performanceProfile
 	return err
 }
 
+/* Replace all the stored profiles with a new set.
+	The profiles are written in a staging collection that is renamed over the current one,
+	so readers find either the old or the new set but never an empty collection
+	in:
+		@performanceProfiles []types.PerformanceProfile
+	out:
+		@error
+*/
+func (p *PerformanceProfileDAO) ReplaceAll(performanceProfiles []types.PerformanceProfile) error {
+	return replaceCollection(p.db, p.Collection, len(performanceProfiles), func(staging *mgo.Collection) error {
+		for _, performanceProfile := range performanceProfiles {
+			if err := staging.Insert(&performanceProfile); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 func (p *PerformanceProfileDAO) FindByLimitsAndReplicas(cores float64, memory float64, replicas int) (types.PerformanceProfile, error) {
 	//db.getCollection('trnProfiles').find({"limits.cpu_cores" : 1000,"limits.mem_gb" : 500, "mscs": {$elemMatch:{"replicas":2} } }, {_id: 0, "mscs.$":1})
 	var performanceProfile types.PerformanceProfile
@@ -194,4 +213,32 @@ func GetPerformanceProfileDAO(serviceName string) *PerformanceProfileDAO {
 		}
 	}
 	return PerformanceProfileDB
-}
\ No newline at end of file
+}
+
+/* Fill a staging collection and rename it atomically over the target collection
+	in:
+		@db *mgo.Database
+		@collection string	- Target collection
+		@size int	- Number of documents of the new set, an empty set is rejected
+		@fill func(*mgo.Collection) error	- Writes the new set in the staging collection
+	out:
+		@error
+*/
+func replaceCollection(db *mgo.Database, collection string, size int, fill func(*mgo.Collection) error) error {
+	if size == 0 {
+		return errors.New("The new set of documents is empty")
+	}
+	stagingName := collection + "_staging"
+	staging := db.C(stagingName)
+	staging.DropCollection()
+	if err := fill(staging); err != nil {
+		staging.DropCollection()
+		return err
+	}
+	command := bson.D{
+		{Name: "renameCollection", Value: db.Name + "." + stagingName},
+		{Name: "to", Value: db.Name + "." + collection},
+		{Name: "dropTarget", Value: true},
+	}
+	return db.Session.DB("admin").Run(command, nil)
+}
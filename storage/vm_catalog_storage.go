@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"gopkg.in/mgo.v2"
+	"github.com/Cloud-Pie/SPDT/types"
+	"gopkg.in/mgo.v2/bson"
+	"os"
+	"time"
+)
+
+type VMCatalogDAO struct {
+	Server     string
+	Database   string
+	Collection string
+	db         *mgo.Database
+	Session    *mgo.Session
+}
+
+var VMCatalogDB *VMCatalogDAO
+
+const (
+	DEFAULT_DB_COLLECTION_VM_CATALOG = "VM_Catalog"
+)
+
+//Connect to the database
+func (p *VMCatalogDAO) Connect() (*mgo.Database, error) {
+	var err error
+
+	if p.Session == nil {
+		p.Session,  err = mgo.DialWithInfo(&mgo.DialInfo{
+			Addrs: profilesDBHost,
+			Username: os.Getenv("PROFILESDB_USER"),
+			Password: os.Getenv("PROFILESDB_PASS"),
+			Timeout:  60 * time.Second,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	p.Session = p.Session.Clone()
+	p.db = p.Session.DB(p.Database)
+	p.db.C(p.Collection).EnsureIndex(mgo.Index{Key: []string{"version"}, Unique: true})
+	return p.db,err
+}
+
+//Insert a new version of the catalog. Fails if the version number already exists
+func (p *VMCatalogDAO) Insert(catalogVersion types.VMCatalogVersion) error {
+	err := p.db.C(p.Collection).Insert(&catalogVersion)
+	return err
+}
+
+//Retrieve the most recent version of the catalog
+func (p *VMCatalogDAO) FindLatest() (types.VMCatalogVersion, error) {
+	var catalogVersion types.VMCatalogVersion
+	err := p.db.C(p.Collection).Find(bson.M{}).Sort("-version").One(&catalogVersion)
+	return catalogVersion, err
+}
+
+//Retrieve a version of the catalog
+func (p *VMCatalogDAO) FindByVersion(version int) (types.VMCatalogVersion, error) {
+	var catalogVersion types.VMCatalogVersion
+	err := p.db.C(p.Collection).Find(bson.M{"version":version}).One(&catalogVersion)
+	return catalogVersion, err
+}
+
+//Retrieve the list of versions without their entries, the most recent first
+func (p *VMCatalogDAO) FindVersions() ([]types.VMCatalogVersion, error) {
+	catalogVersions := []types.VMCatalogVersion{}
+	err := p.db.C(p.Collection).Find(bson.M{}).Select(bson.M{"entries":0}).Sort("-version").All(&catalogVersions)
+	return catalogVersions, err
+}
+
+func GetVMCatalogDAO() *VMCatalogDAO {
+	if VMCatalogDB == nil {
+		VMCatalogDB = &VMCatalogDAO {
+			Database:DEFAULT_DB_PROFILES,
+			Collection:DEFAULT_DB_COLLECTION_VM_CATALOG,
+		}
+		_,err := VMCatalogDB.Connect()
+		if err != nil {
+			log.Error("Error connecting to Profiles database "+err.Error())
+		}
+	}
+	return VMCatalogDB
+}
@@ -96,6 +96,23 @@ func (p *VMBootingProfileDAO) InstanceVMBootingShutdown(vmType string) (types.In
 	return result, err
 }
 
+/* Replace all the stored booting profiles with a new set without leaving the collection empty
+	in:
+		@vmBootingProfiles []types.InstancesBootShutdownTime
+	out:
+		@error
+*/
+func (p *VMBootingProfileDAO) ReplaceAll(vmBootingProfiles []types.InstancesBootShutdownTime) error {
+	return replaceCollection(p.db, p.Collection, len(vmBootingProfiles), func(staging *mgo.Collection) error {
+		for _, vmBootingProfile := range vmBootingProfiles {
+			if err := staging.Insert(&vmBootingProfile); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 //Delete the specified item
 func (p *VMBootingProfileDAO) DeleteAll() error {
 	_,err := p.db.C(p.Collection).RemoveAll(bson.M{})
@@ -0,0 +1,30 @@
+package types
+
+import (
+	"gopkg.in/mgo.v2/bson"
+	"time"
+)
+
+/*Spend of a scheduled scaling action within a calendar month.
+The spend until now is committed, the rest is forecast*/
+type BudgetLedgerEntry struct {
+	ID         bson.ObjectId `json:"id" bson:"_id"`
+	PolicyID   string        `json:"policy_id" bson:"policy_id"`
+	Month      string        `json:"month" bson:"month"` //YYYY-MM
+	TimeStart  time.Time     `json:"time_start" bson:"time_start"`
+	TimeEnd    time.Time     `json:"time_end" bson:"time_end"`
+	Cost       float64       `json:"cost" bson:"cost"`
+	RecordedAt time.Time     `json:"recorded_at" bson:"recorded_at"`
+}
+
+/*Budget and spend of a calendar month*/
+type MonthlySpend struct {
+	Month          string    `json:"month"`
+	Budget         float64   `json:"budget"`
+	Committed      float64   `json:"committed"`       //Spend of the scheduled scaling actions until now
+	Forecast       float64   `json:"forecast"`        //Spend of the scheduled scaling actions after now
+	Remaining      float64   `json:"remaining"`       //Budget minus committed and forecast spend
+	BurnRate       float64   `json:"burn_rate"`       //Committed spend per hour
+	ProjectedSpend float64   `json:"projected_spend"` //Spend expected at the end of the month
+	ScheduledUntil time.Time `json:"scheduled_until"` //End of the last scheduled scaling action in the month
+}
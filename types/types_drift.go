@@ -0,0 +1,32 @@
+package types
+
+import (
+	"gopkg.in/mgo.v2/bson"
+	"time"
+)
+
+//Kinds of differences between the expected and the actual state
+const (
+	DRIFT_MISSING_VMS       = "missing-vms"
+	DRIFT_EXTRA_VMS         = "extra-vms"
+	DRIFT_WRONG_REPLICAS    = "wrong-replicas"
+	DRIFT_WRONG_LIMITS      = "wrong-limits"
+	DRIFT_STATE_NOT_APPLIED = "state-not-applied"
+)
+
+/*Difference found between the state expected by the selected policy and the actual infrastructure*/
+type DriftEvent struct {
+	ID               bson.ObjectId `json:"id" bson:"_id"`
+	ServiceName      string        `json:"service_name" bson:"service_name"`
+	PolicyID         string        `json:"policy_id" bson:"policy_id"`
+	ActionTimeStart  time.Time     `json:"action_time_start" bson:"action_time_start"`
+	DetectedAt       time.Time     `json:"detected_at" bson:"detected_at"`
+	Kinds            []string      `json:"kinds" bson:"kinds"`
+	ExpectedState    State         `json:"expected_state" bson:"expected_state"`
+	ActualState      State         `json:"actual_state" bson:"actual_state"`
+	MissingVMs       VMScale       `json:"missing_vms" bson:"missing_vms"`
+	ExtraVMs         VMScale       `json:"extra_vms" bson:"extra_vms"`
+	ExpectedReplicas int           `json:"expected_replicas" bson:"expected_replicas"`
+	ActualReplicas   int           `json:"actual_replicas" bson:"actual_replicas"`
+	Action           string        `json:"action" bson:"action"` //Reaction of the reconciler
+}
@@ -0,0 +1,53 @@
+package types
+
+import "time"
+
+//Events reported by the scheduler for a scheduled state
+const (
+	EXECUTION_STARTED  = "started"
+	EXECUTION_FINISHED = "finished"
+	EXECUTION_FAILED   = "failed"
+)
+
+/*Event sent by the scheduler when a state starts, finishes or fails*/
+type ExecutionEvent struct {
+	Name          string    `json:"name" binding:"required"`          //Name of the scheduled state
+	ExpectedStart time.Time `json:"expected_time" binding:"required"` //Expected start of the scheduled state
+	Event         string    `json:"event" binding:"required"`
+	Timestamp     time.Time `json:"timestamp"`
+	Error         string    `json:"error"`
+}
+
+/*Actual execution of a scaling action reported by the scheduler*/
+type ActionExecution struct {
+	Status                string    `json:"status,omitempty" bson:"status,omitempty"`
+	StartedAt             time.Time `json:"started_at,omitempty" bson:"started_at,omitempty"`
+	FinishedAt            time.Time `json:"finished_at,omitempty" bson:"finished_at,omitempty"`
+	TransitionDurationSec float64   `json:"transition_duration_sec" bson:"transition_duration_sec"`
+	Error                 string    `json:"error,omitempty" bson:"error,omitempty"`
+}
+
+/*Planned and actual timing of a scaling action*/
+type ActionTiming struct {
+	TimeStart              time.Time `json:"time_start"`
+	PlannedStartTransition time.Time `json:"planned_start_transition"`
+	PlannedTransitionSec   float64   `json:"planned_transition_sec"`
+	Status                 string    `json:"status"`
+	ActualStartTransition  time.Time `json:"actual_start_transition,omitempty"`
+	ActualFinish           time.Time `json:"actual_finish,omitempty"`
+	ActualTransitionSec    float64   `json:"actual_transition_sec"`
+	DelaySec               float64   `json:"delay_sec"` //Time between the planned start of the state and the end of its transition
+}
+
+/*Planned versus actual timing of the scaling actions of a policy*/
+type PolicyTiming struct {
+	PolicyID                string         `json:"policy_id"`
+	Actions                 []ActionTiming `json:"actions"`
+	Finished                int            `json:"n_finished"`
+	Failed                  int            `json:"n_failed"`
+	Pending                 int            `json:"n_pending"`
+	AvgPlannedTransitionSec float64        `json:"avg_planned_transition_sec"`
+	AvgActualTransitionSec  float64        `json:"avg_actual_transition_sec"`
+	AvgDelaySec             float64        `json:"avg_delay_sec"`
+	MaxDelaySec             float64        `json:"max_delay_sec"`
+}
@@ -27,6 +27,7 @@ type Forecast struct {
 	TimeWindowStart  time.Time         `json:"start_time"  bson:"start_time"`
 	TimeWindowEnd    time.Time         `json:"end_time"  bson:"end_time"`
 	IDPrediction     string            `json:"id"  bson:"id_predictions"`
+	ContentHash      string            `json:"-"  bson:"content_hash"`
 }
 
 /*ProcessedForecast metadata after processing the time serie*/
@@ -56,3 +57,26 @@ type PoI struct {
 		Widht_heights	float64	`json:"widht_heights"`
 	}							`json:"index_right_valley"`
 }
+
+//Status of a pushed forecast update
+const (
+	UPDATE_PENDING    = "pending"
+	UPDATE_PROCESSING = "processing"
+	UPDATE_FAILED     = "failed"
+)
+
+/*Forecast pushed by the Forecasting component waiting to be processed.
+There is at most one pending update per service and time window, newer updates replace the forecast*/
+type ForecastUpdate struct {
+	ID              bson.ObjectId `json:"id" bson:"_id,omitempty"`
+	ServiceName     string        `json:"service_name" bson:"service_name"`
+	TimeWindowStart time.Time     `json:"start_time" bson:"start_time"`
+	TimeWindowEnd   time.Time     `json:"end_time" bson:"end_time"`
+	Forecast        Forecast      `json:"forecast" bson:"forecast"`
+	Status          string        `json:"status" bson:"status"`
+	Received        int           `json:"received" bson:"received"` //Number of pushed updates merged in this one
+	ReceivedAt      time.Time     `json:"received_at" bson:"received_at"`
+	UpdatedAt       time.Time     `json:"updated_at" bson:"updated_at"`
+	StartedAt       time.Time     `json:"started_at,omitempty" bson:"started_at,omitempty"`
+	Error           string        `json:"error,omitempty" bson:"error,omitempty"`
+}
@@ -1,6 +1,16 @@
 package types
 
-import "gopkg.in/mgo.v2/bson"
+import (
+	"gopkg.in/mgo.v2/bson"
+	"time"
+)
+
+//Origin of the stored profiles
+const (
+	PROFILE_SOURCE_PROFILER = "performance-profiles-component"
+	PROFILE_SOURCE_IMPORT = "import"
+	PROFILE_SOURCE_LEARNED = "learned"
+)
 
 type Pricing struct {
 	Price float64	`json:"price" bson:"price"`
@@ -14,6 +24,13 @@ type VmProfile struct {
 	OS               string  `json:"os" bson:"os"`
 	Pricing          Pricing `json:"pricing" bson:"pricing"`
 	ReplicasCapacity int	 `json:"replicas_capacity" bson:"replicas_capacity"`
+	Spot             SpotVariant `json:"spot" bson:"spot"`
+}
+
+//Spot or preemptible variant of a VM type. A VM type without discount has no spot variant
+type SpotVariant struct {
+	Discount         float64 `json:"discount" bson:"discount"`                   //Fraction of the on-demand price, between 0 and 1
+	InterruptionRate float64 `json:"interruption_rate" bson:"interruption_rate"` //Expected interruptions per instance and hour
 }
 
 //Times in seconds
@@ -27,6 +44,8 @@ type BootShutDownTime struct {
 type InstancesBootShutdownTime struct {
 	InstancesValues []BootShutDownTime `json:"InstanceValues" bson:"instances_values"`
 	VMType          string             `json:"VMType" bson:"vm_type"`
+	FetchedAt       time.Time          `json:"FetchedAt" bson:"fetched_at"`
+	Source          string             `json:"Source" bson:"source"`
 }
 
 type Limit struct {
@@ -39,6 +58,8 @@ type PerformanceProfile struct {
 	ID          bson.ObjectId      `bson:"_id" json:"id"`
 	MSCSettings []MSCSimpleSetting `json:"mscs" bson:"mscs"`
 	Limit       Limit              `json:"limits" bson:"limits"`
+	FetchedAt   time.Time          `json:"fetched_at" bson:"fetched_at"`
+	Source      string             `json:"source" bson:"source"`
 }
 
 type ServiceProfile struct {
@@ -5,7 +5,7 @@ import (
 	"gopkg.in/mgo.v2/bson"
 )
 
-/*VMScale is the factor for which a type of VM is scales*/
+/*VMTimeRecord keeps the launch time of the running instances of each VM type*/
 type VMTimeRecord map[string][]time.Time
 
 /*Service keeps the name and scale of the scaled service*/
@@ -166,6 +166,32 @@ type PolicyMetrics struct {
 	AvgShadowTime 				  float64		`json:"avg_shadow_time_sec" bson:"avg_shadow_time_sec"`
 	AvgTransitionTime 			  float64		`json:"avg_transition_time_sec" bson:"avg_transition_time_sec"`
 	AvgElapsedTime 			      float64		`json:"avg_time_between_scaling_sec" bson:"avg_time_between_scaling_sec"`
+	BillingBoundarySavings        float64		`json:"billing_boundary_savings" bson:"billing_boundary_savings"`	//Cost saved keeping VMs until their paid period ends
+	ExpectedCost                  float64		`json:"expected_cost" bson:"expected_cost"`	//Cost including the replacement of interrupted spot VMs
+	ExpectedInterruptions         float64		`json:"expected_interruptions" bson:"expected_interruptions"`
+	CapacityAtRisk                float64		`json:"capacity_at_risk" bson:"capacity_at_risk"`	//Highest percentage of spot VMs in a scaling action
+	ReservedUtilization           float64		`json:"reserved_utilization" bson:"reserved_utilization"`	//Percentage of the reserved VM hours used
+	Baselines                     []BaselineMetrics	`json:"baselines" bson:"baselines"`
+}
+
+//Baselines with which the policies are compared
+const (
+	BASELINE_STATIC_PEAK       = "static-peak"
+	BASELINE_STATIC_PERCENTILE = "static-percentile"
+	BASELINE_REACTIVE          = "reactive"
+)
+
+/*Cost and provisioning of a baseline for the forecast of a policy, and the difference with the policy*/
+type BaselineMetrics struct {
+	Name                 string  `json:"name" bson:"name"`
+	Cost                 float64 `json:"cost" bson:"cost"`
+	OverProvision        float64 `json:"over_provision" bson:"over_provision"`
+	UnderProvision       float64 `json:"under_provision" bson:"under_provision"`
+	NumberScalingActions int     `json:"n_scaling_actions" bson:"n_scaling_actions"`
+	Savings              float64 `json:"savings" bson:"savings"`                           //Baseline cost minus policy cost
+	SavingsPercentage    float64 `json:"savings_percentage" bson:"savings_percentage"`     //Savings over the baseline cost
+	OverProvisionDiff    float64 `json:"over_provision_diff" bson:"over_provision_diff"`   //Policy minus baseline over provision
+	UnderProvisionDiff   float64 `json:"under_provision_diff" bson:"under_provision_diff"` //Policy minus baseline under provision
 }
 
 /*Resource configuration*/
@@ -176,6 +202,7 @@ type ScalingAction struct {
 	TimeStart        time.Time     `json:"time_start" bson:"time_start"`
 	TimeEnd          time.Time     `json:"time_end" bson:"time_end"`
 	Metrics          ConfigMetrics `json:"metrics" bson:"metrics"`
+	Execution        ActionExecution `json:"execution" bson:"execution"`
 }
 
 
@@ -187,6 +214,8 @@ const (
 	ISHETEREOGENEOUS= "heterogeneous-vms-allowed"
 	ISRESIZEPODS= "pods-resize-allowed"
 	VMTYPES= "vm-types"
+	REPLANNED_FROM= "replanned-from"
+	ROLLING_HORIZON= "rolling-horizon"
 
 )
 
@@ -207,7 +236,7 @@ type Policy struct {
 	ScalingActions  []ScalingAction   `json:"scaling_actions" bson:"scaling_actions"`
 	TimeWindowStart time.Time         `json:"window_time_start"  bson:"window_time_start"`
 	TimeWindowEnd   time.Time         `json:"window_time_end"  bson:"window_time_end"`
-
+	VMCatalogVersion int              `json:"vm_catalog_version"  bson:"vm_catalog_version"` //Version of the VM catalog used in the derivation
 }
 
 //Utility struct to represent a key value object
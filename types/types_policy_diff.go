@@ -0,0 +1,42 @@
+package types
+
+import "time"
+
+/*Differences between two policies for an interval of time where both keep the same state*/
+type IntervalDiff struct {
+	TimeStart  time.Time `json:"time_start"`
+	TimeEnd    time.Time `json:"time_end"`
+	VMsA       VMScale   `json:"vms_a"`
+	VMsB       VMScale   `json:"vms_b"`
+	VMsAdded   VMScale   `json:"vms_added"`   //VMs in B that are not in A
+	VMsRemoved VMScale   `json:"vms_removed"` //VMs in A that are not in B
+	ReplicasA  int       `json:"replicas_a"`
+	ReplicasB  int       `json:"replicas_b"`
+	LimitsA    Limit     `json:"limits_a"`
+	LimitsB    Limit     `json:"limits_b"`
+	CapacityA  float64   `json:"requests_capacity_a"`
+	CapacityB  float64   `json:"requests_capacity_b"`
+	CostA      float64   `json:"cost_a"`
+	CostB      float64   `json:"cost_b"`
+	CostDelta  float64   `json:"cost_delta"`
+}
+
+/*Summary of the differences between two policies*/
+type PolicyDiffSummary struct {
+	CostA            float64 `json:"cost_a"`
+	CostB            float64 `json:"cost_b"`
+	CostDelta        float64 `json:"cost_delta"`
+	CostDeltaPercent float64 `json:"cost_delta_percent"`
+	ScalingActionsA  int     `json:"n_scaling_actions_a"`
+	ScalingActionsB  int     `json:"n_scaling_actions_b"`
+	ChangedIntervals int     `json:"n_changed_intervals"`
+	ChangedTimeSec   float64 `json:"changed_time_sec"`
+}
+
+/*Differences between the scaling actions of two policies aligned by time*/
+type PolicyDiff struct {
+	PolicyA   string            `json:"policy_a"`
+	PolicyB   string            `json:"policy_b"`
+	Intervals []IntervalDiff    `json:"intervals"`
+	Summary   PolicyDiffSummary `json:"summary"`
+}
@@ -0,0 +1,56 @@
+package types
+
+import (
+	"gopkg.in/mgo.v2/bson"
+	"strings"
+	"time"
+)
+
+//Units of the VM prices
+const (
+	PRICE_UNIT_SECOND = "Second"
+	PRICE_UNIT_MINUTE = "Minute"
+	PRICE_UNIT_HOUR = "Hour"
+	PRICE_UNIT_MONTH = "Month"
+)
+
+//Suffix of the name of the spot variant of a VM type, e.g. t2.large-spot
+const SPOT_SUFFIX = "-spot"
+
+//Name of the spot variant of a VM type
+func SpotType(vmType string) string {
+	return vmType + SPOT_SUFFIX
+}
+
+//Check if a VM type is a spot variant
+func IsSpotType(vmType string) bool {
+	return strings.HasSuffix(vmType, SPOT_SUFFIX)
+}
+
+//Name of the on-demand VM type of a spot variant
+func OnDemandType(vmType string) string {
+	return strings.TrimSuffix(vmType, SPOT_SUFFIX)
+}
+
+//VM types available per cloud service provider and region
+//E.g. catalog["AWS"]["us-east-2"]
+type VMCatalog map[string]map[string][]VmProfile
+
+//VM type of the catalog for a provider and region.
+//Disallowed types are never used. If some type of a provider and region is allowed, only the allowed types are used
+type VMCatalogEntry struct {
+	CSP        string    `json:"csp" bson:"csp"`
+	Region     string    `json:"region" bson:"region"`
+	VmProfile  VmProfile `json:"vm" bson:"vm"`
+	Allowed    bool      `json:"allowed" bson:"allowed"`
+	Disallowed bool      `json:"disallowed" bson:"disallowed"`
+}
+
+//Version of the VM catalog. Every change of the catalog is stored as a new version
+type VMCatalogVersion struct {
+	ID        bson.ObjectId    `json:"id" bson:"_id"`
+	Version   int              `json:"version" bson:"version"`
+	CreatedAt time.Time        `json:"created_at" bson:"created_at"`
+	Source    string           `json:"source" bson:"source"` //File or operation that created the version
+	Entries   []VMCatalogEntry `json:"entries,omitempty" bson:"entries"`
+}
@@ -5,6 +5,7 @@ import (
 	"io/ioutil"
 	"gopkg.in/yaml.v2"
 	"log"
+	"fmt"
 )
 
 //Struct that models the external components to which SPDT should be connected
@@ -29,8 +30,26 @@ type ScalingHorizon struct {
 
 //
 type PricingModel struct {
-	Budget      float64 `yaml:"monthly-budget"`
-	BillingUnit string  `yaml:"billing-unit"`
+	Budget            float64        `yaml:"monthly-budget"`
+	BillingUnit       string         `yaml:"billing-unit"`
+	MinimumBilledTime string         `yaml:"minimum-billed-time"` //E.g. 60s, 10m. Default 60s for s and m, 1h for h
+	DiscountModel     string         `yaml:"discount-model"`      //sustained-use or tiered
+	DiscountTiers     []DiscountTier `yaml:"discount-tiers"`
+	Reservations      []Reservation  `yaml:"reservations"`
+}
+
+//Reserved instances or committed use of a VM type, already paid during their term
+type Reservation struct {
+	VMType string    `yaml:"vm-type"`
+	Count  int       `yaml:"count"`
+	Start  time.Time `yaml:"start"` //Without start the reservation is always active
+	Term   string    `yaml:"term"`  //E.g. 12M, 36M. Without term the reservation does not expire
+}
+
+//Discount applied to the usage of a VM type in a month from the start of the tier to the start of the next one
+type DiscountTier struct {
+	From     float64 `yaml:"from"`     //Fraction of the month for sustained use, hours for tiered discounts
+	Discount float64 `yaml:"discount"` //Between 0 and 1
 }
 
 type PolicySettings struct{
@@ -38,6 +57,65 @@ type PolicySettings struct{
 	PreferredMetric        string    `yaml:"preferred-metric"`
 }
 
+//Tolerance accepted for one direction before a forecast update invalidates a policy
+type ToleranceRule struct {
+	MarginPercentage     float64 `yaml:"margin-percentage"`      //Percentage of the capacity bound that a value can exceed
+	MinConsecutivePoints int     `yaml:"min-consecutive-points"` //Number of consecutive values out of the band
+	MinDuration          string  `yaml:"min-duration"`           //Time that the values should stay out of the band. E.g 30m
+}
+
+//Tolerance bands applied when a forecast update is validated against the selected policy
+type ValidationTolerance struct {
+	ScaleUp   ToleranceRule `yaml:"scale-up"`   //Load above the capacity
+	ScaleDown ToleranceRule `yaml:"scale-down"` //Load below the capacity minus one replica
+}
+
+//Receding horizon planning. A long horizon is planned but only the first segment is committed and scheduled
+type RollingHorizon struct {
+	Enabled            bool   `yaml:"enabled"`
+	PlanningHorizon    string `yaml:"planning-horizon"`    //Time span derived in every cycle. E.g 24h
+	CommitHorizon      string `yaml:"commit-horizon"`      //Time span ahead of now that is scheduled. E.g 6h
+	ReplanningInterval string `yaml:"replanning-interval"` //Time between cycles. E.g 1h
+}
+
+//Periodic comparison of the infrastructure with the state expected by the selected policy
+type DriftReconciliation struct {
+	Enabled     bool   `yaml:"enabled"`
+	Interval    string `yaml:"interval"`     //Time between checks. E.g 5m
+	GracePeriod string `yaml:"grace-period"` //Time after the start of a scaling action before it is checked. E.g 10m
+	Action      string `yaml:"action"`       //alert, reschedule or rederive
+}
+
+//Check that the reaction to a drift is known, an empty action only logs an alert
+func (d DriftReconciliation) Validate() error {
+	switch d.Action {
+	case "", DRIFT_ACTION_ALERT, DRIFT_ACTION_RESCHEDULE, DRIFT_ACTION_REDERIVE:
+		return nil
+	}
+	return fmt.Errorf("Invalid drift-reconciliation action %s. Expected %s, %s or %s", d.Action,
+		DRIFT_ACTION_ALERT, DRIFT_ACTION_RESCHEDULE, DRIFT_ACTION_REDERIVE)
+}
+
+//Periodic refresh of the profiles fetched from the performance profiles component
+type ProfileRefresh struct {
+	Enabled       bool   `yaml:"enabled"`
+	MaxAge        string `yaml:"max-age"`        //Age after which the stored profiles are fetched again. E.g 7D
+	CheckInterval string `yaml:"check-interval"` //Time between checks of the age of the profiles. E.g 1h
+}
+
+//Mix of on-demand and spot VMs
+type SpotInstances struct {
+	Enabled                bool    `yaml:"enabled"`
+	OnDemandBasePercentile float64 `yaml:"on-demand-base-percentile"` //Percentile of the forecasted load served with on-demand VMs. Default 50
+}
+
+//Baselines with which the cost and provisioning of the policies are compared
+type Baselines struct {
+	Percentile        float64 `yaml:"percentile"`          //Percentile of the forecasted load provisioned by the static baseline. Default 95
+	ScaleOutThreshold float64 `yaml:"scale-out-threshold"` //Utilization percentage above which the reactive baseline adds VMs. Default 80
+	ScaleInThreshold  float64 `yaml:"scale-in-threshold"`  //Utilization percentage below which the reactive baseline removes VMs. Default 40
+}
+
 //Struct that models the system configuration to derive the scaling policies
 type SystemConfiguration struct {
 	Host 						 string			   `yaml:"host"`
@@ -55,6 +133,15 @@ type SystemConfiguration struct {
 	PolicySettings               PolicySettings    `yaml:"policy-settings"`
 	PullingInterval              int               `yaml:"pulling-interval"`
 	StorageInterval              string            `yaml:"storage-interval"`
+	ValidationTolerance          ValidationTolerance `yaml:"validation-tolerance"`
+	RollingHorizon               RollingHorizon    `yaml:"rolling-horizon"`
+	ForecastUpdateWorkers        int               `yaml:"forecast-update-workers"`
+	DriftReconciliation          DriftReconciliation `yaml:"drift-reconciliation"`
+	BootTimeLearningRate         float64           `yaml:"boot-time-learning-rate"`
+	ProfileRefresh               ProfileRefresh    `yaml:"profile-refresh"`
+	VMCatalogFile                string            `yaml:"vm-catalog-file"`
+	SpotInstances                SpotInstances     `yaml:"spot-instances"`
+	Baselines                    Baselines         `yaml:"baselines"`
 }
 
 //Method that parses the configuration file into a struct type
@@ -70,5 +157,10 @@ func ReadConfigFile(configFile string) (SystemConfiguration, error) {
 		log.Fatalf("There was a problem parsing the configuration file. Please review the parameters: %v", err)
 		return systemConfig,err
 	}
+	err = systemConfig.DriftReconciliation.Validate()
+	if err != nil {
+		log.Fatalf("There was a problem parsing the configuration file. Please review the parameters: %v", err)
+		return systemConfig,err
+	}
 	return systemConfig,err
 }
\ No newline at end of file
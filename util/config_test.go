@@ -14,3 +14,27 @@ func TestFileFormat(t *testing.T) {
 		)
 	}
 }
+
+func TestDriftReconciliationAction(t *testing.T) {
+	var tests = []struct {
+		action string
+		valid  bool
+	}{
+		{"", true},
+		{DRIFT_ACTION_ALERT, true},
+		{DRIFT_ACTION_RESCHEDULE, true},
+		{DRIFT_ACTION_REDERIVE, true},
+		{"reschedulle", false},
+		{"Alert", false},
+	}
+	for _, test := range tests {
+		err := DriftReconciliation{Action: test.action}.Validate()
+		if (err == nil) != test.valid {
+			t.Error(
+				"For: ", test.action,
+				"expected: ", test.valid,
+				"got: ", err,
+			)
+		}
+	}
+}
@@ -11,11 +11,31 @@ const (
 const UTC_TIME_LAYOUT = "2006-01-02T15:04:00Z"
 const CONFIG_FILE = "config.yml"
 const DEFAULT_LOGFILE = "Logs.log"
+const DEFAULT_VM_CATALOG_FILE = "vm_profiles.json"
 const DEFAULT_VM_SHUTDOWN_TIME = 35
 const DEFAULT_VM_BOOT_TIME = 20
+const DEFAULT_BOOT_TIME_LEARNING_RATE = 0.3
 const DEFAULT_POD_BOOT_TIME = 20
+const DEFAULT_ON_DEMAND_BASE_PERCENTILE = 50
+const DEFAULT_BASELINE_PERCENTILE = 95
+const DEFAULT_SCALE_OUT_THRESHOLD = 80
+const DEFAULT_SCALE_IN_THRESHOLD = 40
+const MSC_PREDICTION_CONFIDENCE_Z = 1.96
 const PERCENTAGE_REQUIRED_k8S_INSTALLATION_CPU = 0.06
 const PERCENTAGE_REQUIRED_k8S_INSTALLATION_MEM = 0.25
 const TIME_ADD_NODE_TO_K8S = 120
 const TIME_CONTAINER_START = 10
 
+
+//Discounts on the usage of a VM type per month
+const (
+	DISCOUNT_SUSTAINED_USE = "sustained-use"
+	DISCOUNT_TIERED = "tiered"
+)
+
+//Reactions to a drift between the selected policy and the infrastructure
+const (
+	DRIFT_ACTION_ALERT = "alert"
+	DRIFT_ACTION_RESCHEDULE = "reschedule"
+	DRIFT_ACTION_REDERIVE = "rederive"
+)
@@ -17,4 +17,6 @@ const ENDPOINT_SERVICE_PROFILE_BY_REPLICAS = "/getPredictedRegressionTRN/{apptyp
 
 const ENDPOINT_SUBSCRIBE_NOTIFICATIONS = "/subscribe"
 const ENDPOINT_RECIVE_NOTIFICATIONS = "/api/forecast"
+const ENDPOINT_EXECUTION_EVENTS = "/api/executions/{service}"
+const ENDPOINT_DERIVATION_JOB = "/derivations/{service}/{id}"
 